@@ -1,72 +1,231 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 
+	"github.com/mjjs/minipl-go/pkg/ast"
+	"github.com/mjjs/minipl-go/pkg/astjson"
+	"github.com/mjjs/minipl-go/pkg/cfg"
+	"github.com/mjjs/minipl-go/pkg/compile"
+	"github.com/mjjs/minipl-go/pkg/dataflow"
+	"github.com/mjjs/minipl-go/pkg/golden"
+	"github.com/mjjs/minipl-go/pkg/grader"
 	"github.com/mjjs/minipl-go/pkg/interpreter"
-	"github.com/mjjs/minipl-go/pkg/lexer"
-	"github.com/mjjs/minipl-go/pkg/parser"
+	"github.com/mjjs/minipl-go/pkg/loader"
+	"github.com/mjjs/minipl-go/pkg/printer"
 	"github.com/mjjs/minipl-go/pkg/symboltable"
-	"github.com/mjjs/minipl-go/pkg/typechecker"
+	"github.com/mjjs/minipl-go/pkg/token"
 )
 
 type frontEnd struct {
 	out io.Writer
 	in  io.Reader
+	// err receives the warnings about the program, so that they do not get
+	// mixed with the output of the program itself.
+	err io.Writer
 }
 
-func (fe *frontEnd) Execute(filepath string) {
-	if fe.out == nil {
-		fe.out = os.Stdout
+// errInvalidProgram is returned for a program with errors, which have already
+// been printed.
+var errInvalidProgram = errors.New("the program has errors")
+
+// Execute runs the program in filepath if it has no errors, and returns
+// errInvalidProgram otherwise. A runtime error is printed after the output of
+// the program and returned.
+func (fe *frontEnd) Execute(filepath string) error {
+	astRoot, _, ok := fe.check(filepath)
+	if !ok {
+		return errInvalidProgram
 	}
 
-	if fe.in == nil {
-		fe.in = os.Stdin
+	for _, warning := range dataflow.Check(astRoot) {
+		fmt.Fprintln(fe.err, warning)
 	}
 
-	sourceBytes := readFile(filepath)
+	i := interpreter.New(fe.out, fe.in)
+	if err := i.Run(astRoot); err != nil {
+		fmt.Fprintln(fe.out, err)
+		return err
+	}
 
-	lexer := lexer.New(string(sourceBytes))
-	parser := parser.New(lexer)
+	return nil
+}
 
-	astRoot, errors := parser.Parse()
-	if len(errors) > 0 {
-		for _, err := range errors {
-			fmt.Fprintln(fe.out, err)
-		}
-		return
+// ControlFlowGraph prints the control-flow graph of the program in filepath,
+// either as a listing of the blocks or in the Graphviz DOT format. It returns
+// errInvalidProgram for a program with syntax errors, and the error of writing
+// the graph otherwise.
+func (fe *frontEnd) ControlFlowGraph(filepath string, dot bool) error {
+	astRoot, ok := fe.parse(filepath)
+	if !ok {
+		return errInvalidProgram
 	}
 
-	stc := &symboltable.SymbolTableCreator{}
-	symbols, errors := stc.Create(astRoot)
-	if len(errors) > 0 {
-		for _, err := range errors {
-			fmt.Fprintln(fe.out, err)
-		}
-		return
+	graph := cfg.New(astRoot.Statements)
+
+	if dot {
+		return graph.WriteDot(fe.out)
 	}
 
-	tc := typechecker.New(symbols)
-	errors = tc.CheckTypes(astRoot)
-	if len(errors) > 0 {
-		for _, err := range errors {
-			fmt.Fprintln(fe.out, err)
-		}
-		return
+	_, err := fmt.Fprint(fe.out, graph)
+	return err
+}
+
+// SyntaxTree prints the abstract syntax tree of the program in filepath,
+// either as JSON or formatted back into source code. It returns
+// errInvalidProgram for a program with syntax errors, and the error of
+// encoding or writing the tree otherwise.
+func (fe *frontEnd) SyntaxTree(filepath string, asJSON bool) error {
+	astRoot, ok := fe.parse(filepath)
+	if !ok {
+		return errInvalidProgram
 	}
 
-	i := interpreter.New(fe.out, fe.in)
-	i.Run(astRoot)
+	if !asJSON {
+		return printer.Fprint(fe.out, astRoot)
+	}
+
+	data, err := astjson.Marshal(astRoot)
+	if err != nil {
+		return err
+	}
+
+	indented := &bytes.Buffer{}
+	if err := json.Indent(indented, data, "", "  "); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(fe.out, indented)
+	return err
 }
 
-func readFile(path string) []byte {
-	sourceCode, err := ioutil.ReadFile(path)
+// Grade runs the programs in programsDir on the test cases in casesDir and
+// prints the scores. The report is also written as JSON to reportPath unless
+// it is empty.
+func (fe *frontEnd) Grade(programsDir string, casesDir string, config grader.Config, reportPath string) error {
+	fe.init()
+
+	programs, err := grader.Programs(programsDir)
+	if err != nil {
+		return err
+	}
+
+	cases, err := grader.LoadCases(casesDir)
+	if err != nil {
+		return err
+	}
+
+	report := grader.Grade(programs, cases, config)
+	report.WriteText(fe.out)
+
+	if reportPath == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	return ioutil.WriteFile(reportPath, append(data, '\n'), 0644)
+}
+
+// Test runs the tests in dir and prints the differences between the expected
+// and the actual output and errors of the tests that fail. If update is true,
+// the expectations of the failing tests are replaced instead. An error is
+// returned if any of the tests fail.
+func (fe *frontEnd) Test(dir string, config golden.Config, update bool) error {
+	fe.init()
+
+	tests, err := golden.Find(dir)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+
+	for _, test := range tests {
+		result := golden.Run(test, config)
+
+		switch {
+		case result.Passed():
+			fmt.Fprintf(fe.out, "ok      %s\n", test.Path)
+		case update:
+			if err := result.Update(); err != nil {
+				return err
+			}
+			fmt.Fprintf(fe.out, "updated %s\n", test.Path)
+		default:
+			failed++
+			fmt.Fprintf(fe.out, "FAIL    %s\n%s", test.Path, result.Diff())
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tests failed", failed, len(tests))
+	}
+
+	return nil
+}
+
+// parse reads and parses the program in filepath and the files it imports.
+// If filepath is "-", the program is read from the input as it is parsed; the
+// program cannot read anything from the input then. Syntax and import errors
+// are printed and false is returned if there are any.
+func (fe *frontEnd) parse(filepath string) (ast.Prog, bool) {
+	fe.init()
+
+	var astRoot ast.Prog
+	var errors []error
+
+	if filepath == "-" {
+		astRoot, errors = loader.LoadReader(token.NewFileSet(), "<stdin>", fe.in)
+	} else {
+		astRoot, errors = loader.Load(token.NewFileSet(), filepath)
+	}
+
+	return astRoot, fe.report(errors)
+}
+
+// check parses the program in filepath and runs the semantic analysis on it.
+// The symbol and type errors are printed together and false is returned if
+// there are any.
+func (fe *frontEnd) check(filepath string) (ast.Prog, *symboltable.SymbolTable, bool) {
+	astRoot, ok := fe.parse(filepath)
+	if !ok {
+		return astRoot, nil, false
+	}
+
+	symbols, errors := compile.Check(astRoot, compile.Options{})
+
+	return astRoot, symbols, fe.report(errors)
+}
+
+func (fe *frontEnd) init() {
+	if fe.out == nil {
+		fe.out = os.Stdout
+	}
+
+	if fe.in == nil {
+		fe.in = os.Stdin
+	}
+
+	if fe.err == nil {
+		fe.err = os.Stderr
+	}
+}
+
+// report prints the errors and returns true if there were none.
+func (fe *frontEnd) report(errors []error) bool {
+	for _, err := range errors {
+		fmt.Fprintln(fe.out, err)
 	}
 
-	return sourceCode
+	return len(errors) == 0
 }
@@ -2,10 +2,18 @@ package main
 
 import (
 	"bytes"
-	"fmt"
+	"encoding/json"
+	"errors"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/mjjs/minipl-go/internal/testfiles"
+	"github.com/mjjs/minipl-go/pkg/golden"
+	"github.com/mjjs/minipl-go/pkg/grader"
 )
 
 var testCases = []struct {
@@ -35,33 +43,110 @@ var testCases = []struct {
 	{
 		name: "Boolean logic",
 		sourceCode: `
-			var true : bool := (1=1);
-			var false : bool := !true;
-			print true;
+			var t : bool := true;
+			var f : bool := !t;
+			print t;
 			print "\n";
-			print false;
+			print f;
 			print "\n";
-			print (true & true);
+			print (t & true);
 			print "\n";
-			print (false & false);
+			print (f & false);
 			print "\n";
-			print (true & false);
+			print (true & f);
 		`,
 		expectedOutput: bytes.NewBufferString("true\nfalse\ntrue\nfalse\nfalse"),
 	},
+	{
+		name: "Integer literals",
+		sourceCode: `
+			print ((0xFF + 0b11) + (0o10 + 010)) + 1_000;
+		`,
+		expectedOutput: bytes.NewBufferString("1276"),
+	},
+	{
+		name:           "Multi-line strings and escapes",
+		sourceCode:     "print `line one\n  line two\n`;\nprint \"\\u{48}\\x69\\\\\";",
+		expectedOutput: bytes.NewBufferString("line one\n  line two\nHi\\"),
+	},
+	{
+		name: "Comparison and logical operators",
+		sourceCode: `
+			var n : int := 0;
+			print ((n = 0) | ((1 / n) > 0)) & ("apple" < "banana");
+			print (n <> 0) & ((1 / n) > 0);
+			print ((n >= 0) & (n <= 0)) & (n != 1);
+		`,
+		expectedOutput: bytes.NewBufferString("truefalsetrue"),
+	},
+	{
+		name: "Negative numbers",
+		sourceCode: `
+			var n : int := -5;
+			var i : int;
+			for i in -2..-n - 4 do
+				print -i * 2;
+			end for;
+			print 3 - -n;
+		`,
+		expectedOutput: bytes.NewBufferString("420-2"),
+	},
+	{
+		name: "Several reads",
+		sourceCode: `
+			var a : int;
+			var b : int;
+			read a;
+			read b;
+			print a * b;
+		`,
+		userInput:      bytes.NewBufferString("6\n7\n"),
+		expectedOutput: bytes.NewBufferString("42"),
+	},
+	{
+		name: "Builtin functions",
+		sourceCode: `
+			var line : string;
+			read line;
+			var name := substring(line, 0, indexOf(line, ","));
+			var age := toInt(substring(line, len(name) + 1, len(line) - 1));
+			print toUpper(charAt(name, 0)) + substring(name, 1, len(name));
+			print (" is " + toString(age + 1)) + " next year";
+		`,
+		userInput:      bytes.NewBufferString("åsa,41\n"),
+		expectedOutput: bytes.NewBufferString("Åsa is 42 next year"),
+	},
+	{
+		name: "Inferred types",
+		sourceCode: `
+			var n := 3;
+			var s := "x";
+			var b := n < 5;
+			var t := s;
+			var i := 0;
+			for i in 0..n do
+				t := t + s;
+			end for;
+			read s;
+			assert(b);
+			assert(i < n);
+			print t + s;
+		`,
+		userInput:      bytes.NewBufferString("y\n"),
+		expectedOutput: bytes.NewBufferString("xxxxy\n"),
+	},
 }
 
 func TestEndToEndInterpreter(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			f := writeTempFile(t, tc.name, tc.sourceCode)
-			defer removeTempFile(t, f)
+			path := writeProgram(t, tc.name, tc.sourceCode)
 
 			w := &bytes.Buffer{}
 
 			fe := &frontEnd{out: w, in: tc.userInput}
 
-			fe.Execute(f.Name())
+			fe.Execute(path)
 
 			if w.String() != tc.expectedOutput.String() {
 				t.Errorf("Expected: %s\ngot: %s", tc.expectedOutput.String(), w.String())
@@ -70,24 +155,310 @@ func TestEndToEndInterpreter(t *testing.T) {
 	}
 }
 
-func writeTempFile(t *testing.T, name string, source string) *os.File {
+// writeProgram writes the source code of a program into a file name.mpl in a
+// temporary directory and returns the path of the file.
+func writeProgram(t *testing.T, name string, source string) string {
 	t.Helper()
 
-	f, err := os.CreateTemp("", fmt.Sprintf("%s.*.minipl", name))
+	file := name + ".mpl"
+	return filepath.Join(testfiles.Write(t, map[string]string{file: source}), file)
+}
+
+func TestControlFlowGraph(t *testing.T) {
+	path := writeProgram(t, "cfg", "var x : int := 1;\nassert(x = 1);")
+
+	expected := `digraph cfg {
+	node [shape=box, fontname="monospace"];
+	b0 [label="entry", shape=oval];
+	b1 [label="exit", shape=oval];
+	b2 [label="var x : int := 1;\lassert(x = 1);\l"];
+	b3 [label="assert failure", shape=oval, color=red];
+	b4 [label=""];
+	b0 -> b2;
+	b2 -> b3 [label="assert failed", color=red];
+	b2 -> b4;
+	b4 -> b1;
+}
+`
+
+	w := &bytes.Buffer{}
+
+	fe := &frontEnd{out: w}
+	if err := fe.ControlFlowGraph(path, true); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	if w.String() != expected {
+		t.Errorf("Expected: %s\ngot: %s", expected, w.String())
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestControlFlowGraphOfInvalidProgram(t *testing.T) {
+	path := writeProgram(t, "cfg", "print 1 +;")
+
+	w := &bytes.Buffer{}
+
+	fe := &frontEnd{out: w}
+	if err := fe.ControlFlowGraph(path, false); err != errInvalidProgram {
+		t.Errorf("Expected %v, got %v", errInvalidProgram, err)
+	}
+
+	expected := path + ":1:10: syntax error: expected operand after '+', found ';'\n"
+	if w.String() != expected {
+		t.Errorf("Expected: %s\ngot: %s", expected, w.String())
+	}
+}
+
+func TestControlFlowGraphWriteErrors(t *testing.T) {
+	path := writeProgram(t, "cfg", "print 1;")
+
+	for _, dot := range []bool{true, false} {
+		fe := &frontEnd{out: failingWriter{}}
+		if err := fe.ControlFlowGraph(path, dot); err == nil || err.Error() != "disk full" {
+			t.Errorf("Expected the write error, got %v", err)
+		}
+	}
+}
+
+func TestWarningsAreWrittenSeparately(t *testing.T) {
+	path := writeProgram(t, "warnings", "var x : int;\nprint x;\nx := 5;")
+
+	out := &bytes.Buffer{}
+	warnings := &bytes.Buffer{}
+
+	fe := &frontEnd{out: out, err: warnings}
+	fe.Execute(path)
+
+	if out.String() != "0" {
+		t.Errorf("Expected: 0\ngot: %s", out.String())
+	}
+
+	expected := path + ":2:7: warning: variable x is read before it is assigned\n" +
+		path + ":3:1: warning: value assigned to x is never read\n"
+	if warnings.String() != expected {
+		t.Errorf("Expected: %s\ngot: %s", expected, warnings.String())
+	}
+}
+
+func TestSyntaxTreeAsJSON(t *testing.T) {
+	path := writeProgram(t, "ast", "print 1;")
+
+	expected := `{
+  "kind": "Prog",
+  "statements": {
+    "kind": "Stmts",
+    "statements": [
+      {
+        "kind": "PrintStmt",
+        "expression": {
+          "kind": "NullaryExpr",
+          "operand": {
+            "kind": "NumberOpnd",
+            "value": 1,
+            "pos": {
+              "filename": "{file}",
+              "offset": 6,
+              "line": 1,
+              "column": 7
+            },
+            "end": {
+              "filename": "{file}",
+              "offset": 7,
+              "line": 1,
+              "column": 8
+            }
+          }
+        },
+        "pos": {
+          "filename": "{file}",
+          "offset": 0,
+          "line": 1,
+          "column": 1
+        },
+        "end": {
+          "filename": "{file}",
+          "offset": 8,
+          "line": 1,
+          "column": 9
+        }
+      }
+    ]
+  }
+}
+`
+	expected = strings.ReplaceAll(expected, "{file}", path)
+
+	w := &bytes.Buffer{}
+
+	fe := &frontEnd{out: w}
+	if err := fe.SyntaxTree(path, true); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if w.String() != expected {
+		t.Errorf("Expected: %s\ngot: %s", expected, w.String())
+	}
+}
+
+func TestSyntaxTreeErrors(t *testing.T) {
+	path := writeProgram(t, "ast", "print 1 +;")
+
+	for _, asJSON := range []bool{false, true} {
+		w := &bytes.Buffer{}
+
+		fe := &frontEnd{out: w}
+		if err := fe.SyntaxTree(path, asJSON); err != errInvalidProgram {
+			t.Errorf("Expected %v, got %v", errInvalidProgram, err)
+		}
+
+		expected := path + ":1:10: syntax error: expected operand after '+', found ';'\n"
+		if w.String() != expected {
+			t.Errorf("Expected: %s\ngot: %s", expected, w.String())
+		}
+	}
+
+	path = writeProgram(t, "ast", "print 1;")
+
+	fe := &frontEnd{out: failingWriter{}}
+	if err := fe.SyntaxTree(path, true); err == nil || err.Error() != "disk full" {
+		t.Errorf("Expected the write error, got %v", err)
+	}
+}
+
+func TestSymbolAndTypeErrorsAreReportedTogether(t *testing.T) {
+	path := writeProgram(t, "errors", "var x := y + 1;\nprint x - 2;\nvar s : string := 5;")
+
+	out := &bytes.Buffer{}
+
+	fe := &frontEnd{out: out}
+	if err := fe.Execute(path); err != errInvalidProgram {
+		t.Errorf("Expected %v, got %v", errInvalidProgram, err)
+	}
+
+	expected := path + ":1:10: variable y used before declaration\n" +
+		path + ":3:1: cannot assign type int to variable s of type string\n"
+	if out.String() != expected {
+		t.Errorf("Expected: %s\ngot: %s", expected, out.String())
+	}
+}
+
+func TestImports(t *testing.T) {
+	dir := testfiles.Write(t, map[string]string{
+		"main.mpl":      "import \"lib/greet.mpl\";\nimport \"lib/greet.mpl\";\nprint greeting + \"!\";",
+		"lib/greet.mpl": "import \"name.mpl\";\nvar greeting := \"Hello, \" + name;\nprint \"loaded\\n\";",
+		"lib/name.mpl":  "var name := \"world\";",
+	})
+
+	w := &bytes.Buffer{}
+
+	fe := &frontEnd{out: w}
+	fe.Execute(filepath.Join(dir, "main.mpl"))
+
+	expected := "loaded\nHello, world!"
+	if w.String() != expected {
+		t.Errorf("Expected: %s\ngot: %s", expected, w.String())
+	}
+}
+
+func TestProgramFromInput(t *testing.T) {
+	w := &bytes.Buffer{}
+
+	fe := &frontEnd{out: w, in: bytes.NewBufferString("var x := 6;\nprint x * 7;\nprint y;")}
+	if err := fe.Execute("-"); err != errInvalidProgram {
+		t.Errorf("Expected %v, got %v", errInvalidProgram, err)
+	}
+
+	expected := "<stdin>:3:7: variable y used before declaration\n"
+	if w.String() != expected {
+		t.Errorf("Expected: %s\ngot: %s", expected, w.String())
+	}
+
+	w.Reset()
+
+	fe = &frontEnd{out: w, in: bytes.NewBufferString("var x := 6;\nprint x * 7;")}
+	if err := fe.Execute("-"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	if w.String() != "42" {
+		t.Errorf("Expected: 42\ngot: %s", w.String())
+	}
+}
+
+func TestGrade(t *testing.T) {
+	dir := testfiles.Write(t, map[string]string{
+		"programs/double.mpl": "var n : int;\nread n;\nprint n * 2;",
+		"programs/square.mpl": "var n : int;\nread n;\nprint n * n;",
+		"cases/one.in":        "1\n",
+		"cases/one.out":       "2",
+		"cases/two.in":        "2\n",
+		"cases/two.out":       "4",
+	})
+
+	w := &bytes.Buffer{}
+	reportPath := filepath.Join(dir, "report.json")
+
+	fe := &frontEnd{out: w}
+	err := fe.Grade(filepath.Join(dir, "programs"), filepath.Join(dir, "cases"), grader.Config{}, reportPath)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	expected := "double.mpl  2/2\nsquare.mpl  1/2\n    one: wrong output\ntotal       3/4\n"
+	if w.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, w.String())
+	}
+
+	data, err := os.ReadFile(reportPath)
 	if err != nil {
-		panic(err)
+		t.Fatalf("Expected the JSON report to be written, got %s", err)
 	}
 
-	if _, err := f.Write([]byte(source)); err != nil {
-		panic(err)
+	var report grader.Report
+	if err := json.Unmarshal(data, &report); err != nil || report.Passed != 3 || report.Total != 4 {
+		t.Errorf("Expected a JSON report with 3 of 4 passed, got %s (%v)", data, err)
 	}
+}
 
-	return f
+func TestGoldenFiles(t *testing.T) {
+	w := &bytes.Buffer{}
+
+	fe := &frontEnd{out: w}
+	if err := fe.Test("testdata", golden.Config{Timeout: time.Minute}, false); err != nil {
+		t.Fatalf("Expected the tests in testdata to pass, got %s:\n%s", err, w)
+	}
 }
 
-func removeTempFile(t *testing.T, f *os.File) {
-	t.Helper()
+func TestGoldenFileFailures(t *testing.T) {
+	dir := testfiles.Write(t, map[string]string{"sum.mpl": "// Output: 3\nprint 1 + 1;"})
+	path := filepath.Join(dir, "sum.mpl")
+
+	w := &bytes.Buffer{}
+	fe := &frontEnd{out: w}
 
-	f.Close()
-	os.Remove(f.Name())
+	err := fe.Test(dir, golden.Config{}, false)
+	if err == nil || err.Error() != "1 of 1 tests failed" {
+		t.Errorf("Expected the test to fail, got %v", err)
+	}
+
+	expected := "FAIL    " + path + "\n--- expected output\n+++ actual output\n-3\n+2\n"
+	if w.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, w)
+	}
+
+	w.Reset()
+	if err := fe.Test(dir, golden.Config{}, true); err != nil || w.String() != "updated "+path+"\n" {
+		t.Errorf("Expected the test to be updated, got %v:\n%s", err, w)
+	}
+
+	w.Reset()
+	if err := fe.Test(dir, golden.Config{}, false); err != nil || w.String() != "ok      "+path+"\n" {
+		t.Errorf("Expected the updated test to pass, got %v:\n%s", err, w)
+	}
 }
@@ -1,18 +1,159 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"time"
+
+	"github.com/mjjs/minipl-go/pkg/golden"
+	"github.com/mjjs/minipl-go/pkg/grader"
+	"github.com/mjjs/minipl-go/pkg/interpreter"
 )
 
+const usage = `Usage: %[1]s [run] <file_path>
+       %[1]s cfg [--dot] <file_path>
+       %[1]s ast [--json] <file_path>
+       %[1]s grade [flags] <programs_dir> <cases_dir>
+       %[1]s test [-update] [-timeout d] <dir>
+
+A file_path of - reads the program from the standard input.
+`
+
 func main() {
-	if len(os.Args) != 2 {
-		fmt.Printf("Usage: %s <file_path>\n", os.Args[0])
+	if len(os.Args) < 2 {
+		fmt.Printf(usage, os.Args[0])
 		return
 	}
 
-	filePath := os.Args[1]
-
 	fe := &frontEnd{}
-	fe.Execute(filePath)
+
+	switch os.Args[1] {
+	case "run":
+		runCommand(fe, os.Args[2:])
+	case "cfg":
+		cfgCommand(fe, os.Args[2:])
+	case "ast":
+		astCommand(fe, os.Args[2:])
+	case "grade":
+		gradeCommand(fe, os.Args[2:])
+	case "test":
+		testCommand(fe, os.Args[2:])
+	default:
+		runCommand(fe, os.Args[1:])
+	}
+}
+
+func runCommand(fe *frontEnd, args []string) {
+	flags := newFlagSet("run")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if err := fe.Execute(flags.Arg(0)); err != nil {
+		os.Exit(1)
+	}
+}
+
+func cfgCommand(fe *frontEnd, args []string) {
+	flags := newFlagSet("cfg")
+	dot := flags.Bool("dot", false, "write the graph in the Graphviz DOT format")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	exitOnError(fe.ControlFlowGraph(flags.Arg(0), *dot))
+}
+
+func astCommand(fe *frontEnd, args []string) {
+	flags := newFlagSet("ast")
+	asJSON := flags.Bool("json", false, "write the abstract syntax tree as JSON")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	exitOnError(fe.SyntaxTree(flags.Arg(0), *asJSON))
+}
+
+func gradeCommand(fe *frontEnd, args []string) {
+	flags := newFlagSet("grade")
+	timeout := flags.Duration("timeout", 5*time.Second, "the time limit of each run")
+	maxSteps := flags.Int("max-steps", 10_000_000, "the number of statements each run may execute, 0 for no limit")
+	maxOutput := flags.Int("max-output", 1<<20, "the number of bytes each run may print, 0 for no limit")
+	maxString := flags.Int("max-string", 1<<20, "the length in bytes of the longest string, 0 for no limit")
+	normalize := flags.Bool("normalize", false, "ignore differences in whitespace when comparing outputs")
+	jobs := flags.Int("jobs", 0, "the number of runs in progress at a time, 0 for one per CPU")
+	report := flags.String("json", "", "also write the report as JSON to this file")
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	config := grader.Config{
+		Timeout: *timeout,
+		Limits: interpreter.Limits{
+			MaxSteps:       *maxSteps,
+			MaxOutputBytes: *maxOutput,
+			MaxStringBytes: *maxString,
+		},
+		Normalize: *normalize,
+		Jobs:      *jobs,
+	}
+
+	if err := fe.Grade(flags.Arg(0), flags.Arg(1), config, *report); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func testCommand(fe *frontEnd, args []string) {
+	flags := newFlagSet("test")
+	update := flags.Bool("update", false, "replace the expectations of the failing tests with the actual results")
+	timeout := flags.Duration("timeout", 10*time.Second, "the time limit of each test")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if err := fe.Test(flags.Arg(0), golden.Config{Timeout: *timeout}, *update); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// exitOnError exits with status 1 if err is not nil. The errors of an invalid
+// program have already been printed, so only the other errors are.
+func exitOnError(err error) {
+	if err == nil {
+		return
+	}
+
+	if err != errInvalidProgram {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	os.Exit(1)
+}
+
+func newFlagSet(command string) *flag.FlagSet {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), usage, os.Args[0])
+		flags.PrintDefaults()
+	}
+
+	return flags
 }
@@ -0,0 +1,31 @@
+// Package testfiles writes the files used by tests that read programs from
+// the file system.
+package testfiles
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Write writes the files, given by their slash-separated paths relative to a
+// new temporary directory, and returns the directory. Missing directories
+// are created, and the directory is removed when the test ends.
+func Write(t testing.TB, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
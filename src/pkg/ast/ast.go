@@ -15,21 +15,28 @@ type Visitor interface {
 	VisitReadStmt(ReadStmt)
 	VisitPrintStmt(PrintStmt)
 	VisitAssertStmt(AssertStmt)
+	VisitImportStmt(ImportStmt)
+	VisitBadStmt(BadStmt)
 
 	VisitBinaryExpr(BinaryExpr)
 	VisitUnaryExpr(UnaryExpr)
 	VisitNullaryExpr(NullaryExpr)
+	VisitCallExpr(CallExpr)
+	VisitBadExpr(BadExpr)
 
 	VisitNumberOpnd(NumberOpnd)
 	VisitStringOpnd(StringOpnd)
+	VisitBoolOpnd(BoolOpnd)
 
 	VisitIdent(Ident)
 }
 
-// Node is a basic node for the abstract syntax tree.
+// Node is a basic node for the abstract syntax tree. The source code of a node
+// spans from Position up to, but not including, End.
 type Node interface {
 	Accept(Visitor)
 	Position() token.Position
+	End() token.Position
 }
 
 // Expr defines all the expression nodes.
@@ -53,37 +60,61 @@ func (p Prog) Position() token.Position {
 	return p.Statements.Position()
 }
 
+func (p Prog) End() token.Position {
+	return p.Statements.End()
+}
+
 // Stmts is an abstract syntax tree node containing all the statements of the program.
+// The list is empty for an import that could not be loaded or a tree whose
+// statements were all removed, and its position is then the zero position.
 type Stmts struct{ Statements []Stmt }
 
 func (s Stmts) Position() token.Position {
+	if len(s.Statements) == 0 {
+		return token.Position{}
+	}
+
 	return s.Statements[0].Position()
 }
 
+func (s Stmts) End() token.Position {
+	if len(s.Statements) == 0 {
+		return token.Position{}
+	}
+
+	return s.Statements[len(s.Statements)-1].End()
+}
+
 // ReadStmt is an abstract syntax tree node which defines the read statement.
 // The destination of the read is defined in TargetIdentifier.
 type ReadStmt struct {
 	TargetIdentifier Ident
 	Pos              token.Position
+	EndPos           token.Position
 }
 
 func (r ReadStmt) Position() token.Position { return r.Pos }
+func (r ReadStmt) End() token.Position      { return r.EndPos }
 
 // PrintStmt is an abstract syntax tree node which defines the print statement.
 type PrintStmt struct {
 	Expression Expr
 	Pos        token.Position
+	EndPos     token.Position
 }
 
 func (p PrintStmt) Position() token.Position { return p.Pos }
+func (p PrintStmt) End() token.Position      { return p.EndPos }
 
 // AssertStmt is an abstract syntax tree node which defines the assert statement.
 type AssertStmt struct {
 	Expression Expr
 	Pos        token.Position
+	EndPos     token.Position
 }
 
 func (a AssertStmt) Position() token.Position { return a.Pos }
+func (a AssertStmt) End() token.Position      { return a.EndPos }
 
 // ForStmt defines a for loop statement over a range of values ranging from
 // Low to High. Index is the control variable used in the statement.
@@ -93,29 +124,64 @@ type ForStmt struct {
 	High       Expr
 	Statements Stmts
 	Pos        token.Position
+	EndPos     token.Position
 }
 
 func (f ForStmt) Position() token.Position { return f.Pos }
+func (f ForStmt) End() token.Position      { return f.EndPos }
 
 // AssignStmt defines a statement node.
 type AssignStmt struct {
 	Identifier Ident
 	Expression Expr
 	Pos        token.Position
+	EndPos     token.Position
 }
 
 func (a AssignStmt) Position() token.Position { return a.Pos }
+func (a AssignStmt) End() token.Position      { return a.EndPos }
 
 // DeclStmt defines a declaration of a new variable.
 type DeclStmt struct {
-	Identifier   token.Token
+	Identifier token.Token
+	// zero Token when the type is inferred from Expression
 	VariableType token.Token
 	// nil when no value is assigned to the variable during declaration
 	Expression Expr
 	Pos        token.Position
+	EndPos     token.Position
 }
 
 func (d DeclStmt) Position() token.Position { return d.Pos }
+func (d DeclStmt) End() token.Position      { return d.EndPos }
+
+// Inferred reports whether the type of the variable is left out of the
+// declaration and has to be inferred from its initializer.
+func (d DeclStmt) Inferred() bool { return d.VariableType == token.Token{} }
+
+// ImportStmt imports the file at Path, relative to the importing file. The
+// parser leaves Statements empty, they are filled in with the statements of
+// the imported file when the imports are resolved. A file that has already
+// been imported earlier in the program is not imported again, and its later
+// imports have no statements.
+type ImportStmt struct {
+	Path       string
+	Statements Stmts
+	Pos        token.Position
+	EndPos     token.Position
+}
+
+func (i ImportStmt) Position() token.Position { return i.Pos }
+func (i ImportStmt) End() token.Position      { return i.EndPos }
+
+// BadStmt is a placeholder for a statement that could not be parsed.
+type BadStmt struct {
+	Pos    token.Position
+	EndPos token.Position
+}
+
+func (b BadStmt) Position() token.Position { return b.Pos }
+func (b BadStmt) End() token.Position      { return b.EndPos }
 
 // BinaryExpr is an expression with two operands and and operator.
 type BinaryExpr struct {
@@ -125,6 +191,7 @@ type BinaryExpr struct {
 }
 
 func (b BinaryExpr) Position() token.Position { return b.Left.Position() }
+func (b BinaryExpr) End() token.Position      { return b.Right.End() }
 
 // UnaryExpr is an expression with one operand preceded by an unary operator.
 type UnaryExpr struct {
@@ -134,6 +201,7 @@ type UnaryExpr struct {
 }
 
 func (u UnaryExpr) Position() token.Position { return u.Pos }
+func (u UnaryExpr) End() token.Position      { return u.Operand.End() }
 
 // NullaryExpr is an expression with a single operand and no operators.
 type NullaryExpr struct {
@@ -141,51 +209,96 @@ type NullaryExpr struct {
 }
 
 func (n NullaryExpr) Position() token.Position { return n.Operand.Position() }
+func (n NullaryExpr) End() token.Position      { return n.Operand.End() }
+
+// CallExpr is a call of the builtin function named by Func.
+type CallExpr struct {
+	Func   token.Token
+	Args   []Expr
+	Pos    token.Position
+	EndPos token.Position
+}
+
+func (c CallExpr) Position() token.Position { return c.Pos }
+func (c CallExpr) End() token.Position      { return c.EndPos }
+
+// BadExpr is a placeholder for an expression that could not be parsed.
+type BadExpr struct {
+	Pos    token.Position
+	EndPos token.Position
+}
+
+func (b BadExpr) Position() token.Position { return b.Pos }
+func (b BadExpr) End() token.Position      { return b.EndPos }
 
 // NumberOpnd is an integer operand.
 type NumberOpnd struct {
-	Value int
-	Pos   token.Position
+	Value  int
+	Pos    token.Position
+	EndPos token.Position
 }
 
 func (n NumberOpnd) Position() token.Position { return n.Pos }
+func (n NumberOpnd) End() token.Position      { return n.EndPos }
 
 // StringOpnd is a string operand.
 type StringOpnd struct {
-	Value string
-	Pos   token.Position
+	Value  string
+	Pos    token.Position
+	EndPos token.Position
 }
 
 func (s StringOpnd) Position() token.Position { return s.Pos }
+func (s StringOpnd) End() token.Position      { return s.EndPos }
+
+// BoolOpnd is a boolean operand, either true or false.
+type BoolOpnd struct {
+	Value  bool
+	Pos    token.Position
+	EndPos token.Position
+}
+
+func (b BoolOpnd) Position() token.Position { return b.Pos }
+func (b BoolOpnd) End() token.Position      { return b.EndPos }
 
 // Ident is an identifier node.
 type Ident struct {
-	Id  token.Token
-	Pos token.Position
+	Id     token.Token
+	Pos    token.Position
+	EndPos token.Position
 }
 
 func (i Ident) Position() token.Position { return i.Pos }
+func (i Ident) End() token.Position      { return i.EndPos }
 
 func (n Prog) Accept(v Visitor)        { v.VisitProg(n) }
 func (n Stmts) Accept(v Visitor)       { v.VisitStmts(n) }
 func (n ForStmt) Accept(v Visitor)     { v.VisitForStmt(n) }
 func (n NumberOpnd) Accept(v Visitor)  { v.VisitNumberOpnd(n) }
 func (n StringOpnd) Accept(v Visitor)  { v.VisitStringOpnd(n) }
+func (n BoolOpnd) Accept(v Visitor)    { v.VisitBoolOpnd(n) }
 func (n Ident) Accept(v Visitor)       { v.VisitIdent(n) }
 func (n BinaryExpr) Accept(v Visitor)  { v.VisitBinaryExpr(n) }
 func (n UnaryExpr) Accept(v Visitor)   { v.VisitUnaryExpr(n) }
 func (n NullaryExpr) Accept(v Visitor) { v.VisitNullaryExpr(n) }
+func (n CallExpr) Accept(v Visitor)    { v.VisitCallExpr(n) }
 func (n AssignStmt) Accept(v Visitor)  { v.VisitAssignStmt(n) }
 func (n ReadStmt) Accept(v Visitor)    { v.VisitReadStmt(n) }
 func (n PrintStmt) Accept(v Visitor)   { v.VisitPrintStmt(n) }
 func (n AssertStmt) Accept(v Visitor)  { v.VisitAssertStmt(n) }
 func (n DeclStmt) Accept(v Visitor)    { v.VisitDeclStmt(n) }
+func (n ImportStmt) Accept(v Visitor)  { v.VisitImportStmt(n) }
+func (n BadStmt) Accept(v Visitor)     { v.VisitBadStmt(n) }
+func (n BadExpr) Accept(v Visitor)     { v.VisitBadExpr(n) }
 
 func (n BinaryExpr) exprNode()  {}
 func (n UnaryExpr) exprNode()   {}
 func (n NullaryExpr) exprNode() {}
+func (n CallExpr) exprNode()    {}
 func (n NumberOpnd) exprNode()  {}
 func (n StringOpnd) exprNode()  {}
+func (n BoolOpnd) exprNode()    {}
+func (n BadExpr) exprNode()     {}
 
 func (n ForStmt) stmtNode()    {}
 func (n PrintStmt) stmtNode()  {}
@@ -193,3 +306,5 @@ func (n ReadStmt) stmtNode()   {}
 func (n AssertStmt) stmtNode() {}
 func (n AssignStmt) stmtNode() {}
 func (n DeclStmt) stmtNode()   {}
+func (n ImportStmt) stmtNode() {}
+func (n BadStmt) stmtNode()    {}
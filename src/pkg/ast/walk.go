@@ -0,0 +1,297 @@
+package ast
+
+import "fmt"
+
+// Children returns the child nodes of node in source order. A declaration
+// without an initializer has no children.
+func Children(node Node) []Node {
+	switch n := node.(type) {
+	case Prog:
+		return []Node{n.Statements}
+	case Stmts:
+		children := make([]Node, len(n.Statements))
+		for i, stmt := range n.Statements {
+			children[i] = stmt
+		}
+		return children
+	case AssignStmt:
+		return []Node{n.Identifier, n.Expression}
+	case DeclStmt:
+		if n.Expression == nil {
+			return nil
+		}
+		return []Node{n.Expression}
+	case ForStmt:
+		return []Node{n.Index, n.Low, n.High, n.Statements}
+	case ReadStmt:
+		return []Node{n.TargetIdentifier}
+	case PrintStmt:
+		return []Node{n.Expression}
+	case AssertStmt:
+		return []Node{n.Expression}
+	case ImportStmt:
+		return []Node{n.Statements}
+	case BinaryExpr:
+		return []Node{n.Left, n.Right}
+	case UnaryExpr:
+		return []Node{n.Operand}
+	case NullaryExpr:
+		return []Node{n.Operand}
+	case CallExpr:
+		children := make([]Node, len(n.Args))
+		for i, arg := range n.Args {
+			children[i] = arg
+		}
+		return children
+	}
+
+	return nil
+}
+
+// Inspect traverses the tree rooted at node in depth-first pre-order and calls
+// f for every node. If f returns false, the children of the node are skipped.
+func Inspect(node Node, f func(Node) bool) {
+	if !f(node) {
+		return
+	}
+
+	for _, child := range Children(node) {
+		Inspect(child, f)
+	}
+}
+
+// BaseVisitor is a Visitor that visits the children of every node in source
+// order and does nothing else. A pass that only cares about a few node types
+// can embed BaseVisitor, implement the methods for those types and set Self
+// to itself so that the children are dispatched to the overriding methods:
+//
+//	type identCounter struct {
+//		ast.BaseVisitor
+//		count int
+//	}
+//
+//	func (c *identCounter) VisitIdent(node ast.Ident) { c.count++ }
+//
+//	c := &identCounter{}
+//	c.Self = c
+//	root.Accept(c)
+type BaseVisitor struct {
+	Self Visitor
+}
+
+func (b *BaseVisitor) visit(node Node) {
+	if b.Self != nil {
+		node.Accept(b.Self)
+	} else {
+		node.Accept(b)
+	}
+}
+
+func (b *BaseVisitor) VisitProg(node Prog) {
+	b.visit(node.Statements)
+}
+
+func (b *BaseVisitor) VisitStmts(node Stmts) {
+	for _, stmt := range node.Statements {
+		b.visit(stmt)
+	}
+}
+
+func (b *BaseVisitor) VisitAssignStmt(node AssignStmt) {
+	b.visit(node.Identifier)
+	b.visit(node.Expression)
+}
+
+func (b *BaseVisitor) VisitDeclStmt(node DeclStmt) {
+	if node.Expression != nil {
+		b.visit(node.Expression)
+	}
+}
+
+func (b *BaseVisitor) VisitForStmt(node ForStmt) {
+	b.visit(node.Index)
+	b.visit(node.Low)
+	b.visit(node.High)
+	b.visit(node.Statements)
+}
+
+func (b *BaseVisitor) VisitReadStmt(node ReadStmt) {
+	b.visit(node.TargetIdentifier)
+}
+
+func (b *BaseVisitor) VisitPrintStmt(node PrintStmt) {
+	b.visit(node.Expression)
+}
+
+func (b *BaseVisitor) VisitAssertStmt(node AssertStmt) {
+	b.visit(node.Expression)
+}
+
+func (b *BaseVisitor) VisitImportStmt(node ImportStmt) {
+	b.visit(node.Statements)
+}
+
+func (b *BaseVisitor) VisitBinaryExpr(node BinaryExpr) {
+	b.visit(node.Left)
+	b.visit(node.Right)
+}
+
+func (b *BaseVisitor) VisitUnaryExpr(node UnaryExpr) {
+	b.visit(node.Operand)
+}
+
+func (b *BaseVisitor) VisitNullaryExpr(node NullaryExpr) {
+	b.visit(node.Operand)
+}
+
+func (b *BaseVisitor) VisitCallExpr(node CallExpr) {
+	for _, arg := range node.Args {
+		b.visit(arg)
+	}
+}
+
+func (b *BaseVisitor) VisitBadStmt(node BadStmt)       {}
+func (b *BaseVisitor) VisitBadExpr(node BadExpr)       {}
+func (b *BaseVisitor) VisitNumberOpnd(node NumberOpnd) {}
+func (b *BaseVisitor) VisitStringOpnd(node StringOpnd) {}
+func (b *BaseVisitor) VisitBoolOpnd(node BoolOpnd)     {}
+func (b *BaseVisitor) VisitIdent(node Ident)           {}
+
+// Rewrite rebuilds the tree rooted at node from the bottom up. The children
+// of every node are rewritten first, then f is called with the rebuilt node
+// and the node it returns takes its place. Returning the argument unchanged
+// keeps the node as it is, and returning nil for a statement removes the
+// statement from its list.
+//
+// Rewrite panics if f replaces a node with one that cannot appear in its
+// place, for example the identifier of an assignment with a number.
+func Rewrite(node Node, f func(Node) Node) Node {
+	switch n := node.(type) {
+	case Prog:
+		n.Statements = rewriteStmts(n.Statements, f)
+		return f(n)
+
+	case Stmts:
+		return f(rewriteList(n, f))
+
+	case AssignStmt:
+		n.Identifier = rewriteIdent(n.Identifier, f)
+		n.Expression = rewriteExpr(n.Expression, f)
+		return f(n)
+
+	case DeclStmt:
+		if n.Expression != nil {
+			n.Expression = rewriteExpr(n.Expression, f)
+		}
+		return f(n)
+
+	case ForStmt:
+		n.Index = rewriteIdent(n.Index, f)
+		n.Low = rewriteExpr(n.Low, f)
+		n.High = rewriteExpr(n.High, f)
+		n.Statements = rewriteStmts(n.Statements, f)
+		return f(n)
+
+	case ReadStmt:
+		n.TargetIdentifier = rewriteIdent(n.TargetIdentifier, f)
+		return f(n)
+
+	case PrintStmt:
+		n.Expression = rewriteExpr(n.Expression, f)
+		return f(n)
+
+	case AssertStmt:
+		n.Expression = rewriteExpr(n.Expression, f)
+		return f(n)
+
+	case ImportStmt:
+		n.Statements = rewriteStmts(n.Statements, f)
+		return f(n)
+
+	case BinaryExpr:
+		n.Left = rewriteNode(n.Left, f)
+		n.Right = rewriteNode(n.Right, f)
+		return f(n)
+
+	case UnaryExpr:
+		n.Operand = rewriteNode(n.Operand, f)
+		return f(n)
+
+	case NullaryExpr:
+		n.Operand = rewriteNode(n.Operand, f)
+		return f(n)
+
+	case CallExpr:
+		args := make([]Expr, len(n.Args))
+		for i, arg := range n.Args {
+			args[i] = rewriteExpr(arg, f)
+		}
+		n.Args = args
+		return f(n)
+	}
+
+	return f(node)
+}
+
+func rewriteStmts(node Stmts, f func(Node) Node) Stmts {
+	rewritten := Rewrite(node, f)
+
+	stmts, ok := rewritten.(Stmts)
+	if !ok {
+		panic(fmt.Sprintf("ast.Rewrite: cannot replace statement list with %T", rewritten))
+	}
+
+	return stmts
+}
+
+// rewriteList rewrites every statement of a list into a new list, leaving
+// the original tree untouched.
+func rewriteList(node Stmts, f func(Node) Node) Stmts {
+	statements := make([]Stmt, 0, len(node.Statements))
+
+	for _, stmt := range node.Statements {
+		rewritten := Rewrite(stmt, f)
+		if rewritten == nil {
+			continue
+		}
+
+		s, ok := rewritten.(Stmt)
+		if !ok {
+			panic(fmt.Sprintf("ast.Rewrite: cannot replace statement %T with %T", stmt, rewritten))
+		}
+		statements = append(statements, s)
+	}
+
+	return Stmts{Statements: statements}
+}
+
+func rewriteIdent(node Ident, f func(Node) Node) Ident {
+	rewritten := Rewrite(node, f)
+
+	ident, ok := rewritten.(Ident)
+	if !ok {
+		panic(fmt.Sprintf("ast.Rewrite: cannot replace identifier with %T", rewritten))
+	}
+
+	return ident
+}
+
+func rewriteExpr(node Expr, f func(Node) Node) Expr {
+	rewritten := Rewrite(node, f)
+
+	expr, ok := rewritten.(Expr)
+	if !ok {
+		panic(fmt.Sprintf("ast.Rewrite: cannot replace expression %T with %T", node, rewritten))
+	}
+
+	return expr
+}
+
+func rewriteNode(node Node, f func(Node) Node) Node {
+	rewritten := Rewrite(node, f)
+	if rewritten == nil {
+		panic(fmt.Sprintf("ast.Rewrite: cannot remove operand %T", node))
+	}
+
+	return rewritten
+}
@@ -0,0 +1,138 @@
+package ast_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/mjjs/minipl-go/pkg/ast"
+	"github.com/mjjs/minipl-go/pkg/lexer"
+	"github.com/mjjs/minipl-go/pkg/parser"
+	"github.com/mjjs/minipl-go/pkg/printer"
+	"github.com/mjjs/minipl-go/pkg/token"
+)
+
+func TestInspect(t *testing.T) {
+	root := parse(t, "var x : int := 1; for i in 0..x do print !(i < 2); end for;")
+
+	var visited []string
+	ast.Inspect(root, func(node ast.Node) bool {
+		visited = append(visited, fmt.Sprintf("%T", node))
+		_, isUnary := node.(ast.UnaryExpr)
+		return !isUnary
+	})
+
+	expected := []string{
+		"ast.Prog", "ast.Stmts",
+		"ast.DeclStmt", "ast.NullaryExpr", "ast.NumberOpnd",
+		"ast.ForStmt", "ast.Ident", "ast.NullaryExpr", "ast.NumberOpnd", "ast.NullaryExpr", "ast.Ident",
+		"ast.Stmts", "ast.PrintStmt", "ast.UnaryExpr",
+	}
+
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Expected:\n%v\ngot:\n%v", expected, visited)
+	}
+}
+
+type identCounter struct {
+	ast.BaseVisitor
+	names []string
+}
+
+func (c *identCounter) VisitIdent(node ast.Ident) {
+	c.names = append(c.names, node.Id.Value())
+}
+
+// VisitDeclStmt counts the declared variable too before visiting the
+// initializer as usual.
+func (c *identCounter) VisitDeclStmt(node ast.DeclStmt) {
+	c.names = append(c.names, node.Identifier.Value())
+	c.BaseVisitor.VisitDeclStmt(node)
+}
+
+func TestBaseVisitor(t *testing.T) {
+	root := parse(t, "var x : int := y; read z; for i in a..b do assert(!c); x := d + (e * f); end for; print charAt(g, h);")
+
+	c := &identCounter{}
+	c.Self = c
+	root.Accept(c)
+
+	expected := []string{"x", "y", "z", "i", "a", "b", "c", "x", "d", "e", "f", "g", "h"}
+	if !reflect.DeepEqual(c.names, expected) {
+		t.Errorf("Expected %v, got %v", expected, c.names)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	root := parse(t, "var x : int := y * 2; print toString(y); assert(y = 1); print x;")
+
+	rewritten := ast.Rewrite(root, func(node ast.Node) ast.Node {
+		switch n := node.(type) {
+		case ast.Ident:
+			if n.Id.Value() == "y" {
+				return ast.NumberOpnd{Value: 21, Pos: n.Pos}
+			}
+		case ast.AssertStmt:
+			return nil
+		}
+
+		return node
+	})
+
+	expected := "var x : int := 21 * 2;\nprint toString(21);\nprint x;\n"
+	if actual := printer.Sprint(rewritten); actual != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, actual)
+	}
+
+	if actual := printer.Sprint(root); actual != "var x : int := y * 2;\nprint toString(y);\nassert(y = 1);\nprint x;\n" {
+		t.Errorf("Expected the original tree to be left untouched, got:\n%s", actual)
+	}
+}
+
+func TestRewriteRemovingAllStatements(t *testing.T) {
+	root := parse(t, "print 1; print 2;")
+
+	rewritten := ast.Rewrite(root, func(node ast.Node) ast.Node {
+		if _, ok := node.(ast.PrintStmt); ok {
+			return nil
+		}
+
+		return node
+	}).(ast.Prog)
+
+	if n := len(rewritten.Statements.Statements); n != 0 {
+		t.Fatalf("Expected no statements, got %d", n)
+	}
+
+	if pos, end := rewritten.Position(), rewritten.End(); pos != (token.Position{}) || end != (token.Position{}) {
+		t.Errorf("Expected the zero position for an empty program, got %s and %s", pos, end)
+	}
+}
+
+func TestRewritePanicsOnInvalidReplacement(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Expected a panic")
+		}
+	}()
+
+	root := parse(t, "x := 1;")
+
+	ast.Rewrite(root, func(node ast.Node) ast.Node {
+		if _, ok := node.(ast.Ident); ok {
+			return ast.StringOpnd{Value: "x", Pos: token.Position{}}
+		}
+		return node
+	})
+}
+
+func parse(t *testing.T, sourceCode string) ast.Prog {
+	t.Helper()
+
+	root, errors := parser.New(lexer.New(sourceCode)).Parse()
+	if len(errors) > 0 {
+		t.Fatalf("Failed to parse %s: %s", sourceCode, errors)
+	}
+
+	return root
+}
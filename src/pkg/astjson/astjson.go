@@ -0,0 +1,353 @@
+// Package astjson encodes abstract syntax trees as JSON and decodes them back.
+//
+// Every node is encoded as an object with a "kind" field naming the node type
+// and one field per field of the node. Tokens are encoded as objects with a
+// "tag" and an optional "lexeme", and positions as objects with an optional
+// "filename", an "offset", a "line" and a "column". Nodes that store their
+// end position have an "end" next to their "pos". A missing optional
+// expression and the type of a declaration whose type is inferred are encoded
+// as null.
+package astjson
+
+import (
+	"encoding/json"
+
+	"github.com/mjjs/minipl-go/pkg/ast"
+	"github.com/mjjs/minipl-go/pkg/stack"
+	"github.com/mjjs/minipl-go/pkg/token"
+)
+
+type jsonPosition struct {
+	Filename string `json:"filename,omitempty"`
+	Offset   int    `json:"offset"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+}
+
+type jsonToken struct {
+	Tag    token.TokenTag `json:"tag"`
+	Lexeme string         `json:"lexeme,omitempty"`
+}
+
+type progNode struct {
+	Kind       string          `json:"kind"`
+	Statements json.RawMessage `json:"statements"`
+}
+
+type stmtsNode struct {
+	Kind       string            `json:"kind"`
+	Statements []json.RawMessage `json:"statements"`
+}
+
+type assignStmtNode struct {
+	Kind       string          `json:"kind"`
+	Identifier json.RawMessage `json:"identifier"`
+	Expression json.RawMessage `json:"expression"`
+	Pos        jsonPosition    `json:"pos"`
+	End        jsonPosition    `json:"end"`
+}
+
+type declStmtNode struct {
+	Kind         string          `json:"kind"`
+	Identifier   jsonToken       `json:"identifier"`
+	VariableType *jsonToken      `json:"variableType"`
+	Expression   json.RawMessage `json:"expression"`
+	Pos          jsonPosition    `json:"pos"`
+	End          jsonPosition    `json:"end"`
+}
+
+type forStmtNode struct {
+	Kind       string          `json:"kind"`
+	Index      json.RawMessage `json:"index"`
+	Low        json.RawMessage `json:"low"`
+	High       json.RawMessage `json:"high"`
+	Statements json.RawMessage `json:"statements"`
+	Pos        jsonPosition    `json:"pos"`
+	End        jsonPosition    `json:"end"`
+}
+
+type readStmtNode struct {
+	Kind             string          `json:"kind"`
+	TargetIdentifier json.RawMessage `json:"targetIdentifier"`
+	Pos              jsonPosition    `json:"pos"`
+	End              jsonPosition    `json:"end"`
+}
+
+type importStmtNode struct {
+	Kind       string          `json:"kind"`
+	Path       string          `json:"path"`
+	Statements json.RawMessage `json:"statements"`
+	Pos        jsonPosition    `json:"pos"`
+	End        jsonPosition    `json:"end"`
+}
+
+// exprStmtNode is the encoding of both print and assert statements.
+type exprStmtNode struct {
+	Kind       string          `json:"kind"`
+	Expression json.RawMessage `json:"expression"`
+	Pos        jsonPosition    `json:"pos"`
+	End        jsonPosition    `json:"end"`
+}
+
+type binaryExprNode struct {
+	Kind     string          `json:"kind"`
+	Left     json.RawMessage `json:"left"`
+	Operator jsonToken       `json:"operator"`
+	Right    json.RawMessage `json:"right"`
+}
+
+type unaryExprNode struct {
+	Kind    string          `json:"kind"`
+	Unary   jsonToken       `json:"unary"`
+	Operand json.RawMessage `json:"operand"`
+	Pos     jsonPosition    `json:"pos"`
+}
+
+type nullaryExprNode struct {
+	Kind    string          `json:"kind"`
+	Operand json.RawMessage `json:"operand"`
+}
+
+type callExprNode struct {
+	Kind string            `json:"kind"`
+	Func jsonToken         `json:"func"`
+	Args []json.RawMessage `json:"args"`
+	Pos  jsonPosition      `json:"pos"`
+	End  jsonPosition      `json:"end"`
+}
+
+// badNode is the encoding of both bad statements and bad expressions.
+type badNode struct {
+	Kind string       `json:"kind"`
+	Pos  jsonPosition `json:"pos"`
+	End  jsonPosition `json:"end"`
+}
+
+type numberOpndNode struct {
+	Kind  string       `json:"kind"`
+	Value int          `json:"value"`
+	Pos   jsonPosition `json:"pos"`
+	End   jsonPosition `json:"end"`
+}
+
+type stringOpndNode struct {
+	Kind  string       `json:"kind"`
+	Value string       `json:"value"`
+	Pos   jsonPosition `json:"pos"`
+	End   jsonPosition `json:"end"`
+}
+
+type boolOpndNode struct {
+	Kind  string       `json:"kind"`
+	Value bool         `json:"value"`
+	Pos   jsonPosition `json:"pos"`
+	End   jsonPosition `json:"end"`
+}
+
+type identNode struct {
+	Kind string       `json:"kind"`
+	Id   jsonToken    `json:"id"`
+	Pos  jsonPosition `json:"pos"`
+	End  jsonPosition `json:"end"`
+}
+
+// Marshal returns the JSON encoding of the tree rooted at node.
+func Marshal(node ast.Node) ([]byte, error) {
+	e := &encoder{stack: stack.New()}
+	node.Accept(e)
+
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	return e.stack.Pop().(json.RawMessage), nil
+}
+
+func encodePosition(pos token.Position) jsonPosition {
+	return jsonPosition{Filename: pos.Filename, Offset: pos.Offset, Line: pos.Line, Column: pos.Column}
+}
+
+func encodeToken(tok token.Token) jsonToken {
+	return jsonToken{Tag: tok.Type(), Lexeme: tok.Lexeme()}
+}
+
+// encoder is an ast.Visitor that leaves the encoding of every visited node
+// on the stack.
+type encoder struct {
+	stack *stack.Stack
+	err   error
+}
+
+func (e *encoder) push(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil && e.err == nil {
+		e.err = err
+	}
+
+	e.stack.Push(json.RawMessage(data))
+}
+
+func (e *encoder) encode(node ast.Node) json.RawMessage {
+	if node == nil {
+		return json.RawMessage("null")
+	}
+
+	node.Accept(e)
+	return e.stack.Pop().(json.RawMessage)
+}
+
+func (e *encoder) VisitProg(node ast.Prog) {
+	e.push(progNode{
+		Kind:       "Prog",
+		Statements: e.encode(node.Statements),
+	})
+}
+
+func (e *encoder) VisitStmts(node ast.Stmts) {
+	statements := []json.RawMessage{}
+	for _, stmt := range node.Statements {
+		statements = append(statements, e.encode(stmt))
+	}
+
+	e.push(stmtsNode{Kind: "Stmts", Statements: statements})
+}
+
+func (e *encoder) VisitAssignStmt(node ast.AssignStmt) {
+	e.push(assignStmtNode{
+		Kind:       "AssignStmt",
+		Identifier: e.encode(node.Identifier),
+		Expression: e.encode(node.Expression),
+		Pos:        encodePosition(node.Pos),
+		End:        encodePosition(node.EndPos),
+	})
+}
+
+func (e *encoder) VisitDeclStmt(node ast.DeclStmt) {
+	var variableType *jsonToken
+	if !node.Inferred() {
+		tok := encodeToken(node.VariableType)
+		variableType = &tok
+	}
+
+	e.push(declStmtNode{
+		Kind:         "DeclStmt",
+		Identifier:   encodeToken(node.Identifier),
+		VariableType: variableType,
+		Expression:   e.encode(node.Expression),
+		Pos:          encodePosition(node.Pos),
+		End:          encodePosition(node.EndPos),
+	})
+}
+
+func (e *encoder) VisitForStmt(node ast.ForStmt) {
+	e.push(forStmtNode{
+		Kind:       "ForStmt",
+		Index:      e.encode(node.Index),
+		Low:        e.encode(node.Low),
+		High:       e.encode(node.High),
+		Statements: e.encode(node.Statements),
+		Pos:        encodePosition(node.Pos),
+		End:        encodePosition(node.EndPos),
+	})
+}
+
+func (e *encoder) VisitReadStmt(node ast.ReadStmt) {
+	e.push(readStmtNode{
+		Kind:             "ReadStmt",
+		TargetIdentifier: e.encode(node.TargetIdentifier),
+		Pos:              encodePosition(node.Pos),
+		End:              encodePosition(node.EndPos),
+	})
+}
+
+func (e *encoder) VisitPrintStmt(node ast.PrintStmt) {
+	e.push(exprStmtNode{
+		Kind:       "PrintStmt",
+		Expression: e.encode(node.Expression),
+		Pos:        encodePosition(node.Pos),
+		End:        encodePosition(node.EndPos),
+	})
+}
+
+func (e *encoder) VisitAssertStmt(node ast.AssertStmt) {
+	e.push(exprStmtNode{
+		Kind:       "AssertStmt",
+		Expression: e.encode(node.Expression),
+		Pos:        encodePosition(node.Pos),
+		End:        encodePosition(node.EndPos),
+	})
+}
+
+func (e *encoder) VisitImportStmt(node ast.ImportStmt) {
+	e.push(importStmtNode{
+		Kind:       "ImportStmt",
+		Path:       node.Path,
+		Statements: e.encode(node.Statements),
+		Pos:        encodePosition(node.Pos),
+		End:        encodePosition(node.EndPos),
+	})
+}
+
+func (e *encoder) VisitBinaryExpr(node ast.BinaryExpr) {
+	e.push(binaryExprNode{
+		Kind:     "BinaryExpr",
+		Left:     e.encode(node.Left),
+		Operator: encodeToken(node.Operator),
+		Right:    e.encode(node.Right),
+	})
+}
+
+func (e *encoder) VisitUnaryExpr(node ast.UnaryExpr) {
+	e.push(unaryExprNode{
+		Kind:    "UnaryExpr",
+		Unary:   encodeToken(node.Unary),
+		Operand: e.encode(node.Operand),
+		Pos:     encodePosition(node.Pos),
+	})
+}
+
+func (e *encoder) VisitBadStmt(node ast.BadStmt) {
+	e.push(badNode{Kind: "BadStmt", Pos: encodePosition(node.Pos), End: encodePosition(node.EndPos)})
+}
+
+func (e *encoder) VisitBadExpr(node ast.BadExpr) {
+	e.push(badNode{Kind: "BadExpr", Pos: encodePosition(node.Pos), End: encodePosition(node.EndPos)})
+}
+
+func (e *encoder) VisitNullaryExpr(node ast.NullaryExpr) {
+	e.push(nullaryExprNode{
+		Kind:    "NullaryExpr",
+		Operand: e.encode(node.Operand),
+	})
+}
+
+func (e *encoder) VisitCallExpr(node ast.CallExpr) {
+	args := make([]json.RawMessage, len(node.Args))
+	for i, arg := range node.Args {
+		args[i] = e.encode(arg)
+	}
+
+	e.push(callExprNode{
+		Kind: "CallExpr",
+		Func: encodeToken(node.Func),
+		Args: args,
+		Pos:  encodePosition(node.Pos),
+		End:  encodePosition(node.EndPos),
+	})
+}
+
+func (e *encoder) VisitNumberOpnd(node ast.NumberOpnd) {
+	e.push(numberOpndNode{Kind: "NumberOpnd", Value: node.Value, Pos: encodePosition(node.Pos), End: encodePosition(node.EndPos)})
+}
+
+func (e *encoder) VisitStringOpnd(node ast.StringOpnd) {
+	e.push(stringOpndNode{Kind: "StringOpnd", Value: node.Value, Pos: encodePosition(node.Pos), End: encodePosition(node.EndPos)})
+}
+
+func (e *encoder) VisitBoolOpnd(node ast.BoolOpnd) {
+	e.push(boolOpndNode{Kind: "BoolOpnd", Value: node.Value, Pos: encodePosition(node.Pos), End: encodePosition(node.EndPos)})
+}
+
+func (e *encoder) VisitIdent(node ast.Ident) {
+	e.push(identNode{Kind: "Ident", Id: encodeToken(node.Id), Pos: encodePosition(node.Pos), End: encodePosition(node.EndPos)})
+}
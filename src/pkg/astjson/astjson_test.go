@@ -0,0 +1,185 @@
+package astjson
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mjjs/minipl-go/pkg/ast"
+	"github.com/mjjs/minipl-go/pkg/interpreter"
+	"github.com/mjjs/minipl-go/pkg/lexer"
+	"github.com/mjjs/minipl-go/pkg/parser"
+)
+
+var roundTripTestCases = []struct {
+	name           string
+	sourceCode     string
+	userInput      string
+	expectedOutput string
+}{
+	{
+		name: "Factorial program",
+		sourceCode: `
+		print "Give a number";
+		var n : int;
+		read n;
+		var v : int := 1;
+		var i : int;
+		for i in 1..n do
+			v := v * i;
+		end for;
+		print "The result is: ";
+		print v;
+		`,
+		userInput:      "5\n",
+		expectedOutput: "Give a numberThe result is: 24",
+	},
+	{
+		name: "Every expression type",
+		sourceCode: `
+		var b : bool := !((1 < 2) & ("a" = "a"));
+		var s : string := "tab\tquote\"";
+		assert(!b);
+		assert(true);
+		print (10 / 3) - 1;
+		print s;
+		`,
+		expectedOutput: "2tab\tquote\"",
+	},
+	{
+		name: "Inferred types",
+		sourceCode: `
+		var n := 2;
+		var s := "a";
+		print s;
+		print n * 3;
+		`,
+		expectedOutput: "a6",
+	},
+	{
+		name: "Function calls",
+		sourceCode: `
+		var s := toUpper("abc");
+		print substring(s, 1, len(s)) + toString(indexOf(s, "C"));
+		`,
+		expectedOutput: "BC2",
+	},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, testCase := range roundTripTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			root, errors := parser.New(lexer.New(testCase.sourceCode)).Parse()
+			if len(errors) > 0 {
+				t.Fatalf("Failed to parse: %s", errors)
+			}
+
+			data, err := Marshal(root)
+			if err != nil {
+				t.Fatalf("Expected no error, got %s", err)
+			}
+
+			decoded, err := Unmarshal(data)
+			if err != nil {
+				t.Fatalf("Expected no error, got %s", err)
+			}
+
+			if !reflect.DeepEqual(decoded, root) {
+				t.Errorf("Expected:\n%+#v\ngot:\n%+#v", root, decoded)
+			}
+
+			w := &bytes.Buffer{}
+			interpreter.New(w, strings.NewReader(testCase.userInput)).Run(decoded)
+
+			if w.String() != testCase.expectedOutput {
+				t.Errorf("Expected: %s\ngot: %s", testCase.expectedOutput, w.String())
+			}
+		})
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	root, _ := parser.New(lexer.New("var x : int;\nprint x + 1;")).Parse()
+
+	expected := `{"kind":"Prog","statements":{"kind":"Stmts","statements":[` +
+		`{"kind":"DeclStmt","identifier":{"tag":"IDENT","lexeme":"x"},"variableType":{"tag":"INTEGER"},` +
+		`"expression":null,"pos":{"offset":0,"line":1,"column":1},"end":{"offset":12,"line":1,"column":13}},` +
+		`{"kind":"PrintStmt","expression":{"kind":"BinaryExpr",` +
+		`"left":{"kind":"Ident","id":{"tag":"IDENT","lexeme":"x"},` +
+		`"pos":{"offset":19,"line":2,"column":7},"end":{"offset":20,"line":2,"column":8}},` +
+		`"operator":{"tag":"PLUS"},` +
+		`"right":{"kind":"NumberOpnd","value":1,` +
+		`"pos":{"offset":23,"line":2,"column":11},"end":{"offset":24,"line":2,"column":12}}},` +
+		`"pos":{"offset":13,"line":2,"column":1},"end":{"offset":25,"line":2,"column":13}}]}}`
+
+	data, err := Marshal(root)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, data)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	testCases := []struct {
+		input         string
+		expectedError string
+	}{
+		{`{"kind":"Foo"}`, `astjson: unknown node kind "Foo"`},
+		{`{"kind":"Ident","id":{"tag":"IDENT","lexeme":"x"}}`, "astjson: expected a Prog, got ast.Ident"},
+		{`{"kind":"Prog","statements":{"kind":"Stmts","statements":[{"kind":"NumberOpnd","value":1}]}}`,
+			"astjson: expected a statement, got ast.NumberOpnd"},
+		{`{"kind":"Prog","statements":{"kind":"Stmts","statements":[{"kind":"PrintStmt"}]}}`,
+			"astjson: missing node"},
+		{`{"kind":"Prog","statements":{"kind":"Stmts","statements":[]}}`, "astjson: empty statement list"},
+		{`{"kind":"Prog","statements":{"kind":"Stmts","statements":[{"kind":"ForStmt",` +
+			`"index":{"kind":"Ident","id":{"tag":"IDENT","lexeme":"i"}},` +
+			`"low":{"kind":"NumberOpnd","value":0},"high":{"kind":"NumberOpnd","value":1},` +
+			`"statements":{"kind":"Stmts","statements":null}}]}}`,
+			"astjson: empty statement list"},
+		{`{"kind":"Prog","statements":{"kind":"Stmts","statements":[{"kind":"PrintStmt","expression":` +
+			`{"kind":"BinaryExpr","left":{"kind":"NumberOpnd","value":1},"operator":{"tag":"PLUS"},` +
+			`"right":{"kind":"PrintStmt","expression":{"kind":"NumberOpnd","value":1}}}}]}}`,
+			"astjson: expected an operand, got ast.PrintStmt"},
+		{`{"kind":"Prog","statements":{"kind":"Stmts","statements":[{"kind":"PrintStmt","expression":` +
+			`{"kind":"UnaryExpr","unary":{"tag":"NOT"},"operand":{"kind":"ReadStmt",` +
+			`"targetIdentifier":{"kind":"Ident","id":{"tag":"IDENT","lexeme":"x"}}}}}]}}`,
+			"astjson: expected an operand, got ast.ReadStmt"},
+		{`{"kind":"Prog","statements":{"kind":"Stmts","statements":[{"kind":"PrintStmt","expression":` +
+			`{"kind":"BinaryExpr","left":{"kind":"NumberOpnd","value":1},"operator":{"tag":"NOT"},` +
+			`"right":{"kind":"NumberOpnd","value":1}}}]}}`,
+			"astjson: NOT is not a binary operator"},
+		{`{"kind":"Prog","statements":{"kind":"Stmts","statements":[{"kind":"PrintStmt","expression":` +
+			`{"kind":"UnaryExpr","unary":{"tag":"MINUS"},"operand":{"kind":"NumberOpnd","value":1}}}]}}`,
+			"astjson: MINUS is not a unary operator"},
+		{`{"kind":"Prog","statements":{"kind":"Stmts","statements":[{"kind":"PrintStmt","expression":` +
+			`{"kind":"Ident","id":{"tag":"IDENT","lexeme":""}}}]}}`,
+			"astjson: identifier without a name"},
+		{`{"kind":"Prog","statements":{"kind":"Stmts","statements":[{"kind":"DeclStmt",` +
+			`"identifier":{"tag":"IDENT"},"variableType":{"tag":"INTEGER"}}]}}`,
+			"astjson: identifier without a name"},
+	}
+
+	for _, testCase := range testCases {
+		_, err := Unmarshal([]byte(testCase.input))
+		if err == nil || err.Error() != testCase.expectedError {
+			t.Errorf("Expected error %s, got %v", testCase.expectedError, err)
+		}
+	}
+}
+
+func TestMarshalNode(t *testing.T) {
+	data, err := Marshal(ast.NullaryExpr{Operand: ast.StringOpnd{Value: "x"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	expected := `{"kind":"NullaryExpr","operand":{"kind":"StringOpnd","value":"x",` +
+		`"pos":{"offset":0,"line":0,"column":0},"end":{"offset":0,"line":0,"column":0}}}`
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, data)
+	}
+}
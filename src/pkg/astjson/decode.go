@@ -0,0 +1,429 @@
+package astjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mjjs/minipl-go/pkg/ast"
+	"github.com/mjjs/minipl-go/pkg/token"
+)
+
+// Unmarshal decodes the JSON encoding of a whole program.
+func Unmarshal(data []byte) (ast.Prog, error) {
+	node, err := decode(data)
+	if err != nil {
+		return ast.Prog{}, err
+	}
+
+	prog, ok := node.(ast.Prog)
+	if !ok {
+		return ast.Prog{}, fmt.Errorf("astjson: expected a Prog, got %T", node)
+	}
+
+	return prog, nil
+}
+
+func decodePosition(pos jsonPosition) token.Position {
+	return token.Position{Filename: pos.Filename, Offset: pos.Offset, Line: pos.Line, Column: pos.Column}
+}
+
+func decodeToken(tok jsonToken) token.Token {
+	return token.New(tok.Tag, tok.Lexeme)
+}
+
+// decodeName decodes the token of an identifier, which must have a name.
+func decodeName(tok jsonToken) (token.Token, error) {
+	if tok.Lexeme == "" {
+		return token.Token{}, fmt.Errorf("astjson: identifier without a name")
+	}
+
+	return decodeToken(tok), nil
+}
+
+func isNull(data json.RawMessage) bool {
+	return len(data) == 0 || bytes.Equal(data, []byte("null"))
+}
+
+// decode decodes any node based on its kind field.
+func decode(data json.RawMessage) (ast.Node, error) {
+	if isNull(data) {
+		return nil, fmt.Errorf("astjson: missing node")
+	}
+
+	var header struct {
+		Kind string `json:"kind"`
+	}
+
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("astjson: %w", err)
+	}
+
+	switch header.Kind {
+	case "Prog":
+		var n progNode
+		if err := json.Unmarshal(data, &n); err != nil {
+			return nil, fmt.Errorf("astjson: %w", err)
+		}
+
+		statements, err := decodeBlock(n.Statements)
+		return ast.Prog{Statements: statements}, err
+
+	case "Stmts":
+		return decodeStmts(data)
+
+	case "AssignStmt":
+		var n assignStmtNode
+		if err := json.Unmarshal(data, &n); err != nil {
+			return nil, fmt.Errorf("astjson: %w", err)
+		}
+
+		identifier, err := decodeIdent(n.Identifier)
+		if err != nil {
+			return nil, err
+		}
+
+		expression, err := decodeExpr(n.Expression)
+		if err != nil {
+			return nil, err
+		}
+
+		return ast.AssignStmt{
+			Identifier: identifier,
+			Expression: expression,
+			Pos:        decodePosition(n.Pos),
+			EndPos:     decodePosition(n.End),
+		}, nil
+
+	case "DeclStmt":
+		var n declStmtNode
+		if err := json.Unmarshal(data, &n); err != nil {
+			return nil, fmt.Errorf("astjson: %w", err)
+		}
+
+		var expression ast.Expr
+		if !isNull(n.Expression) {
+			var err error
+			if expression, err = decodeExpr(n.Expression); err != nil {
+				return nil, err
+			}
+		}
+
+		identifier, err := decodeName(n.Identifier)
+		if err != nil {
+			return nil, err
+		}
+
+		var variableType token.Token
+		if n.VariableType != nil {
+			variableType = decodeToken(*n.VariableType)
+		}
+
+		return ast.DeclStmt{
+			Identifier:   identifier,
+			VariableType: variableType,
+			Expression:   expression,
+			Pos:          decodePosition(n.Pos),
+			EndPos:       decodePosition(n.End),
+		}, nil
+
+	case "ForStmt":
+		var n forStmtNode
+		if err := json.Unmarshal(data, &n); err != nil {
+			return nil, fmt.Errorf("astjson: %w", err)
+		}
+
+		index, err := decodeIdent(n.Index)
+		if err != nil {
+			return nil, err
+		}
+
+		low, err := decodeExpr(n.Low)
+		if err != nil {
+			return nil, err
+		}
+
+		high, err := decodeExpr(n.High)
+		if err != nil {
+			return nil, err
+		}
+
+		statements, err := decodeBlock(n.Statements)
+		if err != nil {
+			return nil, err
+		}
+
+		return ast.ForStmt{
+			Index:      index,
+			Low:        low,
+			High:       high,
+			Statements: statements,
+			Pos:        decodePosition(n.Pos),
+			EndPos:     decodePosition(n.End),
+		}, nil
+
+	case "ReadStmt":
+		var n readStmtNode
+		if err := json.Unmarshal(data, &n); err != nil {
+			return nil, fmt.Errorf("astjson: %w", err)
+		}
+
+		target, err := decodeIdent(n.TargetIdentifier)
+		if err != nil {
+			return nil, err
+		}
+
+		return ast.ReadStmt{TargetIdentifier: target, Pos: decodePosition(n.Pos), EndPos: decodePosition(n.End)}, nil
+
+	case "ImportStmt":
+		var n importStmtNode
+		if err := json.Unmarshal(data, &n); err != nil {
+			return nil, fmt.Errorf("astjson: %w", err)
+		}
+
+		statements, err := decodeStmts(n.Statements)
+		if err != nil {
+			return nil, err
+		}
+
+		return ast.ImportStmt{
+			Path:       n.Path,
+			Statements: statements,
+			Pos:        decodePosition(n.Pos),
+			EndPos:     decodePosition(n.End),
+		}, nil
+
+	case "PrintStmt", "AssertStmt":
+		var n exprStmtNode
+		if err := json.Unmarshal(data, &n); err != nil {
+			return nil, fmt.Errorf("astjson: %w", err)
+		}
+
+		expression, err := decodeExpr(n.Expression)
+		if err != nil {
+			return nil, err
+		}
+
+		if n.Kind == "PrintStmt" {
+			return ast.PrintStmt{Expression: expression, Pos: decodePosition(n.Pos), EndPos: decodePosition(n.End)}, nil
+		}
+
+		return ast.AssertStmt{Expression: expression, Pos: decodePosition(n.Pos), EndPos: decodePosition(n.End)}, nil
+
+	case "BinaryExpr":
+		var n binaryExprNode
+		if err := json.Unmarshal(data, &n); err != nil {
+			return nil, fmt.Errorf("astjson: %w", err)
+		}
+
+		left, err := decodeOperand(n.Left)
+		if err != nil {
+			return nil, err
+		}
+
+		right, err := decodeOperand(n.Right)
+		if err != nil {
+			return nil, err
+		}
+
+		operator := decodeToken(n.Operator)
+		if !operator.IsOperator() {
+			return nil, fmt.Errorf("astjson: %s is not a binary operator", n.Operator.Tag)
+		}
+
+		return ast.BinaryExpr{Left: left, Operator: operator, Right: right}, nil
+
+	case "UnaryExpr":
+		var n unaryExprNode
+		if err := json.Unmarshal(data, &n); err != nil {
+			return nil, fmt.Errorf("astjson: %w", err)
+		}
+
+		operand, err := decodeOperand(n.Operand)
+		if err != nil {
+			return nil, err
+		}
+
+		if n.Unary.Tag != token.NOT {
+			return nil, fmt.Errorf("astjson: %s is not a unary operator", n.Unary.Tag)
+		}
+
+		return ast.UnaryExpr{Unary: decodeToken(n.Unary), Operand: operand, Pos: decodePosition(n.Pos)}, nil
+
+	case "NullaryExpr":
+		var n nullaryExprNode
+		if err := json.Unmarshal(data, &n); err != nil {
+			return nil, fmt.Errorf("astjson: %w", err)
+		}
+
+		operand, err := decodeOperand(n.Operand)
+		if err != nil {
+			return nil, err
+		}
+
+		return ast.NullaryExpr{Operand: operand}, nil
+
+	case "CallExpr":
+		var n callExprNode
+		if err := json.Unmarshal(data, &n); err != nil {
+			return nil, fmt.Errorf("astjson: %w", err)
+		}
+
+		function, err := decodeName(n.Func)
+		if err != nil {
+			return nil, err
+		}
+
+		args := make([]ast.Expr, len(n.Args))
+		for i, data := range n.Args {
+			arg, err := decodeExpr(data)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = arg
+		}
+
+		return ast.CallExpr{
+			Func:   function,
+			Args:   args,
+			Pos:    decodePosition(n.Pos),
+			EndPos: decodePosition(n.End),
+		}, nil
+
+	case "BadStmt", "BadExpr":
+		var n badNode
+		if err := json.Unmarshal(data, &n); err != nil {
+			return nil, fmt.Errorf("astjson: %w", err)
+		}
+
+		if n.Kind == "BadStmt" {
+			return ast.BadStmt{Pos: decodePosition(n.Pos), EndPos: decodePosition(n.End)}, nil
+		}
+
+		return ast.BadExpr{Pos: decodePosition(n.Pos), EndPos: decodePosition(n.End)}, nil
+
+	case "NumberOpnd":
+		var n numberOpndNode
+		if err := json.Unmarshal(data, &n); err != nil {
+			return nil, fmt.Errorf("astjson: %w", err)
+		}
+
+		return ast.NumberOpnd{Value: n.Value, Pos: decodePosition(n.Pos), EndPos: decodePosition(n.End)}, nil
+
+	case "StringOpnd":
+		var n stringOpndNode
+		if err := json.Unmarshal(data, &n); err != nil {
+			return nil, fmt.Errorf("astjson: %w", err)
+		}
+
+		return ast.StringOpnd{Value: n.Value, Pos: decodePosition(n.Pos), EndPos: decodePosition(n.End)}, nil
+
+	case "BoolOpnd":
+		var n boolOpndNode
+		if err := json.Unmarshal(data, &n); err != nil {
+			return nil, fmt.Errorf("astjson: %w", err)
+		}
+
+		return ast.BoolOpnd{Value: n.Value, Pos: decodePosition(n.Pos), EndPos: decodePosition(n.End)}, nil
+
+	case "Ident":
+		var n identNode
+		if err := json.Unmarshal(data, &n); err != nil {
+			return nil, fmt.Errorf("astjson: %w", err)
+		}
+
+		id, err := decodeName(n.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		return ast.Ident{Id: id, Pos: decodePosition(n.Pos), EndPos: decodePosition(n.End)}, nil
+	}
+
+	return nil, fmt.Errorf("astjson: unknown node kind %q", header.Kind)
+}
+
+func decodeStmts(data json.RawMessage) (ast.Stmts, error) {
+	var n stmtsNode
+	if err := json.Unmarshal(data, &n); err != nil {
+		return ast.Stmts{}, fmt.Errorf("astjson: %w", err)
+	}
+
+	if n.Kind != "Stmts" {
+		return ast.Stmts{}, fmt.Errorf("astjson: expected Stmts, got %q", n.Kind)
+	}
+
+	var statements []ast.Stmt
+
+	for _, data := range n.Statements {
+		node, err := decode(data)
+		if err != nil {
+			return ast.Stmts{}, err
+		}
+
+		stmt, ok := node.(ast.Stmt)
+		if !ok {
+			return ast.Stmts{}, fmt.Errorf("astjson: expected a statement, got %T", node)
+		}
+
+		statements = append(statements, stmt)
+	}
+
+	return ast.Stmts{Statements: statements}, nil
+}
+
+// decodeBlock decodes the statements of a program or a loop body, which the
+// parser never leaves empty. The statements of an import are decoded with
+// decodeStmts, as they are only filled in when the import is loaded.
+func decodeBlock(data json.RawMessage) (ast.Stmts, error) {
+	stmts, err := decodeStmts(data)
+	if err == nil && len(stmts.Statements) == 0 {
+		return ast.Stmts{}, fmt.Errorf("astjson: empty statement list")
+	}
+
+	return stmts, err
+}
+
+func decodeExpr(data json.RawMessage) (ast.Expr, error) {
+	node, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	expr, ok := node.(ast.Expr)
+	if !ok {
+		return nil, fmt.Errorf("astjson: expected an expression, got %T", node)
+	}
+
+	return expr, nil
+}
+
+// decodeOperand decodes the operand of an operator, which is an expression or
+// an identifier.
+func decodeOperand(data json.RawMessage) (ast.Node, error) {
+	node, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch node.(type) {
+	case ast.Expr, ast.Ident:
+		return node, nil
+	}
+
+	return nil, fmt.Errorf("astjson: expected an operand, got %T", node)
+}
+
+func decodeIdent(data json.RawMessage) (ast.Ident, error) {
+	node, err := decode(data)
+	if err != nil {
+		return ast.Ident{}, err
+	}
+
+	ident, ok := node.(ast.Ident)
+	if !ok {
+		return ast.Ident{}, fmt.Errorf("astjson: expected an identifier, got %T", node)
+	}
+
+	return ident, nil
+}
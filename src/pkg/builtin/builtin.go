@@ -0,0 +1,83 @@
+// Package builtin holds the builtin functions of MiniPL, such as len and
+// substring. The functions are kept in a registry where the type checker
+// looks up their signatures and the interpreters their implementations.
+// More functions can be added from Go with Register:
+//
+//	func init() {
+//		builtin.Register("twice", types.NewFunction([]types.Type{types.Int}, types.Int),
+//			func(args []interface{}) (interface{}, error) {
+//				return 2 * args[0].(int), nil
+//			})
+//	}
+package builtin
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mjjs/minipl-go/pkg/types"
+)
+
+// Impl is the implementation of a builtin function. The arguments are the
+// values of the parameter types, an int, a string or a bool each, and the
+// result must be a value of the result type. A returned error stops the
+// program with a runtime error.
+type Impl func(args []interface{}) (interface{}, error)
+
+// Func is a registered builtin function.
+type Func struct {
+	Name      string
+	Signature *types.Function
+	Impl      Impl
+}
+
+var registry = map[string]Func{}
+
+// Register adds the builtin function name with the given signature and
+// implementation. A builtin function must return a value, as calls are
+// expressions. Register panics if a function with the same name has already
+// been registered, so it is meant to be called from init functions.
+func Register(name string, signature *types.Function, impl Impl) {
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("builtin: function %s registered twice", name))
+	}
+
+	if signature.Result() == nil {
+		panic(fmt.Sprintf("builtin: function %s does not return a value", name))
+	}
+
+	registry[name] = Func{Name: name, Signature: signature, Impl: impl}
+}
+
+// Lookup returns the builtin function with the given name.
+func Lookup(name string) (Func, bool) {
+	f, ok := registry[name]
+	return f, ok
+}
+
+// Set is a set of functions that a program can call in addition to the
+// registered builtin functions, for example the functions provided by a
+// program that embeds MiniPL. The nil Set holds no functions.
+type Set map[string]Func
+
+// Lookup returns the function with the given name from the set or, if there
+// is none, the registered builtin function.
+func (s Set) Lookup(name string) (Func, bool) {
+	if f, ok := s[name]; ok {
+		return f, true
+	}
+
+	return Lookup(name)
+}
+
+// Names returns the names of all the builtin functions in alphabetical order.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
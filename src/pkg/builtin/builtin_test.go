@@ -0,0 +1,100 @@
+package builtin
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mjjs/minipl-go/pkg/types"
+)
+
+func TestStringFunctions(t *testing.T) {
+	testCases := []struct {
+		name          string
+		args          []interface{}
+		expected      interface{}
+		expectedError string
+	}{
+		{"len", []interface{}{""}, 0, ""},
+		{"len", []interface{}{"häst"}, 4, ""},
+		{"substring", []interface{}{"häst", 1, 3}, "äs", ""},
+		{"substring", []interface{}{"häst", 4, 4}, "", ""},
+		{"substring", []interface{}{"häst", 2, 5}, nil, "range 2..5 out of bounds for a string of length 4"},
+		{"substring", []interface{}{"häst", 2, 1}, nil, "range 2..1 out of bounds for a string of length 4"},
+		{"charAt", []interface{}{"häst", 1}, "ä", ""},
+		{"charAt", []interface{}{"häst", -1}, nil, "index -1 out of bounds for a string of length 4"},
+		{"indexOf", []interface{}{"häst", "st"}, 2, ""},
+		{"indexOf", []interface{}{"häst", ""}, 0, ""},
+		{"indexOf", []interface{}{"häst", "x"}, -1, ""},
+		{"toUpper", []interface{}{"Häst"}, "HÄST", ""},
+		{"toLower", []interface{}{"Häst"}, "häst", ""},
+		{"toString", []interface{}{-42}, "-42", ""},
+		{"toInt", []interface{}{"+17"}, 17, ""},
+		{"toInt", []interface{}{"1.5"}, nil, `cannot convert "1.5" to int`},
+	}
+
+	for _, testCase := range testCases {
+		f, ok := Lookup(testCase.name)
+		if !ok {
+			t.Fatalf("Expected %s to be registered", testCase.name)
+		}
+
+		actual, err := f.Impl(testCase.args)
+
+		var errorMessage string
+		if err != nil {
+			errorMessage = err.Error()
+		}
+
+		if actual != testCase.expected || errorMessage != testCase.expectedError {
+			t.Errorf(
+				"%s%v: expected %#v (%s), got %#v (%v)",
+				testCase.name, testCase.args, testCase.expected, testCase.expectedError, actual, err,
+			)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	defer delete(registry, "twice")
+
+	signature := types.NewFunction([]types.Type{types.Int}, types.Int)
+	Register("twice", signature, func(args []interface{}) (interface{}, error) {
+		return 2 * args[0].(int), nil
+	})
+
+	f, ok := Lookup("twice")
+	if !ok || !types.Identical(f.Signature, signature) {
+		t.Fatalf("Expected twice to be registered with the signature %s, got %+v", signature, f)
+	}
+
+	if x, err := f.Impl([]interface{}{21}); x != 42 || err != nil {
+		t.Errorf("Expected 42, got %v (%v)", x, err)
+	}
+
+	expected := []string{"charAt", "indexOf", "len", "substring", "toInt", "toLower", "toString", "toUpper", "twice"}
+	if names := Names(); !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+}
+
+func TestRegisterPanics(t *testing.T) {
+	testCases := []struct {
+		name      string
+		signature *types.Function
+	}{
+		{"len", types.NewFunction([]types.Type{types.String}, types.Int)},
+		{"noResult", types.NewFunction(nil, nil)},
+	}
+
+	for _, testCase := range testCases {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("Expected registering %s to panic", testCase.name)
+				}
+			}()
+
+			Register(testCase.name, testCase.signature, nil)
+		}()
+	}
+}
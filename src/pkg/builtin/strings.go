@@ -0,0 +1,92 @@
+package builtin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mjjs/minipl-go/pkg/types"
+)
+
+// The string functions count characters, not bytes: len("é") is 1, and the
+// first character of a string is at index 0.
+func init() {
+	Register("len", signature(types.Int, types.String), length)
+	Register("substring", signature(types.String, types.String, types.Int, types.Int), substring)
+	Register("charAt", signature(types.String, types.String, types.Int), charAt)
+	Register("indexOf", signature(types.Int, types.String, types.String), indexOf)
+	Register("toUpper", signature(types.String, types.String), toUpper)
+	Register("toLower", signature(types.String, types.String), toLower)
+	Register("toString", signature(types.String, types.Int), toString)
+	Register("toInt", signature(types.Int, types.String), toInt)
+}
+
+func signature(result types.Type, params ...types.Type) *types.Function {
+	return types.NewFunction(params, result)
+}
+
+// length returns the number of characters in a string.
+func length(args []interface{}) (interface{}, error) {
+	return len([]rune(args[0].(string))), nil
+}
+
+// substring returns the characters of a string from a start index up to, but
+// not including, an end index.
+func substring(args []interface{}) (interface{}, error) {
+	s, start, end := []rune(args[0].(string)), args[1].(int), args[2].(int)
+
+	if start < 0 || end < start || end > len(s) {
+		return nil, fmt.Errorf("range %d..%d out of bounds for a string of length %d", start, end, len(s))
+	}
+
+	return string(s[start:end]), nil
+}
+
+// charAt returns the character at an index as a string of length one.
+func charAt(args []interface{}) (interface{}, error) {
+	s, i := []rune(args[0].(string)), args[1].(int)
+
+	if i < 0 || i >= len(s) {
+		return nil, fmt.Errorf("index %d out of bounds for a string of length %d", i, len(s))
+	}
+
+	return string(s[i]), nil
+}
+
+// indexOf returns the index of the first occurrence of a substring in a
+// string, or -1 if there is none.
+func indexOf(args []interface{}) (interface{}, error) {
+	s, substr := args[0].(string), args[1].(string)
+
+	i := strings.Index(s, substr)
+	if i < 0 {
+		return -1, nil
+	}
+
+	return len([]rune(s[:i])), nil
+}
+
+func toUpper(args []interface{}) (interface{}, error) {
+	return strings.ToUpper(args[0].(string)), nil
+}
+
+func toLower(args []interface{}) (interface{}, error) {
+	return strings.ToLower(args[0].(string)), nil
+}
+
+// toString returns the decimal representation of an integer.
+func toString(args []interface{}) (interface{}, error) {
+	return strconv.Itoa(args[0].(int)), nil
+}
+
+// toInt parses a decimal integer with an optional sign.
+func toInt(args []interface{}) (interface{}, error) {
+	s := args[0].(string)
+
+	x, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert %q to int", s)
+	}
+
+	return x, nil
+}
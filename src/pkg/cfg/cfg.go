@@ -0,0 +1,223 @@
+// Package cfg builds control-flow graphs of MiniPL programs. The nodes of the
+// graph are basic blocks of statements and the edges describe every way the
+// execution can move from one block to another, including loop back-edges
+// and the exits taken when an assertion fails.
+package cfg
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mjjs/minipl-go/pkg/ast"
+)
+
+// BlockKind tells what part of the program a block represents.
+type BlockKind int
+
+const (
+	// Entry is the empty block where the execution starts.
+	Entry BlockKind = iota
+	// Exit is the empty block reached when the program ends normally.
+	Exit
+	// AssertFailure is the empty block reached when an assertion fails.
+	AssertFailure
+	// Plain is a block of statements executed one after another.
+	Plain
+	// LoopHeader is a block holding a single for statement. It represents
+	// assigning the loop index and testing whether the loop continues.
+	LoopHeader
+)
+
+func (k BlockKind) String() string {
+	switch k {
+	case Entry:
+		return "entry"
+	case Exit:
+		return "exit"
+	case AssertFailure:
+		return "assert failure"
+	case LoopHeader:
+		return "loop header"
+	default:
+		return "plain"
+	}
+}
+
+// EdgeKind tells why control flows along an edge.
+type EdgeKind int
+
+const (
+	// Normal is an edge to the next block in the program order.
+	Normal EdgeKind = iota
+	// LoopEnter is an edge from a loop header into the loop body.
+	LoopEnter
+	// LoopExit is an edge from a loop header to the statements after the loop.
+	LoopExit
+	// BackEdge is an edge from the end of a loop body back to its header.
+	BackEdge
+	// AssertFailed is an edge from an assert statement to the assert failure block.
+	AssertFailed
+)
+
+func (k EdgeKind) String() string {
+	switch k {
+	case LoopEnter:
+		return "loop enter"
+	case LoopExit:
+		return "loop exit"
+	case BackEdge:
+		return "back edge"
+	case AssertFailed:
+		return "assert failed"
+	default:
+		return "normal"
+	}
+}
+
+// Edge is a directed edge of the control-flow graph.
+type Edge struct {
+	From *Block
+	To   *Block
+	Kind EdgeKind
+}
+
+// Block is a basic block of the control-flow graph. Only the last statement of
+// a block may transfer control elsewhere than to the next statement.
+type Block struct {
+	Index int
+	Kind  BlockKind
+	Stmts []ast.Stmt
+	Succs []*Edge
+	Preds []*Edge
+}
+
+// Graph is the control-flow graph of a program. AssertFailure is nil when the
+// program contains no assert statements.
+type Graph struct {
+	Entry         *Block
+	Exit          *Block
+	AssertFailure *Block
+	Blocks        []*Block
+}
+
+// New builds the control-flow graph of the given statements.
+func New(stmts ast.Stmts) *Graph {
+	b := &builder{graph: &Graph{}}
+
+	b.graph.Entry = b.newBlock(Entry)
+	b.graph.Exit = b.newBlock(Exit)
+
+	b.current = b.newBlock(Plain)
+	b.addEdge(b.graph.Entry, b.current, Normal)
+
+	stmts.Accept(b)
+
+	b.addEdge(b.current, b.graph.Exit, Normal)
+
+	return b.graph
+}
+
+// String returns a textual listing of the blocks and their successors.
+func (g *Graph) String() string {
+	var sb strings.Builder
+
+	for _, block := range g.Blocks {
+		fmt.Fprintf(&sb, "b%d (%s):\n", block.Index, block.Kind)
+
+		for _, stmt := range block.Stmts {
+			fmt.Fprintf(&sb, "\t%s\n", label(stmt))
+		}
+
+		for _, edge := range block.Succs {
+			fmt.Fprintf(&sb, "\t-> b%d (%s)\n", edge.To.Index, edge.Kind)
+		}
+	}
+
+	return sb.String()
+}
+
+// builder is an ast.Visitor that adds the visited statements to the current
+// block and starts new blocks where the control flow branches.
+type builder struct {
+	graph   *Graph
+	current *Block
+}
+
+func (b *builder) newBlock(kind BlockKind) *Block {
+	block := &Block{Index: len(b.graph.Blocks), Kind: kind}
+	b.graph.Blocks = append(b.graph.Blocks, block)
+	return block
+}
+
+func (b *builder) addEdge(from *Block, to *Block, kind EdgeKind) {
+	edge := &Edge{From: from, To: to, Kind: kind}
+	from.Succs = append(from.Succs, edge)
+	to.Preds = append(to.Preds, edge)
+}
+
+func (b *builder) add(stmt ast.Stmt) {
+	b.current.Stmts = append(b.current.Stmts, stmt)
+}
+
+func (b *builder) VisitProg(node ast.Prog) {
+	node.Statements.Accept(b)
+}
+
+func (b *builder) VisitStmts(node ast.Stmts) {
+	for _, stmt := range node.Statements {
+		stmt.Accept(b)
+	}
+}
+
+func (b *builder) VisitAssignStmt(node ast.AssignStmt) { b.add(node) }
+func (b *builder) VisitDeclStmt(node ast.DeclStmt)     { b.add(node) }
+func (b *builder) VisitReadStmt(node ast.ReadStmt)     { b.add(node) }
+func (b *builder) VisitPrintStmt(node ast.PrintStmt)   { b.add(node) }
+func (b *builder) VisitBadStmt(node ast.BadStmt)       { b.add(node) }
+
+// VisitImportStmt adds the statements of the imported file in place of the
+// import.
+func (b *builder) VisitImportStmt(node ast.ImportStmt) {
+	node.Statements.Accept(b)
+}
+
+func (b *builder) VisitForStmt(node ast.ForStmt) {
+	header := b.newBlock(LoopHeader)
+	header.Stmts = []ast.Stmt{node}
+	b.addEdge(b.current, header, Normal)
+
+	body := b.newBlock(Plain)
+	b.addEdge(header, body, LoopEnter)
+
+	b.current = body
+	node.Statements.Accept(b)
+	b.addEdge(b.current, header, BackEdge)
+
+	b.current = b.newBlock(Plain)
+	b.addEdge(header, b.current, LoopExit)
+}
+
+func (b *builder) VisitAssertStmt(node ast.AssertStmt) {
+	b.add(node)
+
+	if b.graph.AssertFailure == nil {
+		b.graph.AssertFailure = b.newBlock(AssertFailure)
+	}
+	b.addEdge(b.current, b.graph.AssertFailure, AssertFailed)
+
+	next := b.newBlock(Plain)
+	b.addEdge(b.current, next, Normal)
+	b.current = next
+}
+
+// Expressions do not affect the control flow.
+
+func (b *builder) VisitBinaryExpr(node ast.BinaryExpr)   {}
+func (b *builder) VisitUnaryExpr(node ast.UnaryExpr)     {}
+func (b *builder) VisitNullaryExpr(node ast.NullaryExpr) {}
+func (b *builder) VisitCallExpr(node ast.CallExpr)       {}
+func (b *builder) VisitBadExpr(node ast.BadExpr)         {}
+func (b *builder) VisitNumberOpnd(node ast.NumberOpnd)   {}
+func (b *builder) VisitStringOpnd(node ast.StringOpnd)   {}
+func (b *builder) VisitBoolOpnd(node ast.BoolOpnd)       {}
+func (b *builder) VisitIdent(node ast.Ident)             {}
@@ -0,0 +1,148 @@
+package cfg
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mjjs/minipl-go/pkg/ast"
+	"github.com/mjjs/minipl-go/pkg/lexer"
+	"github.com/mjjs/minipl-go/pkg/parser"
+)
+
+var graphTestCases = []struct {
+	name            string
+	sourceCode      string
+	expectedListing string
+}{
+	{
+		name:       "Straight-line program is a single block",
+		sourceCode: `var x : int := 1; print x;`,
+		expectedListing: `b0 (entry):
+	-> b2 (normal)
+b1 (exit):
+b2 (plain):
+	var x : int := 1;
+	print x;
+	-> b1 (normal)
+`,
+	},
+	{
+		name:       "Loops have a header and a back-edge",
+		sourceCode: `var n : int; read n; for i in 0..n do assert(i < 3); print "x"; end for; print n;`,
+		expectedListing: `b0 (entry):
+	-> b2 (normal)
+b1 (exit):
+b2 (plain):
+	var n : int;
+	read n;
+	-> b3 (normal)
+b3 (loop header):
+	for i in 0..n do
+	-> b4 (loop enter)
+	-> b7 (loop exit)
+b4 (plain):
+	assert(i < 3);
+	-> b5 (assert failed)
+	-> b6 (normal)
+b5 (assert failure):
+b6 (plain):
+	print "x";
+	-> b3 (back edge)
+b7 (plain):
+	print n;
+	-> b1 (normal)
+`,
+	},
+	{
+		name:       "Nested loops",
+		sourceCode: `for i in 0..2 do for j in 0..i do print j; end for; end for;`,
+		expectedListing: `b0 (entry):
+	-> b2 (normal)
+b1 (exit):
+b2 (plain):
+	-> b3 (normal)
+b3 (loop header):
+	for i in 0..2 do
+	-> b4 (loop enter)
+	-> b8 (loop exit)
+b4 (plain):
+	-> b5 (normal)
+b5 (loop header):
+	for j in 0..i do
+	-> b6 (loop enter)
+	-> b7 (loop exit)
+b6 (plain):
+	print j;
+	-> b5 (back edge)
+b7 (plain):
+	-> b3 (back edge)
+b8 (plain):
+	-> b1 (normal)
+`,
+	},
+}
+
+func TestNew(t *testing.T) {
+	for _, testCase := range graphTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			graph := New(parse(t, testCase.sourceCode))
+
+			if actual := graph.String(); actual != testCase.expectedListing {
+				t.Errorf("Expected:\n%s\ngot:\n%s", testCase.expectedListing, actual)
+			}
+
+			for _, block := range graph.Blocks {
+				for _, edge := range block.Preds {
+					if edge.To != block {
+						t.Errorf("Predecessor edge of b%d points to b%d", block.Index, edge.To.Index)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestWriteDot(t *testing.T) {
+	graph := New(parse(t, `for i in 0..2 do assert(i < 3); print "a\"b"; end for;`))
+
+	expected := `digraph cfg {
+	node [shape=box, fontname="monospace"];
+	b0 [label="entry", shape=oval];
+	b1 [label="exit", shape=oval];
+	b2 [label=""];
+	b3 [label="for i in 0..2 do\l"];
+	b4 [label="assert(i < 3);\l"];
+	b5 [label="assert failure", shape=oval, color=red];
+	b6 [label="print \"a\\\"b\";\l"];
+	b7 [label=""];
+	b0 -> b2;
+	b2 -> b3;
+	b3 -> b4 [label="do"];
+	b3 -> b7 [label="end"];
+	b4 -> b5 [label="assert failed", color=red];
+	b4 -> b6;
+	b6 -> b3 [style=dashed];
+	b7 -> b1;
+}
+`
+
+	w := &bytes.Buffer{}
+	if err := graph.WriteDot(w); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if w.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, w.String())
+	}
+}
+
+func parse(t *testing.T, sourceCode string) ast.Stmts {
+	t.Helper()
+
+	root, errors := parser.New(lexer.New(sourceCode)).Parse()
+	if len(errors) > 0 {
+		t.Fatalf("Failed to parse %s: %s", sourceCode, errors)
+	}
+
+	return root.Statements
+}
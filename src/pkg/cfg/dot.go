@@ -0,0 +1,71 @@
+package cfg
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mjjs/minipl-go/pkg/ast"
+	"github.com/mjjs/minipl-go/pkg/printer"
+)
+
+var edgeAttributes = map[EdgeKind]string{
+	Normal:       "",
+	LoopEnter:    ` [label="do"]`,
+	LoopExit:     ` [label="end"]`,
+	BackEdge:     ` [style=dashed]`,
+	AssertFailed: ` [label="assert failed", color=red]`,
+}
+
+// WriteDot writes the graph into w in the Graphviz DOT format.
+func (g *Graph) WriteDot(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "digraph cfg {")
+	fmt.Fprintln(bw, "\tnode [shape=box, fontname=\"monospace\"];")
+
+	for _, block := range g.Blocks {
+		switch block.Kind {
+		case Entry, Exit:
+			fmt.Fprintf(bw, "\tb%d [label=%q, shape=oval];\n", block.Index, block.Kind.String())
+		case AssertFailure:
+			fmt.Fprintf(bw, "\tb%d [label=%q, shape=oval, color=red];\n", block.Index, block.Kind.String())
+		default:
+			lines := make([]string, len(block.Stmts))
+			for i, stmt := range block.Stmts {
+				lines[i] = escape(label(stmt)) + `\l`
+			}
+			fmt.Fprintf(bw, "\tb%d [label=\"%s\"];\n", block.Index, strings.Join(lines, ""))
+		}
+	}
+
+	for _, block := range g.Blocks {
+		for _, edge := range block.Succs {
+			fmt.Fprintf(bw, "\tb%d -> b%d%s;\n", edge.From.Index, edge.To.Index, edgeAttributes[edge.Kind])
+		}
+	}
+
+	fmt.Fprintln(bw, "}")
+
+	return bw.Flush()
+}
+
+// label returns the text shown for a statement in a block. The body of a for
+// statement belongs to other blocks, so only its header is shown.
+func label(stmt ast.Stmt) string {
+	if node, ok := stmt.(ast.ForStmt); ok {
+		return fmt.Sprintf(
+			"for %s in %s..%s do",
+			printer.Sprint(node.Index), printer.Sprint(node.Low), printer.Sprint(node.High),
+		)
+	}
+
+	return printer.Sprint(stmt)
+}
+
+// escape escapes the characters that have a special meaning inside a quoted
+// DOT label.
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,53 @@
+// Package compile runs the front end of the compiler on a program: loading
+// and parsing it and then checking its symbols and types.
+package compile
+
+import (
+	"github.com/mjjs/minipl-go/pkg/ast"
+	"github.com/mjjs/minipl-go/pkg/builtin"
+	"github.com/mjjs/minipl-go/pkg/loader"
+	"github.com/mjjs/minipl-go/pkg/symboltable"
+	"github.com/mjjs/minipl-go/pkg/token"
+	"github.com/mjjs/minipl-go/pkg/typechecker"
+)
+
+// Options holds what a program can use without declaring it. The zero
+// Options gives a program the builtin functions only.
+type Options struct {
+	// Symbols holds the variables declared outside of the program, such as
+	// the variables of a host program. The program's own variables are
+	// inserted into it. A nil Symbols is empty.
+	Symbols *symboltable.SymbolTable
+	// Funcs holds the functions the program can call in addition to the
+	// builtin ones.
+	Funcs builtin.Set
+}
+
+// Check creates the symbol table of a parsed program and checks its types.
+// The symbol errors and the type errors are returned together.
+func Check(root ast.Prog, opts Options) (*symboltable.SymbolTable, []error) {
+	symbols := opts.Symbols
+	if symbols == nil {
+		symbols = symboltable.NewSymbolTable()
+	}
+
+	symbols, errors := (&symboltable.SymbolTableCreator{}).CreateIn(root, symbols)
+	_, typeErrors := typechecker.New(symbols).WithFunctions(opts.Funcs).CheckTypes(root)
+
+	return symbols, append(errors, typeErrors...)
+}
+
+// Load loads the program in path and the files it imports with loader.Load
+// and checks it. Only the syntax and import errors are returned if there are
+// any, as the symbols and types of a program that could not be parsed are not
+// checked.
+func Load(fset *token.FileSet, path string, opts Options) (ast.Prog, *symboltable.SymbolTable, []error) {
+	root, errors := loader.Load(fset, path)
+	if len(errors) > 0 {
+		return root, nil, errors
+	}
+
+	symbols, errors := Check(root, opts)
+
+	return root, symbols, errors
+}
@@ -0,0 +1,55 @@
+package compile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mjjs/minipl-go/pkg/builtin"
+	"github.com/mjjs/minipl-go/pkg/lexer"
+	"github.com/mjjs/minipl-go/pkg/parser"
+	"github.com/mjjs/minipl-go/pkg/symboltable"
+	"github.com/mjjs/minipl-go/pkg/token"
+	"github.com/mjjs/minipl-go/pkg/types"
+)
+
+func TestCheck(t *testing.T) {
+	root, errors := parser.New(lexer.New("var s := greet(name);\nprint x;\nvar n : int := \"a\";")).Parse()
+	if len(errors) > 0 {
+		t.Fatalf("Expected no syntax errors, got %v", errors)
+	}
+
+	symbols := symboltable.NewSymbolTable()
+	symbols.Insert("name", types.String)
+
+	funcs := builtin.Set{"greet": {
+		Name:      "greet",
+		Signature: types.NewFunction([]types.Type{types.String}, types.String),
+	}}
+
+	symbols, errors = Check(root, Options{Symbols: symbols, Funcs: funcs})
+
+	expected := "[2:7: variable x used before declaration 3:1: cannot assign type string to variable n of type int]"
+	if fmt.Sprint(errors) != expected {
+		t.Errorf("Expected errors %s, got %v", expected, errors)
+	}
+
+	if symbol, ok := symbols.Get("s"); !ok || symbol.Type() != types.String {
+		t.Errorf("Expected s to be inferred as a string, got %v", symbol)
+	}
+}
+
+func TestLoadReportsOnlySyntaxErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.mpl")
+	if err := os.WriteFile(path, []byte("print x;\nprint 1 +;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, symbols, errors := Load(token.NewFileSet(), path, Options{})
+
+	expected := "[" + path + ":2:10: syntax error: expected operand after '+', found ';']"
+	if symbols != nil || fmt.Sprint(errors) != expected {
+		t.Errorf("Expected errors %s, got %v", expected, errors)
+	}
+}
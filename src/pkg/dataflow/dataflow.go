@@ -0,0 +1,221 @@
+// Package dataflow implements data-flow analyses on the control-flow graphs
+// built by the cfg package, and uses them to find suspicious code such as
+// variables read before they are assigned and values that are never read.
+package dataflow
+
+import (
+	"github.com/mjjs/minipl-go/pkg/ast"
+	"github.com/mjjs/minipl-go/pkg/cfg"
+)
+
+// Set is a set of data-flow facts.
+type Set map[interface{}]struct{}
+
+func (s Set) copy() Set {
+	c := make(Set, len(s))
+	for x := range s {
+		c[x] = struct{}{}
+	}
+	return c
+}
+
+// union adds every fact of other into s and reports whether s grew.
+func (s Set) union(other Set) bool {
+	grew := false
+	for x := range other {
+		if _, ok := s[x]; !ok {
+			s[x] = struct{}{}
+			grew = true
+		}
+	}
+	return grew
+}
+
+// Contains reports whether x is in the set.
+func (s Set) Contains(x interface{}) bool {
+	_, ok := s[x]
+	return ok
+}
+
+// TransferFunc computes the facts at one end of a block from the facts at
+// the other end. It must not modify its argument.
+type TransferFunc func(block *cfg.Block, facts Set) Set
+
+// Solve solves a data-flow problem whose facts are combined with set union
+// at the points where control flow merges. Forward problems flow along the
+// edges of the graph and backward problems against them. The facts holding
+// at the start and at the end of every block are returned.
+func Solve(graph *cfg.Graph, forward bool, transfer TransferFunc) (in map[*cfg.Block]Set, out map[*cfg.Block]Set) {
+	in = make(map[*cfg.Block]Set, len(graph.Blocks))
+	out = make(map[*cfg.Block]Set, len(graph.Blocks))
+
+	for _, block := range graph.Blocks {
+		in[block] = Set{}
+		out[block] = Set{}
+	}
+
+	for changed := true; changed; {
+		changed = false
+
+		for _, block := range graph.Blocks {
+			if forward {
+				for _, edge := range block.Preds {
+					in[block].union(out[edge.From])
+				}
+
+				if out[block].union(transfer(block, in[block])) {
+					changed = true
+				}
+			} else {
+				for _, edge := range block.Succs {
+					out[block].union(in[edge.To])
+				}
+
+				if in[block].union(transfer(block, out[block])) {
+					changed = true
+				}
+			}
+		}
+	}
+
+	return in, out
+}
+
+// Definition is a statement that assigns a value to a variable. Declarations
+// without an initializer are implicit definitions: they assign the zero value
+// of the type of the variable.
+type Definition struct {
+	Name     string
+	Stmt     ast.Stmt
+	Implicit bool
+}
+
+// effect holds the variables read by a statement and the definition it makes.
+type effect struct {
+	uses []ast.Ident
+	def  *Definition
+}
+
+// Analysis holds the reaching definitions and the live variables of every
+// block of a control-flow graph.
+type Analysis struct {
+	Graph *cfg.Graph
+
+	// ReachingIn holds the definitions that reach the start of each block.
+	ReachingIn map[*cfg.Block]Set
+	// LiveOut holds the names of the variables live at the end of each block.
+	LiveOut map[*cfg.Block]Set
+
+	effects map[*cfg.Block][]effect
+}
+
+// Analyze computes the reaching definitions and the live variables of graph.
+func Analyze(graph *cfg.Graph) *Analysis {
+	a := &Analysis{
+		Graph:   graph,
+		effects: make(map[*cfg.Block][]effect),
+	}
+
+	for _, block := range graph.Blocks {
+		for _, stmt := range block.Stmts {
+			a.effects[block] = append(a.effects[block], effectOf(stmt))
+		}
+	}
+
+	a.ReachingIn, _ = Solve(graph, true, a.reachingDefinitions)
+	_, a.LiveOut = Solve(graph, false, a.liveVariables)
+
+	return a
+}
+
+func (a *Analysis) reachingDefinitions(block *cfg.Block, in Set) Set {
+	out := in.copy()
+	for _, e := range a.effects[block] {
+		if e.def != nil {
+			kill(out, e.def.Name)
+			out[e.def] = struct{}{}
+		}
+	}
+
+	return out
+}
+
+func (a *Analysis) liveVariables(block *cfg.Block, out Set) Set {
+	in := out.copy()
+	effects := a.effects[block]
+
+	for i := len(effects) - 1; i >= 0; i-- {
+		if effects[i].def != nil {
+			delete(in, effects[i].def.Name)
+		}
+		for _, use := range effects[i].uses {
+			in[use.Id.Value()] = struct{}{}
+		}
+	}
+
+	return in
+}
+
+// kill removes the definitions of the named variable from the set.
+func kill(defs Set, name string) {
+	for x := range defs {
+		if x.(*Definition).Name == name {
+			delete(defs, x)
+		}
+	}
+}
+
+// effectOf returns the variables read and the variable defined by stmt.
+func effectOf(stmt ast.Stmt) effect {
+	switch node := stmt.(type) {
+	case ast.DeclStmt:
+		e := effect{def: &Definition{
+			Name:     node.Identifier.Value(),
+			Stmt:     node,
+			Implicit: node.Expression == nil,
+		}}
+		if node.Expression != nil {
+			e.uses = identifiers(node.Expression)
+		}
+		return e
+
+	case ast.AssignStmt:
+		return effect{
+			uses: identifiers(node.Expression),
+			def:  &Definition{Name: node.Identifier.Id.Value(), Stmt: node},
+		}
+
+	case ast.ReadStmt:
+		return effect{
+			def: &Definition{Name: node.TargetIdentifier.Id.Value(), Stmt: node},
+		}
+
+	case ast.ForStmt:
+		return effect{
+			uses: append(identifiers(node.Low), identifiers(node.High)...),
+			def:  &Definition{Name: node.Index.Id.Value(), Stmt: node},
+		}
+
+	case ast.PrintStmt:
+		return effect{uses: identifiers(node.Expression)}
+
+	case ast.AssertStmt:
+		return effect{uses: identifiers(node.Expression)}
+	}
+
+	return effect{}
+}
+
+// identifiers returns the identifiers read in an expression.
+func identifiers(node ast.Node) []ast.Ident {
+	var idents []ast.Ident
+
+	ast.Inspect(node, func(n ast.Node) bool {
+		if ident, ok := n.(ast.Ident); ok {
+			idents = append(idents, ident)
+		}
+		return true
+	})
+
+	return idents
+}
@@ -0,0 +1,192 @@
+package dataflow
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mjjs/minipl-go/internal/testfiles"
+	"github.com/mjjs/minipl-go/pkg/ast"
+	"github.com/mjjs/minipl-go/pkg/cfg"
+	"github.com/mjjs/minipl-go/pkg/lexer"
+	"github.com/mjjs/minipl-go/pkg/loader"
+	"github.com/mjjs/minipl-go/pkg/parser"
+	"github.com/mjjs/minipl-go/pkg/token"
+)
+
+var checkTestCases = []struct {
+	name             string
+	sourceCode       string
+	expectedWarnings []string
+}{
+	{
+		name: "Factorial program has no warnings",
+		sourceCode: `print "Give a number";
+var n : int;
+read n;
+var v : int := 1;
+var i : int;
+for i in 1..n do
+	v := v * i;
+end for;
+print v;`,
+	},
+	{
+		name: "Read before assignment",
+		sourceCode: `var x : int;
+print x;
+x := 1;
+print x;`,
+		expectedWarnings: []string{
+			"2:7: warning: variable x is read before it is assigned",
+		},
+	},
+	{
+		name: "Read that may happen before assignment",
+		sourceCode: `var x : int;
+var n : int;
+var i : int;
+read n;
+for i in 0..n do
+	x := i;
+end for;
+print x;`,
+		expectedWarnings: []string{
+			"8:7: warning: variable x may be read before it is assigned",
+		},
+	},
+	{
+		name: "Unused variables",
+		sourceCode: `var x : int;
+var y : string := "a";
+y := "b";
+var z : bool;
+read z;`,
+		expectedWarnings: []string{
+			"1:1: warning: variable x is declared but never read",
+			"2:1: warning: variable y is declared but never read",
+			"4:1: warning: variable z is declared but never read",
+		},
+	},
+	{
+		name: "Dead stores",
+		sourceCode: `var x : int := 1;
+x := 2;
+print x;
+x := 3;`,
+		expectedWarnings: []string{
+			"1:1: warning: value assigned to x is never read",
+			"4:1: warning: value assigned to x is never read",
+		},
+	},
+	{
+		name: "Stores read by a later loop iteration are not dead",
+		sourceCode: `var sum : int := 0;
+var last : int := 0;
+var i : int := 0;
+for i in 0..10 do
+	print last;
+	last := i;
+	sum := sum + i;
+end for;
+print sum;`,
+		expectedWarnings: []string{
+			"3:1: warning: value assigned to i is never read",
+		},
+	},
+}
+
+func TestCheck(t *testing.T) {
+	for _, testCase := range checkTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			warnings := []string{}
+			for _, w := range Check(parse(t, testCase.sourceCode)) {
+				warnings = append(warnings, w.String())
+			}
+
+			expected := testCase.expectedWarnings
+			if expected == nil {
+				expected = []string{}
+			}
+
+			if !reflect.DeepEqual(warnings, expected) {
+				t.Errorf("Expected:\n%q\ngot:\n%q", expected, warnings)
+			}
+		})
+	}
+}
+
+func TestCheckSortsWarningsByFile(t *testing.T) {
+	dir := testfiles.Write(t, map[string]string{
+		"main.mpl": "var y : int;\nimport \"a.mpl\";",
+		"a.mpl":    "// A library.\n\nvar x : int;",
+	})
+
+	root, errors := loader.Load(token.NewFileSet(), filepath.Join(dir, "main.mpl"))
+	if len(errors) > 0 {
+		t.Fatalf("Failed to load: %s", errors)
+	}
+
+	var warnings []string
+	for _, w := range Check(root) {
+		warnings = append(warnings, strings.TrimPrefix(w.String(), dir+string(filepath.Separator)))
+	}
+
+	expected := []string{
+		"a.mpl:3:1: warning: variable x is declared but never read",
+		"main.mpl:1:1: warning: variable y is declared but never read",
+	}
+	if !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("Expected:\n%q\ngot:\n%q", expected, warnings)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	root := parse(t, `var x : int := 1;
+var n : int;
+read n;
+for i in 0..n do
+	x := x + i;
+end for;
+print x;`)
+
+	graph := cfg.New(root.Statements)
+	a := Analyze(graph)
+
+	header := graph.Blocks[3]
+	if header.Kind != cfg.LoopHeader {
+		t.Fatalf("Expected b3 to be the loop header, got %s", header.Kind)
+	}
+
+	reaching := map[string]int{}
+	for x := range a.ReachingIn[header] {
+		reaching[x.(*Definition).Name]++
+	}
+
+	expectedReaching := map[string]int{"x": 2, "n": 1, "i": 1}
+	if !reflect.DeepEqual(reaching, expectedReaching) {
+		t.Errorf("Expected definitions %v to reach the loop header, got %v", expectedReaching, reaching)
+	}
+
+	for _, name := range []string{"x", "n"} {
+		if !a.LiveOut[graph.Blocks[2]].Contains(name) {
+			t.Errorf("Expected %s to be live before the loop", name)
+		}
+	}
+
+	if a.LiveOut[graph.Blocks[2]].Contains("i") {
+		t.Errorf("Expected i not to be live before the loop")
+	}
+}
+
+func parse(t *testing.T, sourceCode string) ast.Prog {
+	t.Helper()
+
+	root, errors := parser.New(lexer.New(sourceCode)).Parse()
+	if len(errors) > 0 {
+		t.Fatalf("Failed to parse %s: %s", sourceCode, errors)
+	}
+
+	return root
+}
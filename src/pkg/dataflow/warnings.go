@@ -0,0 +1,159 @@
+package dataflow
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mjjs/minipl-go/pkg/ast"
+	"github.com/mjjs/minipl-go/pkg/cfg"
+	"github.com/mjjs/minipl-go/pkg/token"
+)
+
+// Warning is a diagnostic about a valid program that most likely does not do
+// what its author intended.
+type Warning struct {
+	Pos     token.Position
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: warning: %s", w.Pos, w.Message)
+}
+
+// Check analyses the program and returns warnings, ordered by position, for
+// variables read before any explicit assignment, variables that are never
+// read and assignments whose value is never read.
+func Check(root ast.Prog) []Warning {
+	a := Analyze(cfg.New(root.Statements))
+
+	var warnings []Warning
+	read := map[string]bool{}
+
+	for _, block := range a.Graph.Blocks {
+		for _, e := range a.effects[block] {
+			for _, use := range e.uses {
+				read[use.Id.Value()] = true
+			}
+		}
+	}
+
+	for _, block := range a.Graph.Blocks {
+		warnings = append(warnings, a.uninitializedReads(block)...)
+		warnings = append(warnings, a.deadStores(block, read)...)
+
+		for _, e := range a.effects[block] {
+			if e.def == nil || read[e.def.Name] {
+				continue
+			}
+
+			if decl, ok := e.def.Stmt.(ast.DeclStmt); ok {
+				warnings = append(warnings, Warning{
+					Pos:     decl.Position(),
+					Message: fmt.Sprintf("variable %s is declared but never read", e.def.Name),
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(warnings, func(i, j int) bool {
+		a, b := warnings[i].Pos, warnings[j].Pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+
+	return warnings
+}
+
+// uninitializedReads finds the variables read in the block that may still
+// hold the value given by a declaration without an initializer.
+func (a *Analysis) uninitializedReads(block *cfg.Block) []Warning {
+	var warnings []Warning
+	reaching := a.ReachingIn[block].copy()
+
+	for _, e := range a.effects[block] {
+		for _, use := range e.uses {
+			implicit, explicit := 0, 0
+
+			for x := range reaching {
+				def := x.(*Definition)
+				if def.Name != use.Id.Value() {
+					continue
+				}
+
+				if def.Implicit {
+					implicit++
+				} else {
+					explicit++
+				}
+			}
+
+			if implicit == 0 {
+				continue
+			}
+
+			message := "variable %s is read before it is assigned"
+			if explicit > 0 {
+				message = "variable %s may be read before it is assigned"
+			}
+
+			warnings = append(warnings, Warning{
+				Pos:     use.Position(),
+				Message: fmt.Sprintf(message, use.Id.Value()),
+			})
+		}
+
+		if e.def != nil {
+			kill(reaching, e.def.Name)
+			reaching[e.def] = struct{}{}
+		}
+	}
+
+	return warnings
+}
+
+// deadStores finds the assignments in the block whose value is overwritten or
+// left unused on every path. Variables that are never read at all are
+// reported as unused instead.
+func (a *Analysis) deadStores(block *cfg.Block, read map[string]bool) []Warning {
+	var warnings []Warning
+	live := a.LiveOut[block].copy()
+	effects := a.effects[block]
+
+	for i := len(effects) - 1; i >= 0; i-- {
+		e := effects[i]
+
+		if e.def != nil {
+			if !live.Contains(e.def.Name) && read[e.def.Name] && isStore(e.def) {
+				warnings = append(warnings, Warning{
+					Pos:     e.def.Stmt.Position(),
+					Message: fmt.Sprintf("value assigned to %s is never read", e.def.Name),
+				})
+			}
+
+			delete(live, e.def.Name)
+		}
+
+		for _, use := range e.uses {
+			live[use.Id.Value()] = struct{}{}
+		}
+	}
+
+	return warnings
+}
+
+// isStore reports whether the definition explicitly assigns the value of an
+// expression. Reads consume user input and loop indices are assigned by the
+// loop itself, so leaving them unused is not considered a mistake.
+func isStore(def *Definition) bool {
+	switch def.Stmt.(type) {
+	case ast.AssignStmt:
+		return true
+	case ast.DeclStmt:
+		return !def.Implicit
+	}
+
+	return false
+}
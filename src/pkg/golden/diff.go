@@ -0,0 +1,70 @@
+package golden
+
+import (
+	"strings"
+)
+
+// diff returns the lines of a and b that differ, prefixed with - for the
+// lines only in a and + for the lines only in b, together with the lines
+// they have in common, prefixed with a space. The lines in common are the
+// longest common subsequence of the lines of a and b.
+func diff(a, b string) string {
+	x, y := splitLines(a), splitLines(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and
+	// y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			switch {
+			case x[i] == y[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	out := &strings.Builder{}
+	i, j := 0, 0
+
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			out.WriteString(" " + x[i])
+			i++
+			j++
+		case j == len(y) || (i < len(x) && lcs[i+1][j] >= lcs[i][j+1]):
+			out.WriteString("-" + x[i])
+			i++
+		default:
+			out.WriteString("+" + y[j])
+			j++
+		}
+	}
+
+	return out.String()
+}
+
+// splitLines splits s into lines that end with a newline. A missing newline
+// at the end of s is marked, so that it shows up in the differences.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	lines := strings.SplitAfter(s, "\n")
+	if last := len(lines) - 1; lines[last] == "" {
+		lines = lines[:last]
+	} else {
+		lines[last] += "\n\\ No newline at end\n"
+	}
+
+	return lines
+}
@@ -0,0 +1,419 @@
+// Package golden tests MiniPL programs against the output and errors they are
+// expected to produce.
+//
+// The expectations of a program name.mpl are either in golden files next to
+// it: name.in holds the input, name.out the output and name.err the errors,
+// or in sections of the comments at the start of the program:
+//
+//	// Sums two numbers.
+//	//
+//	// Input:
+//	// 1
+//	// 2
+//	// Output: 3
+//	var a : int; var b : int;
+//	read a; read b;
+//	print a + b;
+//
+// The errors are everything the front end reports about the program: the
+// syntax, import, symbol and type errors, the warnings and the runtime error
+// that stops it. The directory of the program is left out of the file names
+// in the errors, so that the expectations do not depend on where the tests
+// are run from.
+package golden
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mjjs/minipl-go/pkg/ast"
+	"github.com/mjjs/minipl-go/pkg/compile"
+	"github.com/mjjs/minipl-go/pkg/dataflow"
+	"github.com/mjjs/minipl-go/pkg/interpreter"
+	"github.com/mjjs/minipl-go/pkg/token"
+)
+
+// The headers of the sections of the expectations in comments. The text
+// after a header on the same line is the first line of the section.
+const (
+	inputHeader  = "Input:"
+	outputHeader = "Output:"
+	errorsHeader = "Errors:"
+)
+
+// Test is a program and its expectations.
+type Test struct {
+	Path   string
+	Input  string
+	Output string
+	Errors string
+	// Embedded is true if the expectations are in the comments of the
+	// program. The trailing newlines of the output and the errors are then
+	// ignored, as a comment cannot end with one.
+	Embedded bool
+}
+
+// Find returns the tests in dir and its subdirectories in alphabetical order.
+// A program without golden files or sections in its comments is not a test,
+// so that the files imported by the tests can be kept next to them.
+func Find(dir string) ([]Test, error) {
+	var tests []Test
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != ".mpl" {
+			return err
+		}
+
+		test, ok, err := load(path)
+		if ok {
+			tests = append(tests, test)
+		}
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tests) == 0 {
+		return nil, fmt.Errorf("no tests in %s", dir)
+	}
+
+	return tests, nil
+}
+
+// load reads the expectations of the program in path and returns false if
+// there are none.
+func load(path string) (Test, bool, error) {
+	test := Test{Path: path}
+	found := false
+
+	for _, golden := range []struct {
+		ext  string
+		text *string
+	}{
+		{".in", &test.Input},
+		{".out", &test.Output},
+		{".err", &test.Errors},
+	} {
+		data, err := ioutil.ReadFile(goldenPath(path, golden.ext))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return test, false, err
+		}
+
+		*golden.text = string(data)
+		found = true
+	}
+
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return test, false, err
+	}
+
+	_, sections, _ := splitComments(string(src))
+	if len(sections) == 0 {
+		return test, found, nil
+	}
+
+	if found {
+		return test, false, fmt.Errorf("%s has both golden files and expectations in its comments", path)
+	}
+
+	test.Embedded = true
+
+	for _, s := range sections {
+		switch s.header {
+		case inputHeader:
+			test.Input = s.text() + "\n"
+		case outputHeader:
+			test.Output = s.text()
+		case errorsHeader:
+			test.Errors = s.text()
+		}
+	}
+
+	return test, true, nil
+}
+
+// Config controls how the programs are run. A zero Timeout means that there
+// is no time limit.
+type Config struct {
+	Timeout time.Duration
+	Limits  interpreter.Limits
+}
+
+// Result is what a program of a test produced.
+type Result struct {
+	Test   Test
+	Output string
+	Errors string
+	config Config
+}
+
+// Run runs the program of the test like the front end does: the program is
+// only run if it has no errors. A crash of the interpreter is reported in the
+// errors, so that it fails the test instead of stopping the run of the tests.
+func Run(test Test, config Config) Result {
+	out := &strings.Builder{}
+	errs := &strings.Builder{}
+
+	root, _, errors := compile.Load(token.NewFileSet(), test.Path, compile.Options{})
+
+	for _, err := range errors {
+		fmt.Fprintln(errs, err)
+	}
+
+	if len(errors) == 0 {
+		for _, warning := range dataflow.Check(root) {
+			fmt.Fprintln(errs, warning)
+		}
+
+		if err := interpret(root, test.Input, out, config); err != nil {
+			fmt.Fprintln(errs, err)
+		}
+	}
+
+	dir := filepath.Dir(test.Path) + string(filepath.Separator)
+
+	return Result{
+		Test:   test,
+		Output: out.String(),
+		Errors: strings.ReplaceAll(errs.String(), dir, ""),
+		config: config,
+	}
+}
+
+// interpret runs the program with input and writes its output to out. The
+// interpreter only recovers from runtime errors itself, so any other panic is
+// recovered here and returned as an internal error.
+func interpret(root ast.Prog, input string, out io.Writer, config Config) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("internal interpreter error: %v", r)
+		}
+	}()
+
+	ctx := context.Background()
+	if config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Timeout)
+		defer cancel()
+	}
+
+	return interpreter.New(out, strings.NewReader(input)).
+		WithContext(ctx).
+		WithLimits(config.Limits).
+		Run(root)
+}
+
+// Passed reports whether the program met the expectations of its test.
+func (r Result) Passed() bool {
+	output, errors := r.actual()
+	return output == r.Test.Output && errors == r.Test.Errors
+}
+
+// Diff returns the differences between the expected and the actual output and
+// errors, or an empty string if the test passed.
+func (r Result) Diff() string {
+	output, errors := r.actual()
+
+	b := &strings.Builder{}
+	if output != r.Test.Output {
+		fmt.Fprintf(b, "--- expected output\n+++ actual output\n%s", r.diff(r.Test.Output, output))
+	}
+	if errors != r.Test.Errors {
+		fmt.Fprintf(b, "--- expected errors\n+++ actual errors\n%s", r.diff(r.Test.Errors, errors))
+	}
+
+	return b.String()
+}
+
+// diff returns the differences between the expected and the actual text. The
+// missing newlines at the end of embedded expectations are not differences.
+func (r Result) diff(expected, actual string) string {
+	if r.Test.Embedded {
+		return diff(terminate(expected), terminate(actual))
+	}
+
+	return diff(expected, actual)
+}
+
+func terminate(text string) string {
+	if text == "" {
+		return text
+	}
+
+	return text + "\n"
+}
+
+// actual returns the output and the errors in the form they are compared to
+// the expectations in.
+func (r Result) actual() (string, string) {
+	if r.Test.Embedded {
+		return strings.TrimRight(r.Output, "\n"), strings.TrimRight(r.Errors, "\n")
+	}
+
+	return r.Output, r.Errors
+}
+
+// Update replaces the expectations of the test with what the program
+// produced. The golden file of the output is always written, but the one of
+// the errors is removed if there were none. The input is left as it is.
+func (r Result) Update() error {
+	if r.Test.Embedded {
+		if err := r.updateComments(); err != nil || r.Errors == "" {
+			return err
+		}
+
+		// Rewriting the comments moves the positions in the errors if the
+		// number of lines in the comments changed. The number of lines in the
+		// errors does not depend on the positions, so running the program
+		// again gives the final errors.
+		if again := Run(r.Test, r.config); again.Errors != r.Errors {
+			return again.updateComments()
+		}
+
+		return nil
+	}
+
+	if err := ioutil.WriteFile(goldenPath(r.Test.Path, ".out"), []byte(r.Output), 0644); err != nil {
+		return err
+	}
+
+	errPath := goldenPath(r.Test.Path, ".err")
+	if r.Errors != "" {
+		return ioutil.WriteFile(errPath, []byte(r.Errors), 0644)
+	}
+
+	if err := os.Remove(errPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// updateComments rewrites the sections of the output and the errors in the
+// comments of the program. The comments before the first section and the
+// input section are kept.
+func (r Result) updateComments() error {
+	src, err := ioutil.ReadFile(r.Test.Path)
+	if err != nil {
+		return err
+	}
+
+	prelude, sections, rest := splitComments(string(src))
+
+	lines := prelude
+	for _, s := range sections {
+		if s.header == inputHeader {
+			lines = append(lines, s.comment()...)
+		}
+	}
+
+	output, errors := r.actual()
+	lines = append(lines, newSection(outputHeader, output).comment()...)
+	if errors != "" {
+		lines = append(lines, newSection(errorsHeader, errors).comment()...)
+	}
+
+	updated := strings.Join(lines, "\n") + "\n" + rest
+
+	return ioutil.WriteFile(r.Test.Path, []byte(updated), 0644)
+}
+
+func goldenPath(path string, ext string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ext
+}
+
+// section is a section of the expectations in the comments of a program.
+type section struct {
+	header string
+	lines  []string
+}
+
+func newSection(header string, text string) section {
+	s := section{header: header}
+	if text != "" {
+		s.lines = strings.Split(text, "\n")
+	}
+
+	return s
+}
+
+func (s section) text() string {
+	return strings.Join(s.lines, "\n")
+}
+
+// comment returns the lines of the comment the section is written as. A
+// section of one line is written on the line of the header.
+func (s section) comment() []string {
+	if len(s.lines) == 1 {
+		return []string{strings.TrimRight("// "+s.header+" "+s.lines[0], " ")}
+	}
+
+	lines := []string{"// " + s.header}
+	for _, line := range s.lines {
+		lines = append(lines, strings.TrimRight("// "+line, " "))
+	}
+
+	return lines
+}
+
+// splitComments splits src into the lines of the comments at its start that
+// come before the first section, the sections and the rest of the source
+// code.
+func splitComments(src string) ([]string, []section, string) {
+	lines := strings.SplitAfter(src, "\n")
+
+	n := 0
+	for n < len(lines) && strings.HasPrefix(lines[n], "//") {
+		n++
+	}
+
+	var prelude []string
+	var sections []section
+
+	for _, line := range lines[:n] {
+		line = strings.TrimRight(line, "\r\n")
+		text := strings.TrimPrefix(strings.TrimPrefix(line, "//"), " ")
+
+		if header, first, ok := sectionHeader(text); ok {
+			sections = append(sections, section{header: header})
+			if first != "" {
+				sections[len(sections)-1].lines = []string{first}
+			}
+			continue
+		}
+
+		if len(sections) == 0 {
+			prelude = append(prelude, line)
+			continue
+		}
+
+		s := &sections[len(sections)-1]
+		s.lines = append(s.lines, text)
+	}
+
+	return prelude, sections, strings.Join(lines[n:], "")
+}
+
+// sectionHeader returns the header that text starts with and the rest of
+// text.
+func sectionHeader(text string) (string, string, bool) {
+	for _, header := range []string{inputHeader, outputHeader, errorsHeader} {
+		if strings.HasPrefix(text, header) {
+			return header, strings.TrimPrefix(strings.TrimPrefix(text, header), " "), true
+		}
+	}
+
+	return "", "", false
+}
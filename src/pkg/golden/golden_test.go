@@ -0,0 +1,221 @@
+package golden
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mjjs/minipl-go/internal/testfiles"
+	"github.com/mjjs/minipl-go/pkg/ast"
+	"github.com/mjjs/minipl-go/pkg/token"
+)
+
+const sum = `// Sums two numbers.
+//
+// Input:
+// 1
+// 2
+// Output: 3
+var a : int; var b : int;
+read a; read b;
+print a + b;
+`
+
+func TestFind(t *testing.T) {
+	dir := testfiles.Write(t, map[string]string{
+		"sum.mpl":          sum,
+		"lines/lines.mpl":  `print "a\nb\n";`,
+		"lines/lines.out":  "a\nb\n",
+		"errors/error.mpl": "print x;",
+		"errors/error.err": "error.mpl:1:7: variable x used before declaration\n",
+		"lib.mpl":          "var x : int;",
+	})
+
+	tests, err := Find(dir)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	expected := []Test{
+		{
+			Path:   filepath.Join(dir, "errors", "error.mpl"),
+			Errors: "error.mpl:1:7: variable x used before declaration\n",
+		},
+		{Path: filepath.Join(dir, "lines", "lines.mpl"), Output: "a\nb\n"},
+		{Path: filepath.Join(dir, "sum.mpl"), Input: "1\n2\n", Output: "3", Embedded: true},
+	}
+	if !reflect.DeepEqual(tests, expected) {
+		t.Fatalf("Expected:\n%+v\ngot:\n%+v", expected, tests)
+	}
+
+	for _, test := range tests {
+		if result := Run(test, Config{}); !result.Passed() {
+			t.Errorf("Expected %s to pass, got:\n%s", test.Path, result.Diff())
+		}
+	}
+}
+
+func TestFindErrors(t *testing.T) {
+	dir := testfiles.Write(t, map[string]string{"lib.mpl": "var x : int;"})
+	if _, err := Find(dir); err == nil || err.Error() != "no tests in "+dir {
+		t.Errorf("Expected an error for a directory without tests, got %v", err)
+	}
+
+	dir = testfiles.Write(t, map[string]string{"sum.mpl": sum, "sum.out": "3"})
+	expected := filepath.Join(dir, "sum.mpl") + " has both golden files and expectations in its comments"
+	if _, err := Find(dir); err == nil || err.Error() != expected {
+		t.Errorf("Expected error %s, got %v", expected, err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := testfiles.Write(t, map[string]string{
+		"warning.mpl": "var x : int;\nprint 1;",
+		"runtime.mpl": "print 1;\nassert(1 > 2);",
+		"looping.mpl": "var i : int; var x : int; for i in 0..1000000000 do x := i; end for; print x;",
+	})
+
+	testCases := []struct {
+		program        string
+		expectedOutput string
+		expectedErrors string
+	}{
+		{"warning.mpl", "1", "warning.mpl:1:1: warning: variable x is declared but never read\n"},
+		{"runtime.mpl", "1", "runtime.mpl:2:1: runtime error: assert failed\n"},
+		{
+			"looping.mpl",
+			"",
+			"looping.mpl:1:76: warning: variable x may be read before it is assigned\n" +
+				"looping.mpl:1:53: runtime error: context deadline exceeded\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		test := Test{Path: filepath.Join(dir, testCase.program)}
+		result := Run(test, Config{Timeout: 10 * time.Millisecond})
+
+		if result.Output != testCase.expectedOutput {
+			t.Errorf("Expected output %q, got %q", testCase.expectedOutput, result.Output)
+		}
+
+		if result.Errors != testCase.expectedErrors {
+			t.Errorf("Expected errors %q, got %q", testCase.expectedErrors, result.Errors)
+		}
+	}
+}
+
+func TestInterpretRecoversFromCrashes(t *testing.T) {
+	pos := token.Position{Line: 1, Column: 1}
+	root := ast.Prog{Statements: ast.Stmts{Statements: []ast.Stmt{
+		ast.PrintStmt{Expression: ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 1}}},
+		ast.BadStmt{Pos: pos, EndPos: pos},
+	}}}
+
+	out := &strings.Builder{}
+	err := interpret(root, "", out, Config{})
+
+	expected := "internal interpreter error: 1:1: cannot interpret a statement with syntax errors"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error %s, got %v", expected, err)
+	}
+
+	if out.String() != "1" {
+		t.Errorf("Expected the output before the crash, got %q", out)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	testCases := []struct {
+		result   Result
+		expected string
+	}{
+		{
+			result: Result{
+				Test:   Test{Output: "a\nb\nc\n"},
+				Output: "a\nc\nd\n",
+			},
+			expected: "--- expected output\n+++ actual output\n a\n-b\n c\n+d\n",
+		},
+		{
+			result: Result{
+				Test:   Test{Output: "1", Errors: "x\n"},
+				Output: "1\n",
+			},
+			expected: "--- expected output\n+++ actual output\n" +
+				"-1\n\\ No newline at end\n+1\n" +
+				"--- expected errors\n+++ actual errors\n-x\n",
+		},
+		{
+			result: Result{
+				Test:   Test{Output: "1\n2", Embedded: true},
+				Output: "1\n3\n",
+			},
+			expected: "--- expected output\n+++ actual output\n 1\n-2\n+3\n",
+		},
+		{
+			result: Result{
+				Test:   Test{Output: "1", Embedded: true},
+				Output: "1\n\n",
+			},
+			expected: "",
+		},
+	}
+
+	for _, testCase := range testCases {
+		if actual := testCase.result.Diff(); actual != testCase.expected {
+			t.Errorf("Expected:\n%s\ngot:\n%s", testCase.expected, actual)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	dir := testfiles.Write(t, map[string]string{
+		"sum.mpl":    sum,
+		"lines.mpl":  "print \"a\\nb\\n\";\nprint x;",
+		"lines.in":   "unused\n",
+		"lines.out":  "",
+		"fixed.mpl":  "print 1;",
+		"fixed.err":  "fixed.mpl:1:7: variable x used before declaration\n",
+		"errors.mpl": "// A comment that is kept.\n// Output: 1\n// Errors:\n// none\nprint x;",
+	})
+
+	for _, name := range []string{"sum.mpl", "lines.mpl", "fixed.mpl", "errors.mpl"} {
+		test, _, err := load(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+
+		test.Output = "wrong"
+		if err := Run(test, Config{}).Update(); err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+	}
+
+	expected := map[string]string{
+		"sum.mpl":    "// Sums two numbers.\n//\n// Input:\n// 1\n// 2\n// Output: 3\nvar a : int; var b : int;\nread a; read b;\nprint a + b;\n",
+		"lines.in":   "unused\n",
+		"lines.out":  "",
+		"lines.err":  "lines.mpl:2:7: variable x used before declaration\n",
+		"fixed.out":  "1",
+		"errors.mpl": "// A comment that is kept.\n// Output:\n// Errors: errors.mpl:4:7: variable x used before declaration\nprint x;",
+	}
+
+	for name, content := range expected {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("Expected %s to be written, got %s", name, err)
+			continue
+		}
+
+		if string(data) != content {
+			t.Errorf("Expected %s to be:\n%q\ngot:\n%q", name, content, data)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "fixed.err")); !os.IsNotExist(err) {
+		t.Errorf("Expected the golden file of the errors to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,348 @@
+// Package grader runs a set of programs against test cases of expected input
+// and output and scores them, for example to grade programming assignments.
+package grader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mjjs/minipl-go/pkg/ast"
+	"github.com/mjjs/minipl-go/pkg/compile"
+	"github.com/mjjs/minipl-go/pkg/interpreter"
+	"github.com/mjjs/minipl-go/pkg/lexer"
+	"github.com/mjjs/minipl-go/pkg/parser"
+	"github.com/mjjs/minipl-go/pkg/token"
+)
+
+// Case is a test case: the input given to a program and the output expected
+// from it.
+type Case struct {
+	Name     string
+	Input    string
+	Expected string
+}
+
+// LoadCases reads the test cases in dir. A test case is a file name.out that
+// holds the expected output, and an optional file name.in that holds the
+// input. A case without an input file gets an empty input.
+func LoadCases(dir string) ([]Case, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var cases []Case
+
+	for _, entry := range entries {
+		name := entry.Name()
+
+		switch filepath.Ext(name) {
+		case ".out":
+			expected, err := ioutil.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				return nil, err
+			}
+
+			c := Case{Name: strings.TrimSuffix(name, ".out"), Expected: string(expected)}
+
+			input, err := ioutil.ReadFile(filepath.Join(dir, c.Name+".in"))
+			if err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+			c.Input = string(input)
+
+			cases = append(cases, c)
+
+		case ".in":
+			if _, err := os.Stat(filepath.Join(dir, strings.TrimSuffix(name, ".in")+".out")); err != nil {
+				return nil, fmt.Errorf("test case %s has no expected output", filepath.Join(dir, name))
+			}
+		}
+	}
+
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("no test cases in %s", dir)
+	}
+
+	return cases, nil
+}
+
+// Config controls how the programs are run. A zero Timeout or Jobs means
+// that there is no time limit or that as many programs are run at a time as
+// there are CPUs.
+type Config struct {
+	// Timeout is the time limit of each run.
+	Timeout time.Duration
+	// Limits bounds the resources of each run.
+	Limits interpreter.Limits
+	// Normalize compares the outputs as sequences of words, ignoring
+	// differences in whitespace.
+	Normalize bool
+	// Jobs is the number of runs that may be in progress at a time.
+	Jobs int
+}
+
+// Status is the outcome of a run.
+type Status string
+
+const (
+	Passed        Status = "passed"
+	WrongOutput   Status = "wrong output"
+	RuntimeError  Status = "runtime error"
+	Timeout       Status = "timeout"
+	LimitExceeded Status = "limit exceeded"
+	CompileError  Status = "compile error"
+)
+
+// Result is the outcome of running a program on a test case. The output and
+// the error are only kept for the runs that did not pass.
+type Result struct {
+	Case   string `json:"case"`
+	Status Status `json:"status"`
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ProgramReport holds the results of a program. Errors holds the syntax,
+// symbol and type errors of a program that could not be run, and its imports,
+// which are not allowed.
+type ProgramReport struct {
+	Program string   `json:"program"`
+	Passed  int      `json:"passed"`
+	Total   int      `json:"total"`
+	Errors  []string `json:"errors,omitempty"`
+	Results []Result `json:"results"`
+}
+
+// Report holds the results of all the programs in the order they were given.
+type Report struct {
+	Programs []ProgramReport `json:"programs"`
+	Passed   int             `json:"passed"`
+	Total    int             `json:"total"`
+}
+
+// Programs returns the paths of the MiniPL programs, the files ending in
+// .mpl, in dir in alphabetical order.
+func Programs(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.mpl"))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no programs in %s", dir)
+	}
+
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+// Grade runs every program on every test case concurrently and reports the
+// results. A program with errors fails every test case without being run. The
+// programs are untrusted, so a program that crashes the compiler or the
+// interpreter only fails its own test cases: as a compile error or as a
+// runtime error.
+func Grade(paths []string, cases []Case, config Config) *Report {
+	report := &Report{Programs: make([]ProgramReport, len(paths))}
+
+	type run struct {
+		root   ast.Prog
+		c      Case
+		result *Result
+	}
+
+	var runs []run
+
+	for i, path := range paths {
+		program := &report.Programs[i]
+		program.Program = filepath.Base(path)
+		program.Total = len(cases)
+		program.Results = make([]Result, len(cases))
+
+		root, errors := check(path)
+		for _, err := range errors {
+			program.Errors = append(program.Errors, err.Error())
+		}
+
+		for j, c := range cases {
+			program.Results[j] = Result{Case: c.Name, Status: CompileError}
+			if len(errors) == 0 {
+				runs = append(runs, run{root: root, c: c, result: &program.Results[j]})
+			}
+		}
+	}
+
+	jobs := config.Jobs
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+
+	queue := make(chan run)
+	var wg sync.WaitGroup
+
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range queue {
+				*r.result = execute(r.root, r.c, config)
+			}
+		}()
+	}
+
+	for _, r := range runs {
+		queue <- r
+	}
+	close(queue)
+	wg.Wait()
+
+	for i := range report.Programs {
+		program := &report.Programs[i]
+		for _, result := range program.Results {
+			if result.Status == Passed {
+				program.Passed++
+			}
+		}
+
+		report.Passed += program.Passed
+		report.Total += program.Total
+	}
+
+	return report
+}
+
+// check parses the program in path and checks it. Imports are reported as
+// errors instead of being loaded, as they would let a program read any file
+// the grader can, such as the other programs.
+func check(path string) (root ast.Prog, errors []error) {
+	defer func() {
+		if r := recover(); r != nil {
+			errors = []error{fmt.Errorf("%s: internal compiler error: %v", path, r)}
+		}
+	}()
+
+	source, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ast.Prog{}, []error{err}
+	}
+
+	fset := token.NewFileSet()
+	root, errors = parser.New(lexer.NewFile(fset.AddFile(path, string(source)))).Parse()
+
+	ast.Inspect(root, func(node ast.Node) bool {
+		if imp, ok := node.(ast.ImportStmt); ok {
+			errors = append(errors, token.Errorf(imp.Pos, "import is not allowed in graded programs"))
+		}
+
+		return true
+	})
+
+	if len(errors) > 0 {
+		return root, errors
+	}
+
+	_, errors = compile.Check(root, compile.Options{})
+
+	return root, errors
+}
+
+// execute runs a checked program on a test case.
+func execute(root ast.Prog, c Case, config Config) (result Result) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = Result{Case: c.Name, Status: RuntimeError, Error: fmt.Sprintf("internal interpreter error: %v", r)}
+		}
+	}()
+
+	ctx := context.Background()
+	if config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Timeout)
+		defer cancel()
+	}
+
+	out := &strings.Builder{}
+	err := interpreter.New(out, strings.NewReader(c.Input)).
+		WithContext(ctx).
+		WithLimits(config.Limits).
+		Run(root)
+
+	result = Result{Case: c.Name, Status: Passed}
+
+	switch {
+	case err == nil:
+		if !sameOutput(out.String(), c.Expected, config.Normalize) {
+			result.Status = WrongOutput
+		}
+	case errors.Is(err, context.DeadlineExceeded):
+		result.Status = Timeout
+	case errors.Is(err, interpreter.ErrStepLimit),
+		errors.Is(err, interpreter.ErrOutputLimit),
+		errors.Is(err, interpreter.ErrStringLimit):
+		result.Status = LimitExceeded
+	default:
+		result.Status = RuntimeError
+	}
+
+	if result.Status != Passed {
+		result.Output = out.String()
+		if err != nil {
+			result.Error = err.Error()
+		}
+	}
+
+	return result
+}
+
+func sameOutput(actual, expected string, normalize bool) bool {
+	if normalize {
+		return strings.Join(strings.Fields(actual), " ") == strings.Join(strings.Fields(expected), " ")
+	}
+
+	return actual == expected
+}
+
+// WriteText writes the report in a human-readable form: the score of every
+// program followed by the test cases it did not pass, and the total score.
+func (r *Report) WriteText(w io.Writer) {
+	width := 0
+	for _, program := range r.Programs {
+		if len(program.Program) > width {
+			width = len(program.Program)
+		}
+	}
+
+	for _, program := range r.Programs {
+		fmt.Fprintf(w, "%-*s  %d/%d\n", width, program.Program, program.Passed, program.Total)
+
+		if len(program.Errors) > 0 {
+			for _, err := range program.Errors {
+				fmt.Fprintf(w, "    %s\n", err)
+			}
+			continue
+		}
+
+		for _, result := range program.Results {
+			switch result.Status {
+			case Passed:
+			case WrongOutput:
+				fmt.Fprintf(w, "    %s: %s\n", result.Case, result.Status)
+			default:
+				fmt.Fprintf(w, "    %s: %s: %s\n", result.Case, result.Status, result.Error)
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "%-*s  %d/%d\n", width, "total", r.Passed, r.Total)
+}
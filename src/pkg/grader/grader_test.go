@@ -0,0 +1,241 @@
+package grader
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mjjs/minipl-go/internal/testfiles"
+	"github.com/mjjs/minipl-go/pkg/interpreter"
+)
+
+func TestLoadCases(t *testing.T) {
+	dir := testfiles.Write(t, map[string]string{
+		"sum.in":    "1\n2\n",
+		"sum.out":   "3\n",
+		"empty.out": "0\n",
+		"notes.txt": "not a test case",
+	})
+
+	cases, err := LoadCases(dir)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	expected := []Case{
+		{Name: "empty", Expected: "0\n"},
+		{Name: "sum", Input: "1\n2\n", Expected: "3\n"},
+	}
+	if !reflect.DeepEqual(cases, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, cases)
+	}
+}
+
+func TestLoadCasesErrors(t *testing.T) {
+	testCases := []struct {
+		files         map[string]string
+		expectedError string
+	}{
+		{map[string]string{"a.in": "1\n"}, "test case {dir}/a.in has no expected output"},
+		{map[string]string{}, "no test cases in {dir}"},
+	}
+
+	for _, testCase := range testCases {
+		dir := testfiles.Write(t, testCase.files)
+
+		expected := strings.ReplaceAll(testCase.expectedError, "{dir}", dir)
+		if _, err := LoadCases(dir); err == nil || err.Error() != expected {
+			t.Errorf("Expected error %s, got %v", expected, err)
+		}
+	}
+}
+
+func TestGrade(t *testing.T) {
+	programs := testfiles.Write(t, map[string]string{
+		"correct.mpl": "var a : int; var b : int; read a; read b; print a + b; print \"\\n\";",
+		"spaces.mpl":  "var a : int; var b : int; read a; read b; print a + b; print \"  \";",
+		"failing.mpl": "var a : int; read a; assert(a > 0); print a;",
+		"looping.mpl": "var i : int; var x : int; for i in 0..1000000000 do x := i; end for;",
+		"broken.mpl":  "print x;",
+	})
+	cases := []Case{
+		{Name: "positive", Input: "1\n2\n", Expected: "3\n"},
+		{Name: "zero", Input: "0\n0\n", Expected: "0\n"},
+	}
+
+	paths, err := Programs(programs)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	config := Config{
+		Timeout: time.Minute,
+		Limits:  interpreter.Limits{MaxSteps: 1000},
+		Jobs:    3,
+	}
+
+	report := Grade(paths, cases, config)
+
+	expected := &Report{
+		Programs: []ProgramReport{
+			{
+				Program: "broken.mpl",
+				Total:   2,
+				Errors:  []string{filepath.Join(programs, "broken.mpl") + ":1:7: variable x used before declaration"},
+				Results: []Result{{Case: "positive", Status: CompileError}, {Case: "zero", Status: CompileError}},
+			},
+			{
+				Program: "correct.mpl",
+				Passed:  2,
+				Total:   2,
+				Results: []Result{{Case: "positive", Status: Passed}, {Case: "zero", Status: Passed}},
+			},
+			{
+				Program: "failing.mpl",
+				Total:   2,
+				Results: []Result{
+					{Case: "positive", Status: WrongOutput, Output: "1"},
+					{
+						Case:   "zero",
+						Status: RuntimeError,
+						Error:  filepath.Join(programs, "failing.mpl") + ":1:22: runtime error: assert failed",
+					},
+				},
+			},
+			{
+				Program: "looping.mpl",
+				Total:   2,
+				Results: []Result{
+					{
+						Case:   "positive",
+						Status: LimitExceeded,
+						Error:  filepath.Join(programs, "looping.mpl") + ":1:53: runtime error: step limit exceeded (1000 statements)",
+					},
+					{
+						Case:   "zero",
+						Status: LimitExceeded,
+						Error:  filepath.Join(programs, "looping.mpl") + ":1:53: runtime error: step limit exceeded (1000 statements)",
+					},
+				},
+			},
+			{
+				Program: "spaces.mpl",
+				Total:   2,
+				Results: []Result{
+					{Case: "positive", Status: WrongOutput, Output: "3  "},
+					{Case: "zero", Status: WrongOutput, Output: "0  "},
+				},
+			},
+		},
+		Passed: 2,
+		Total:  10,
+	}
+
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("Expected:\n%+v\ngot:\n%+v", expected, report)
+	}
+
+	config.Normalize = true
+	if report := Grade(paths, cases, config); report.Programs[4].Passed != 2 {
+		t.Errorf("Expected the outputs to match with normalized whitespace, got %+v", report.Programs[4])
+	}
+}
+
+func TestGradeRejectsImports(t *testing.T) {
+	programs := testfiles.Write(t, map[string]string{
+		"importing.mpl": "import \"other.mpl\";\nimport \"/dev/zero\";\nprint answer;",
+		"other.mpl":     "var answer : int := 3;",
+	})
+	cases := []Case{{Name: "positive", Input: "1\n2\n", Expected: "3"}}
+
+	report := Grade([]string{filepath.Join(programs, "importing.mpl")}, cases, Config{})
+
+	path := filepath.Join(programs, "importing.mpl")
+	expected := []string{
+		path + ":1:1: import is not allowed in graded programs",
+		path + ":2:1: import is not allowed in graded programs",
+	}
+	if actual := report.Programs[0].Errors; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected:\n%q\ngot:\n%q", expected, actual)
+	}
+
+	if report.Passed != 0 {
+		t.Errorf("Expected the program to fail, got %+v", report.Programs[0])
+	}
+}
+
+func TestGradeTimeout(t *testing.T) {
+	programs := testfiles.Write(t, map[string]string{
+		"looping.mpl": "var i : int; var x : int; for i in 0..1000000000 do x := i; end for;",
+	})
+
+	report := Grade(
+		[]string{filepath.Join(programs, "looping.mpl")},
+		[]Case{{Name: "forever"}},
+		Config{Timeout: 10 * time.Millisecond},
+	)
+
+	if status := report.Programs[0].Results[0].Status; status != Timeout {
+		t.Errorf("Expected a timeout, got %s", status)
+	}
+}
+
+func TestGradeEmptyStrings(t *testing.T) {
+	programs := testfiles.Write(t, map[string]string{
+		"empty.mpl": "print \"\"; print ``; print 1;",
+		"other.mpl": "print 1;",
+	})
+
+	paths, err := Programs(programs)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	report := Grade(paths, []Case{{Name: "one", Expected: "1"}}, Config{})
+
+	if report.Passed != 2 || report.Total != 2 {
+		t.Errorf("Expected both programs to pass, got %+v", report.Programs)
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	report := &Report{
+		Programs: []ProgramReport{
+			{
+				Program: "alice.mpl",
+				Passed:  1,
+				Total:   3,
+				Results: []Result{
+					{Case: "a", Status: Passed},
+					{Case: "b", Status: WrongOutput, Output: "2"},
+					{Case: "c", Status: Timeout, Error: "1:1: runtime error: context deadline exceeded"},
+				},
+			},
+			{
+				Program: "bob.mpl",
+				Total:   3,
+				Errors:  []string{"bob.mpl:1:7: variable x used before declaration"},
+			},
+		},
+		Passed: 1,
+		Total:  6,
+	}
+
+	expected := `alice.mpl  1/3
+    b: wrong output
+    c: timeout: 1:1: runtime error: context deadline exceeded
+bob.mpl    0/3
+    bob.mpl:1:7: variable x used before declaration
+total      1/6
+`
+
+	w := &bytes.Buffer{}
+	report.WriteText(w)
+
+	if w.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, w)
+	}
+}
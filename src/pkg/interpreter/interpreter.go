@@ -2,31 +2,76 @@ package interpreter
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"os"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/mjjs/minipl-go/pkg/ast"
+	"github.com/mjjs/minipl-go/pkg/builtin"
 	"github.com/mjjs/minipl-go/pkg/stack"
 	"github.com/mjjs/minipl-go/pkg/token"
+	"github.com/mjjs/minipl-go/pkg/types"
 )
 
 type Interpreter struct {
 	stack *stack.Stack
 
 	variables    map[string]interface{}
+	funcs        builtin.Set
 	outputWriter io.Writer
-	inputReader  io.Reader
+	inputReader  *bufio.Reader
+
+	ctx    context.Context
+	limits Limits
+	steps  int
+	output int
+}
+
+// Limits bounds the resources a program may use, so that programs that cannot
+// be trusted can be run safely. A zero limit means that there is no limit.
+type Limits struct {
+	// MaxSteps is the number of statements that may be executed.
+	MaxSteps int
+	// MaxOutputBytes is the number of bytes that may be printed.
+	MaxOutputBytes int
+	// MaxStringBytes is the length in bytes of the longest string that may
+	// be created by joining strings, by reading user input or by a function.
+	MaxStringBytes int
+}
+
+// The errors wrapped by the RuntimeError of a program that exceeds a limit.
+var (
+	ErrStepLimit   = errors.New("step limit exceeded")
+	ErrOutputLimit = errors.New("output limit exceeded")
+	ErrStringLimit = errors.New("string length limit exceeded")
+)
+
+// RuntimeError is an error that stops a program, such as a failing
+// assertion, an exceeded limit or the cancellation of the context of the
+// interpreter. Pos is the position of the statement or the expression that
+// failed.
+type RuntimeError struct {
+	Pos token.Position
+	Err error
 }
 
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("%s: runtime error: %s", e.Pos, e.Err)
+}
+
+func (e *RuntimeError) Unwrap() error { return e.Err }
+
 func New(outputWriter io.Writer, inputReader io.Reader) *Interpreter {
 	return &Interpreter{
 		stack:        stack.New(),
 		variables:    make(map[string]interface{}),
 		outputWriter: outputWriter,
-		inputReader:  inputReader,
+		inputReader:  bufio.NewReader(inputReader),
+		ctx:          context.Background(),
 	}
 }
 
@@ -35,11 +80,61 @@ func NewWithOutputWriter(output io.Writer) *Interpreter {
 		stack:        stack.New(),
 		variables:    make(map[string]interface{}),
 		outputWriter: output,
+		ctx:          context.Background(),
 	}
 }
 
-func (i *Interpreter) Run(program ast.Prog) {
+// WithContext makes the program stop with a runtime error wrapping the error
+// of ctx when ctx is done. The context is checked before every statement.
+func (i *Interpreter) WithContext(ctx context.Context) *Interpreter {
+	i.ctx = ctx
+	return i
+}
+
+// WithLimits makes the program stop with a runtime error when it exceeds one
+// of the limits.
+func (i *Interpreter) WithLimits(limits Limits) *Interpreter {
+	i.limits = limits
+	return i
+}
+
+// WithFunctions makes the functions in funcs callable in addition to the
+// builtin ones.
+func (i *Interpreter) WithFunctions(funcs builtin.Set) *Interpreter {
+	i.funcs = funcs
+	return i
+}
+
+// SetVariable sets the value of a variable before the program is run, so that
+// a variable the program does not declare can be given to it.
+func (i *Interpreter) SetVariable(name string, value interface{}) {
+	i.variables[name] = value
+}
+
+// Variable returns the value of a variable, for example after the program
+// has been run.
+func (i *Interpreter) Variable(name string) (interface{}, bool) {
+	value, ok := i.variables[name]
+	return value, ok
+}
+
+// Run runs the program. A runtime error, such as a failing assertion, stops
+// the program and is returned.
+func (i *Interpreter) Run(program ast.Prog) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			stop, ok := r.(*RuntimeError)
+			if !ok {
+				panic(r)
+			}
+
+			err = stop
+		}
+	}()
+
 	program.Accept(i)
+
+	return nil
 }
 
 func (i *Interpreter) VisitProg(node ast.Prog) {
@@ -48,10 +143,59 @@ func (i *Interpreter) VisitProg(node ast.Prog) {
 
 func (i *Interpreter) VisitStmts(node ast.Stmts) {
 	for _, stmt := range node.Statements {
+		i.step(stmt)
 		stmt.Accept(i)
 	}
 }
 
+// step counts the execution of a statement and stops the program if the
+// context is done or there are no steps left.
+func (i *Interpreter) step(stmt ast.Stmt) {
+	select {
+	case <-i.ctx.Done():
+		i.terminate(stmt.Position(), i.ctx.Err())
+	default:
+	}
+
+	i.steps++
+	if i.limits.MaxSteps > 0 && i.steps > i.limits.MaxSteps {
+		i.terminate(stmt.Position(), fmt.Errorf("%w (%d statements)", ErrStepLimit, i.limits.MaxSteps))
+	}
+}
+
+// checkString stops the program if a string created at pos is longer than
+// allowed.
+func (i *Interpreter) checkString(pos token.Position, value interface{}) {
+	if s, ok := value.(string); ok {
+		i.checkLength(pos, len(s))
+	}
+}
+
+// checkLength stops the program if a string of n bytes created at pos is
+// longer than allowed.
+func (i *Interpreter) checkLength(pos token.Position, n int) {
+	if i.limits.MaxStringBytes > 0 && n > i.limits.MaxStringBytes {
+		i.terminate(pos, fmt.Errorf("%w (%d bytes)", ErrStringLimit, i.limits.MaxStringBytes))
+	}
+}
+
+// readLine reads a line of input for the read statement at pos, including
+// its newline. The line is checked against the limit of strings as it is
+// read, so that a long line stops the program before it is held in memory.
+func (i *Interpreter) readLine(pos token.Position) (string, error) {
+	var line []byte
+
+	for {
+		chunk, err := i.inputReader.ReadSlice('\n')
+		line = append(line, chunk...)
+		i.checkLength(pos, len(line))
+
+		if err != bufio.ErrBufferFull {
+			return string(line), err
+		}
+	}
+}
+
 func (i *Interpreter) VisitAssignStmt(node ast.AssignStmt) {
 	varName := node.Identifier.Id.Value()
 	node.Expression.Accept(i)
@@ -67,19 +211,25 @@ func (i *Interpreter) VisitDeclStmt(node ast.DeclStmt) {
 		node.Expression.Accept(i)
 		value = i.stack.Pop()
 	} else {
-		typ := node.VariableType.Type()
-		if typ == token.INTEGER {
-			value = 0
-		} else if typ == token.STRING {
-			value = ""
-		} else {
-			value = false
-		}
+		value = zeroValue(types.FromToken(node.VariableType.Type()))
 	}
 
 	i.variables[varName] = value
 }
 
+// zeroValue returns the value of a variable of type t declared without an
+// initializer.
+func zeroValue(t types.Type) interface{} {
+	switch {
+	case types.Identical(t, types.Int):
+		return 0
+	case types.Identical(t, types.String):
+		return ""
+	default:
+		return false
+	}
+}
+
 func (i *Interpreter) VisitForStmt(node ast.ForStmt) {
 	idx := node.Index.Id.Value()
 
@@ -100,44 +250,74 @@ func (i *Interpreter) VisitReadStmt(node ast.ReadStmt) {
 
 	x := i.variables[varName]
 
-	r := bufio.NewReader(i.inputReader)
-
 	if _, ok := x.(int); ok {
-		str, _ := r.ReadString('\n')
+		str, _ := i.readLine(node.Position())
 		x, err := strconv.Atoi(strings.Trim(str, "\n"))
 		if err != nil {
-			i.terminate(fmt.Sprintf("%s: runtime error: failed to parse integer", node.Position()))
+			i.terminate(node.Position(), errors.New("failed to parse integer"))
 		}
 		i.variables[varName] = x
 	} else if _, ok := x.(string); ok {
-		x, err := r.ReadString('\n')
+		x, err := i.readLine(node.Position())
 		if err != nil {
-			i.terminate(fmt.Sprintf("%s: runtime error: failed to parse string", node.Position()))
+			i.terminate(node.Position(), errors.New("failed to parse string"))
 		}
 		i.variables[varName] = x
 	} else {
-		i.terminate(fmt.Sprintf("%s: runtime error: could not read user input", node.Position()))
+		i.terminate(node.Position(), errors.New("could not read user input"))
 	}
 }
 
+// VisitPrintStmt prints the value of the expression. If the output would
+// exceed its limit, the characters that fit are printed before the program
+// stops.
 func (i *Interpreter) VisitPrintStmt(node ast.PrintStmt) {
 	node.Expression.Accept(i)
-	fmt.Fprint(i.outputWriter, i.stack.Pop())
+	s := fmt.Sprint(i.stack.Pop())
+
+	max := i.limits.MaxOutputBytes
+	if max > 0 && i.output+len(s) > max {
+		n := max - i.output
+		for n > 0 && !utf8.RuneStart(s[n]) {
+			n--
+		}
+
+		io.WriteString(i.outputWriter, s[:n])
+		i.output += n
+		i.terminate(node.Position(), fmt.Errorf("%w (%d bytes)", ErrOutputLimit, max))
+	}
+
+	io.WriteString(i.outputWriter, s)
+	i.output += len(s)
 }
 
 func (i *Interpreter) VisitAssertStmt(node ast.AssertStmt) {
 	node.Expression.Accept(i)
 	if !i.stack.Pop().(bool) {
-		i.terminate(fmt.Sprintf("%s: runtime error: assert failed", node.Position()))
+		i.terminate(node.Position(), errors.New("assert failed"))
 	}
 }
 
+// VisitBinaryExpr evaluates a binary expression. The right operand of & and
+// | is evaluated only if the left operand does not decide the result.
 func (i *Interpreter) VisitBinaryExpr(node ast.BinaryExpr) {
 	operator := node.Operator.Type()
 
 	node.Left.Accept(i)
 	left := i.stack.Pop()
 
+	switch operator {
+	case token.AND, token.OR:
+		// false decides the result of & and true the result of |.
+		if left.(bool) == (operator == token.OR) {
+			i.stack.Push(left)
+			return
+		}
+
+		node.Right.Accept(i)
+		return
+	}
+
 	node.Right.Accept(i)
 	right := i.stack.Pop()
 
@@ -158,7 +338,9 @@ func (i *Interpreter) VisitBinaryExpr(node ast.BinaryExpr) {
 			r, rightOk := right.(string)
 
 			if leftOk && rightOk {
-				i.stack.Push(l + r)
+				s := l + r
+				i.checkString(node.Position(), s)
+				i.stack.Push(s)
 				return
 			}
 		}
@@ -177,6 +359,10 @@ func (i *Interpreter) VisitBinaryExpr(node ast.BinaryExpr) {
 		r, rightOk := right.(int)
 
 		if leftOk && rightOk {
+			if r == 0 {
+				i.terminate(node.Position(), errors.New("division by zero"))
+			}
+
 			i.stack.Push(l / r)
 			return
 		}
@@ -190,21 +376,9 @@ func (i *Interpreter) VisitBinaryExpr(node ast.BinaryExpr) {
 			return
 		}
 
-	case token.AND:
-		l, leftOk := left.(bool)
-		r, rightOk := right.(bool)
-
-		if leftOk && rightOk {
-			i.stack.Push(l && r)
-			return
-		}
-
-	case token.LT:
-		l, leftOk := left.(int)
-		r, rightOk := right.(int)
-
-		if leftOk && rightOk {
-			i.stack.Push(l < r)
+	case token.LT, token.LE, token.GT, token.GE:
+		if c, ok := compare(left, right); ok {
+			i.stack.Push(orderings[operator](c))
 			return
 		}
 
@@ -212,6 +386,10 @@ func (i *Interpreter) VisitBinaryExpr(node ast.BinaryExpr) {
 		i.stack.Push(left == right)
 		return
 
+	case token.NE:
+		i.stack.Push(left != right)
+		return
+
 	default:
 		panic(fmt.Sprintf("Encountered an unsupported operator %v", operator))
 	}
@@ -219,6 +397,51 @@ func (i *Interpreter) VisitBinaryExpr(node ast.BinaryExpr) {
 	panic(fmt.Sprintf("Unsupported operands %v and %v for operator %v", left, right, operator))
 }
 
+// orderings tells for every ordering operator whether it holds for the result
+// of compare.
+var orderings = map[token.TokenTag]func(int) bool{
+	token.LT: func(c int) bool { return c < 0 },
+	token.LE: func(c int) bool { return c <= 0 },
+	token.GT: func(c int) bool { return c > 0 },
+	token.GE: func(c int) bool { return c >= 0 },
+}
+
+// compare returns -1, 0 or +1 depending on whether left is less than, equal
+// to or greater than right. The returned boolean is false if the values are
+// not of the same type.
+func compare(left interface{}, right interface{}) (int, bool) {
+	switch l := left.(type) {
+	case int:
+		if r, ok := right.(int); ok {
+			switch {
+			case l < r:
+				return -1, true
+			case l > r:
+				return 1, true
+			}
+			return 0, true
+		}
+
+	case string:
+		if r, ok := right.(string); ok {
+			return strings.Compare(l, r), true
+		}
+
+	case bool:
+		if r, ok := right.(bool); ok {
+			switch {
+			case l == r:
+				return 0, true
+			case r:
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+
+	return 0, false
+}
+
 func (i *Interpreter) VisitUnaryExpr(node ast.UnaryExpr) {
 	node.Operand.Accept(i)
 	val := i.stack.Pop()
@@ -226,11 +449,54 @@ func (i *Interpreter) VisitUnaryExpr(node ast.UnaryExpr) {
 	switch node.Unary.Type() {
 	case token.NOT:
 		i.stack.Push(!val.(bool))
+	case token.MINUS:
+		i.stack.Push(-val.(int))
 	default:
 		panic(fmt.Sprintf("Unsupported unary type %v", node.Unary.Type()))
 	}
 }
 
+// VisitCallExpr calls a function with the values of the arguments.
+// An error returned by the function stops the program.
+func (i *Interpreter) VisitCallExpr(node ast.CallExpr) {
+	args := node.Args
+	values := make([]interface{}, len(args))
+	for j, arg := range args {
+		arg.Accept(i)
+		values[j] = i.stack.Pop()
+	}
+
+	name := node.Func.Value()
+
+	f, ok := i.funcs.Lookup(name)
+	if !ok {
+		panic(fmt.Sprintf("Unknown function %s", name))
+	}
+
+	result, err := f.Impl(values)
+	if err != nil {
+		i.terminate(node.Position(), fmt.Errorf("%s: %w", name, err))
+	}
+
+	i.checkString(node.Position(), result)
+
+	i.stack.Push(result)
+}
+
+// VisitImportStmt runs the statements of the imported file in place of the
+// import.
+func (i *Interpreter) VisitImportStmt(node ast.ImportStmt) {
+	node.Statements.Accept(i)
+}
+
+func (i *Interpreter) VisitBadStmt(node ast.BadStmt) {
+	panic(fmt.Sprintf("%s: cannot interpret a statement with syntax errors", node.Position()))
+}
+
+func (i *Interpreter) VisitBadExpr(node ast.BadExpr) {
+	panic(fmt.Sprintf("%s: cannot interpret an expression with syntax errors", node.Position()))
+}
+
 func (i *Interpreter) VisitNullaryExpr(node ast.NullaryExpr) {
 	node.Operand.Accept(i)
 }
@@ -243,11 +509,15 @@ func (i *Interpreter) VisitStringOpnd(node ast.StringOpnd) {
 	i.stack.Push(node.Value)
 }
 
+func (i *Interpreter) VisitBoolOpnd(node ast.BoolOpnd) {
+	i.stack.Push(node.Value)
+}
+
 func (i *Interpreter) VisitIdent(node ast.Ident) {
 	i.stack.Push(i.variables[node.Id.Value()])
 }
 
-func (i *Interpreter) terminate(message string) {
-	fmt.Fprintln(i.outputWriter, message)
-	os.Exit(1)
+// terminate stops the program with a runtime error at pos.
+func (i *Interpreter) terminate(pos token.Position, err error) {
+	panic(&RuntimeError{Pos: pos, Err: err})
 }
@@ -2,7 +2,9 @@ package interpreter
 
 import (
 	"bytes"
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/mjjs/minipl-go/pkg/ast"
@@ -235,6 +237,91 @@ var testCases = []struct {
 		},
 		expectedOutput: bytes.NewBufferString(""),
 	},
+	{
+		name: "Short-circuit evaluation and comparisons",
+		input: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.PrintStmt{
+						Expression: ast.BinaryExpr{
+							Left:     ast.NullaryExpr{Operand: ast.BoolOpnd{Value: false}},
+							Operator: token.New(token.AND, ""),
+							Right:    divisionByZero,
+						},
+					},
+					ast.PrintStmt{
+						Expression: ast.BinaryExpr{
+							Left:     ast.NullaryExpr{Operand: ast.BoolOpnd{Value: true}},
+							Operator: token.New(token.OR, ""),
+							Right:    divisionByZero,
+						},
+					},
+					ast.PrintStmt{
+						Expression: ast.BinaryExpr{
+							Left:     ast.NullaryExpr{Operand: ast.StringOpnd{Value: "abc"}},
+							Operator: token.New(token.GE, ""),
+							Right:    ast.NullaryExpr{Operand: ast.StringOpnd{Value: "abd"}},
+						},
+					},
+					ast.PrintStmt{
+						Expression: ast.BinaryExpr{
+							Left:     ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 1}},
+							Operator: token.New(token.NE, ""),
+							Right:    ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 2}},
+						},
+					},
+				},
+			},
+		},
+		expectedVariables: map[string]interface{}{},
+		expectedOutput:    bytes.NewBufferString("falsetruefalsetrue"),
+	},
+	{
+		name: "Builtin function calls",
+		input: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.PrintStmt{
+						Expression: ast.NullaryExpr{Operand: ast.CallExpr{
+							Func: token.New(token.IDENT, "substring"),
+							Args: []ast.Expr{
+								ast.NullaryExpr{Operand: ast.StringOpnd{Value: "minipl"}},
+								ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 4}},
+								ast.NullaryExpr{Operand: ast.CallExpr{
+									Func: token.New(token.IDENT, "len"),
+									Args: []ast.Expr{ast.NullaryExpr{Operand: ast.StringOpnd{Value: "minipl"}}},
+								}},
+							},
+						}},
+					},
+					ast.PrintStmt{
+						Expression: ast.BinaryExpr{
+							Left: ast.CallExpr{
+								Func: token.New(token.IDENT, "toInt"),
+								Args: []ast.Expr{ast.NullaryExpr{Operand: ast.StringOpnd{Value: "-7"}}},
+							},
+							Operator: token.New(token.MULTIPLY, ""),
+							Right:    ast.NumberOpnd{Value: 2},
+						},
+					},
+				},
+			},
+		},
+		expectedVariables: map[string]interface{}{},
+		expectedOutput:    bytes.NewBufferString("pl-14"),
+	},
+}
+
+// divisionByZero is the expression (1 / 0) = 1, which stops the program with
+// a runtime error if it is evaluated.
+var divisionByZero = ast.BinaryExpr{
+	Left: ast.BinaryExpr{
+		Left:     ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 1}},
+		Operator: token.New(token.INTEGER_DIV, ""),
+		Right:    ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 0}},
+	},
+	Operator: token.New(token.EQ, ""),
+	Right:    ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 1}},
 }
 
 func TestInterpreter(t *testing.T) {
@@ -255,3 +342,125 @@ func TestInterpreter(t *testing.T) {
 		})
 	}
 }
+
+func TestRuntimeErrorsAreReturned(t *testing.T) {
+	testCases := []struct {
+		statement     ast.Stmt
+		expectedError string
+	}{
+		{
+			ast.AssertStmt{
+				Expression: ast.NullaryExpr{Operand: ast.BoolOpnd{Value: false}},
+				Pos:        token.Position{Line: 2, Column: 1},
+			},
+			"2:1: runtime error: assert failed",
+		},
+		{ast.PrintStmt{Expression: divisionByZero}, "0:0: runtime error: division by zero"},
+	}
+
+	for _, testCase := range testCases {
+		w := &bytes.Buffer{}
+		program := ast.Prog{Statements: ast.Stmts{Statements: []ast.Stmt{
+			ast.PrintStmt{Expression: ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 1}}},
+			testCase.statement,
+			ast.PrintStmt{Expression: ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 2}}},
+		}}}
+
+		err := NewWithOutputWriter(w).Run(program)
+		if err == nil || err.Error() != testCase.expectedError {
+			t.Errorf("Expected error %s, got %v", testCase.expectedError, err)
+		}
+
+		if w.String() != "1" {
+			t.Errorf("Expected the program to stop after printing 1, got %s", w)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	loop := ast.Prog{Statements: ast.Stmts{Statements: []ast.Stmt{
+		ast.ForStmt{
+			Index: ast.Ident{Id: token.New(token.IDENT, "i")},
+			Low:   ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 0}},
+			High:  ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 1000}},
+			Statements: ast.Stmts{Statements: []ast.Stmt{
+				ast.PrintStmt{
+					Expression: ast.NullaryExpr{Operand: ast.StringOpnd{Value: "ab"}},
+					Pos:        token.Position{Line: 2, Column: 3},
+				},
+			}},
+		},
+	}}}
+
+	testCases := []struct {
+		limits         Limits
+		expectedErr    error
+		expectedOutput string
+	}{
+		{Limits{MaxSteps: 4}, ErrStepLimit, "ababab"},
+		{Limits{MaxOutputBytes: 5}, ErrOutputLimit, "ababa"},
+	}
+
+	for _, testCase := range testCases {
+		w := &bytes.Buffer{}
+
+		err := NewWithOutputWriter(w).WithLimits(testCase.limits).Run(loop)
+
+		var runtimeError *RuntimeError
+		if !errors.Is(err, testCase.expectedErr) || !errors.As(err, &runtimeError) || runtimeError.Pos.Line != 2 {
+			t.Errorf("Expected a runtime error at 2:3 wrapping %v, got %v", testCase.expectedErr, err)
+		}
+
+		if w.String() != testCase.expectedOutput {
+			t.Errorf("Expected %s, got %s", testCase.expectedOutput, w)
+		}
+	}
+}
+
+func TestOutputLimitKeepsCharactersWhole(t *testing.T) {
+	prog := ast.Prog{Statements: ast.Stmts{Statements: []ast.Stmt{
+		ast.PrintStmt{Expression: ast.NullaryExpr{Operand: ast.StringOpnd{Value: "aäö"}}},
+	}}}
+
+	w := &bytes.Buffer{}
+
+	err := NewWithOutputWriter(w).WithLimits(Limits{MaxOutputBytes: 4}).Run(prog)
+	if !errors.Is(err, ErrOutputLimit) {
+		t.Errorf("Expected %v, got %v", ErrOutputLimit, err)
+	}
+
+	if w.String() != "aä" {
+		t.Errorf("Expected the characters that fit, got %q", w)
+	}
+}
+
+func TestReadStringLimit(t *testing.T) {
+	prog := ast.Prog{Statements: ast.Stmts{Statements: []ast.Stmt{
+		ast.DeclStmt{Identifier: token.New(token.IDENT, "s"), VariableType: token.New(token.STRING, "")},
+		ast.ReadStmt{
+			TargetIdentifier: ast.Ident{Id: token.New(token.IDENT, "s")},
+			Pos:              token.Position{Line: 1, Column: 15},
+		},
+	}}}
+
+	testCases := []struct {
+		input       string
+		expectedErr error
+	}{
+		{"short\n", nil},
+		{strings.Repeat("a", 10000) + "\n", ErrStringLimit},
+	}
+
+	for _, testCase := range testCases {
+		input := strings.NewReader(testCase.input)
+
+		err := New(&bytes.Buffer{}, input).WithLimits(Limits{MaxStringBytes: 100}).Run(prog)
+		if !errors.Is(err, testCase.expectedErr) {
+			t.Errorf("Expected %v, got %v", testCase.expectedErr, err)
+		}
+
+		if testCase.expectedErr != nil && input.Len() == 0 {
+			t.Errorf("Expected the read to stop at the limit, but the whole input was read")
+		}
+	}
+}
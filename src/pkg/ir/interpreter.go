@@ -0,0 +1,219 @@
+package ir
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/mjjs/minipl-go/pkg/builtin"
+)
+
+// errDivisionByZero is returned by evaluate when an integer is divided by zero.
+var errDivisionByZero = errors.New("division by zero")
+
+// Interpreter executes a Program. It is mainly used to verify that the
+// optimization passes preserve the semantics of the program they transform.
+type Interpreter struct {
+	variables map[Var]interface{}
+	output    io.Writer
+	input     *bufio.Reader
+}
+
+// NewInterpreter returns an Interpreter that prints to output and reads user
+// input from input.
+func NewInterpreter(output io.Writer, input io.Reader) *Interpreter {
+	i := &Interpreter{
+		variables: make(map[Var]interface{}),
+		output:    output,
+	}
+
+	if input != nil {
+		i.input = bufio.NewReader(input)
+	}
+
+	return i
+}
+
+// Run executes the program from its entry block until it halts. A failing
+// assertion or any other runtime error stops the execution and is returned.
+func (i *Interpreter) Run(program *Program) error {
+	block := program.Entry()
+
+	for {
+		for _, instr := range block.Instrs {
+			if err := i.execute(instr); err != nil {
+				return err
+			}
+		}
+
+		switch term := block.Term.(type) {
+		case *Jump:
+			block = term.Target
+		case *Branch:
+			if i.value(term.Cond).(bool) {
+				block = term.Then
+			} else {
+				block = term.Else
+			}
+		case *Halt:
+			return nil
+		}
+	}
+}
+
+func (i *Interpreter) value(operand Operand) interface{} {
+	switch o := operand.(type) {
+	case Const:
+		return o.Value
+	case Var:
+		return i.variables[o]
+	}
+
+	panic(fmt.Sprintf("Unsupported operand %v", operand))
+}
+
+func (i *Interpreter) execute(instr Instr) error {
+	switch instr.Op {
+	case OpPrint:
+		fmt.Fprint(i.output, i.value(instr.Args[0]))
+
+	case OpAssert:
+		if !i.value(instr.Args[0]).(bool) {
+			return fmt.Errorf("%s: runtime error: assert failed", instr.Pos)
+		}
+
+	case OpReadInt:
+		str, _ := i.readLine()
+		x, err := strconv.Atoi(strings.Trim(str, "\n"))
+		if err != nil {
+			return fmt.Errorf("%s: runtime error: failed to parse integer", instr.Pos)
+		}
+		i.variables[instr.Dst] = x
+
+	case OpReadString:
+		x, err := i.readLine()
+		if err != nil {
+			return fmt.Errorf("%s: runtime error: failed to parse string", instr.Pos)
+		}
+		i.variables[instr.Dst] = x
+
+	case OpReadBool:
+		return fmt.Errorf("%s: runtime error: could not read user input", instr.Pos)
+
+	default:
+		args := make([]interface{}, len(instr.Args))
+		for j, arg := range instr.Args {
+			args[j] = i.value(arg)
+		}
+
+		x, err := evaluate(instr, args)
+		if err != nil {
+			return fmt.Errorf("%s: runtime error: %s", instr.Pos, err)
+		}
+		i.variables[instr.Dst] = x
+	}
+
+	return nil
+}
+
+func (i *Interpreter) readLine() (string, error) {
+	if i.input == nil {
+		return "", io.EOF
+	}
+
+	return i.input.ReadString('\n')
+}
+
+// evaluate computes the result of a side-effect free operation. It is shared
+// by the interpreter and the constant folding pass so that both agree on the
+// semantics of every operation. The builtin functions have no side effects
+// either, but they may fail like a division by zero.
+func evaluate(instr Instr, args []interface{}) (interface{}, error) {
+	op := instr.Op
+
+	switch op {
+	case OpCall:
+		f, ok := builtin.Lookup(instr.Func)
+		if !ok {
+			panic(fmt.Sprintf("Encountered an unknown function %s", instr.Func))
+		}
+
+		x, err := f.Impl(args)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", instr.Func, err)
+		}
+		return x, nil
+	case OpCopy:
+		return args[0], nil
+	case OpNot:
+		return !args[0].(bool), nil
+	case OpNeg:
+		return -args[0].(int), nil
+	case OpEq:
+		return args[0] == args[1], nil
+	case OpNe:
+		return args[0] != args[1], nil
+	case OpLt:
+		return compare(args[0], args[1]) < 0, nil
+	case OpLe:
+		return compare(args[0], args[1]) <= 0, nil
+	case OpGt:
+		return compare(args[0], args[1]) > 0, nil
+	case OpGe:
+		return compare(args[0], args[1]) >= 0, nil
+	}
+
+	if op == OpAdd {
+		if l, ok := args[0].(string); ok {
+			return l + args[1].(string), nil
+		}
+	}
+
+	l, r := args[0].(int), args[1].(int)
+
+	switch op {
+	case OpAdd:
+		return l + r, nil
+	case OpSub:
+		return l - r, nil
+	case OpMul:
+		return l * r, nil
+	case OpDiv:
+		if r == 0 {
+			return nil, errDivisionByZero
+		}
+		return l / r, nil
+	}
+
+	panic(fmt.Sprintf("Encountered an unsupported operation %v", op))
+}
+
+// compare returns a negative number, zero or a positive number depending on
+// whether a is less than, equal to or greater than b. Integers are ordered by
+// value, strings by bytes and false is less than true.
+func compare(a interface{}, b interface{}) int {
+	switch x := a.(type) {
+	case int:
+		y := b.(int)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	case string:
+		return strings.Compare(x, b.(string))
+	case bool:
+		if x != b.(bool) {
+			if x {
+				return 1
+			}
+			return -1
+		}
+	}
+
+	return 0
+}
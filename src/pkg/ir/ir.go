@@ -0,0 +1,218 @@
+// Package ir defines a three-address code intermediate representation for
+// MiniPL programs. A checked abstract syntax tree is lowered into a Program,
+// which is a list of basic blocks that end in a single terminator each. The
+// logical operators & and | are lowered into branches, so that their right
+// operand is evaluated only when needed.
+package ir
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mjjs/minipl-go/pkg/token"
+)
+
+// Op is the operation performed by an instruction.
+type Op int
+
+const (
+	OpCopy Op = iota // Dst = a
+	OpAdd            // Dst = a + b, for integers and strings
+	OpSub            // Dst = a - b
+	OpMul            // Dst = a * b
+	OpDiv            // Dst = a / b
+	OpLt             // Dst = a < b
+	OpLe             // Dst = a <= b
+	OpGt             // Dst = a > b
+	OpGe             // Dst = a >= b
+	OpEq             // Dst = a = b
+	OpNe             // Dst = a <> b
+	OpNot            // Dst = !a
+	OpNeg            // Dst = -a
+	OpCall           // Dst = Func(a, b, ...), a call of a builtin function
+
+	OpReadInt    // read an integer into Dst
+	OpReadString // read a string into Dst
+	OpReadBool   // read a boolean into Dst, always fails at runtime
+
+	OpPrint  // print a
+	OpAssert // assert a
+)
+
+var opSymbols = map[Op]string{
+	OpAdd: "+",
+	OpSub: "-",
+	OpMul: "*",
+	OpDiv: "/",
+	OpLt:  "<",
+	OpLe:  "<=",
+	OpGt:  ">",
+	OpGe:  ">=",
+	OpEq:  "=",
+	OpNe:  "<>",
+	OpNot: "!",
+}
+
+// IsBinary reports whether the operation takes two operands and produces a value.
+func (o Op) IsBinary() bool {
+	return o >= OpAdd && o <= OpNe
+}
+
+// IsRead reports whether the operation reads user input into its destination.
+func (o Op) IsRead() bool {
+	return o == OpReadInt || o == OpReadString || o == OpReadBool
+}
+
+// Operand is an argument of an instruction. It is either a Var or a Const.
+type Operand interface {
+	fmt.Stringer
+	operand()
+}
+
+// Var is a variable of the program. Variables declared in the source program
+// use their source names, while temporaries are named %1, %2 and so on.
+type Var string
+
+func (v Var) String() string { return string(v) }
+
+// IsTemp reports whether the variable is a temporary created during lowering.
+func (v Var) IsTemp() bool { return strings.HasPrefix(string(v), "%") }
+
+// Const is a constant int, string or bool value.
+type Const struct {
+	Value interface{}
+}
+
+func (c Const) String() string {
+	if s, ok := c.Value.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+
+	return fmt.Sprint(c.Value)
+}
+
+func (Var) operand()   {}
+func (Const) operand() {}
+
+// Instr is a single three-address instruction. Dst is empty for instructions
+// that do not produce a value, i.e. print and assert. Func is the name of the
+// builtin function called by an OpCall instruction.
+type Instr struct {
+	Op   Op
+	Dst  Var
+	Func string
+	Args []Operand
+	Pos  token.Position
+}
+
+func (i Instr) String() string {
+	switch {
+	case i.Op == OpCopy:
+		return fmt.Sprintf("%s = %s", i.Dst, i.Args[0])
+	case i.Op == OpNot:
+		return fmt.Sprintf("%s = !%s", i.Dst, i.Args[0])
+	case i.Op == OpNeg:
+		return fmt.Sprintf("%s = -%s", i.Dst, i.Args[0])
+	case i.Op == OpCall:
+		args := make([]string, len(i.Args))
+		for j, arg := range i.Args {
+			args[j] = arg.String()
+		}
+		return fmt.Sprintf("%s = %s(%s)", i.Dst, i.Func, strings.Join(args, ", "))
+	case i.Op.IsBinary():
+		return fmt.Sprintf("%s = %s %s %s", i.Dst, i.Args[0], opSymbols[i.Op], i.Args[1])
+	case i.Op == OpReadInt:
+		return fmt.Sprintf("read int %s", i.Dst)
+	case i.Op == OpReadString:
+		return fmt.Sprintf("read string %s", i.Dst)
+	case i.Op == OpReadBool:
+		return fmt.Sprintf("read bool %s", i.Dst)
+	case i.Op == OpPrint:
+		return fmt.Sprintf("print %s", i.Args[0])
+	case i.Op == OpAssert:
+		return fmt.Sprintf("assert %s", i.Args[0])
+	}
+
+	return fmt.Sprintf("<unknown op %d>", i.Op)
+}
+
+// Terminator ends a basic block and transfers control to its successors.
+type Terminator interface {
+	fmt.Stringer
+	Succs() []*Block
+}
+
+// Jump transfers control unconditionally to Target.
+type Jump struct {
+	Target *Block
+}
+
+func (j *Jump) Succs() []*Block { return []*Block{j.Target} }
+func (j *Jump) String() string  { return fmt.Sprintf("jump %s", j.Target.Label()) }
+
+// Branch transfers control to Then if Cond is true and to Else otherwise.
+type Branch struct {
+	Cond Operand
+	Then *Block
+	Else *Block
+}
+
+func (b *Branch) Succs() []*Block { return []*Block{b.Then, b.Else} }
+func (b *Branch) String() string {
+	return fmt.Sprintf("branch %s %s %s", b.Cond, b.Then.Label(), b.Else.Label())
+}
+
+// Halt ends the execution of the program.
+type Halt struct{}
+
+func (h *Halt) Succs() []*Block { return nil }
+func (h *Halt) String() string  { return "halt" }
+
+// Block is a basic block: a straight-line sequence of instructions followed
+// by a terminator.
+type Block struct {
+	ID     int
+	Instrs []Instr
+	Term   Terminator
+}
+
+// Label returns the name of the block used in the textual dump.
+func (b *Block) Label() string { return fmt.Sprintf("b%d", b.ID) }
+
+// Succs returns the successors of the block.
+func (b *Block) Succs() []*Block { return b.Term.Succs() }
+
+// Program is a lowered MiniPL program. Execution starts from the first block.
+type Program struct {
+	Blocks []*Block
+}
+
+// Entry returns the block where execution starts.
+func (p *Program) Entry() *Block { return p.Blocks[0] }
+
+// String returns the textual dump of the program.
+func (p *Program) String() string {
+	var sb strings.Builder
+
+	for _, block := range p.Blocks {
+		fmt.Fprintf(&sb, "%s:\n", block.Label())
+		for _, instr := range block.Instrs {
+			fmt.Fprintf(&sb, "\t%s\n", instr)
+		}
+		fmt.Fprintf(&sb, "\t%s\n", block.Term)
+	}
+
+	return sb.String()
+}
+
+// preds returns the predecessors of every block in the program.
+func (p *Program) preds() map[*Block][]*Block {
+	preds := make(map[*Block][]*Block)
+	for _, block := range p.Blocks {
+		for _, succ := range block.Succs() {
+			preds[succ] = append(preds[succ], block)
+		}
+	}
+
+	return preds
+}
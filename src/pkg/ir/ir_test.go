@@ -0,0 +1,407 @@
+package ir
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mjjs/minipl-go/pkg/ast"
+	"github.com/mjjs/minipl-go/pkg/interpreter"
+	"github.com/mjjs/minipl-go/pkg/lexer"
+	"github.com/mjjs/minipl-go/pkg/parser"
+	"github.com/mjjs/minipl-go/pkg/symboltable"
+	"github.com/mjjs/minipl-go/pkg/typechecker"
+)
+
+var lowerTestCases = []struct {
+	name         string
+	sourceCode   string
+	expectedDump string
+}{
+	{
+		name:       "Declarations and expressions",
+		sourceCode: `var x : int; var s : string := "a" + "b"; x := (x * 2) - 1; print !(x < 3);`,
+		expectedDump: `b0:
+	x = 0
+	%1 = "a" + "b"
+	s = %1
+	%2 = x * 2
+	%3 = %2 - 1
+	x = %3
+	%4 = x < 3
+	%5 = !%4
+	print %5
+	halt
+`,
+	},
+	{
+		name:       "Unary minus",
+		sourceCode: `var x : int := 3; print -x * - -2;`,
+		expectedDump: `b0:
+	x = 3
+	%1 = -x
+	%2 = -2
+	%3 = -%2
+	%4 = %1 * %3
+	print %4
+	halt
+`,
+	},
+	{
+		name:       "Logical operators",
+		sourceCode: `var a : bool; var b : bool; print a | (b & (1 <= 2));`,
+		expectedDump: `b0:
+	a = false
+	b = false
+	%1 = a
+	branch %1 b4 b1
+b1:
+	%2 = b
+	branch %2 b2 b3
+b2:
+	%3 = 1 <= 2
+	%2 = %3
+	jump b3
+b3:
+	%1 = %2
+	jump b4
+b4:
+	print %1
+	halt
+`,
+	},
+	{
+		name:       "Function calls",
+		sourceCode: `var s : string; read s; print substring(s, 1, len(s) - 1);`,
+		expectedDump: `b0:
+	s = ""
+	read string s
+	%1 = len(s)
+	%2 = %1 - 1
+	%3 = substring(s, 1, %2)
+	print %3
+	halt
+`,
+	},
+	{
+		name:       "For loop",
+		sourceCode: `var i : int; var n : int; read n; for i in 0..n do print i; end for; assert(i = n);`,
+		expectedDump: `b0:
+	i = 0
+	n = 0
+	read int n
+	%1 = 0
+	jump b1
+b1:
+	%2 = %1 < n
+	branch %2 b2 b3
+b2:
+	i = %1
+	print i
+	%1 = %1 + 1
+	jump b1
+b3:
+	%3 = i = n
+	assert %3
+	halt
+`,
+	},
+}
+
+func TestLower(t *testing.T) {
+	for _, testCase := range lowerTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			root, symbols := compile(t, testCase.sourceCode)
+
+			actual := Lower(root, symbols).String()
+			if actual != testCase.expectedDump {
+				t.Errorf("Expected:\n%s\ngot:\n%s", testCase.expectedDump, actual)
+			}
+		})
+	}
+}
+
+var optimizeTestCases = []struct {
+	name         string
+	sourceCode   string
+	expectedDump string
+}{
+	{
+		name:       "Constants are propagated and folded",
+		sourceCode: `var x : int := 2 * 3; var y : int := x; print y + 1; assert(x = 6);`,
+		expectedDump: `b0:
+	print 7
+	halt
+`,
+	},
+	{
+		name:       "Copies are propagated and dead stores removed",
+		sourceCode: `var x : int; read x; var y : int := x; var z : int := y; print z * z;`,
+		expectedDump: `b0:
+	read int x
+	%1 = x * x
+	print %1
+	halt
+`,
+	},
+	{
+		name:       "Branches on constant conditions are removed",
+		sourceCode: `var i : int; for i in 0..0 do print i; end for; print "done";`,
+		expectedDump: `b0:
+	jump b1
+b1:
+	jump b3
+b3:
+	print "done"
+	halt
+`,
+	},
+	{
+		name:       "Constants are not propagated out of loops",
+		sourceCode: `var i : int; var s : int := 0; for i in 0..3 do s := s + i; end for; print s;`,
+		expectedDump: `b0:
+	s = 0
+	%1 = 0
+	jump b1
+b1:
+	%2 = %1 < 3
+	branch %2 b2 b3
+b2:
+	%3 = s + %1
+	s = %3
+	%1 = %1 + 1
+	jump b1
+b3:
+	print s
+	halt
+`,
+	},
+	{
+		name:       "Division by zero is not folded nor removed",
+		sourceCode: `var x : int := 1 / 0; var y : int := 4 / 2;`,
+		expectedDump: `b0:
+	%1 = 1 / 0
+	halt
+`,
+	},
+	{
+		name:       "Boolean literals are folded",
+		sourceCode: `var b : bool := (!false) & true; assert(b);`,
+		expectedDump: `b0:
+	jump b1
+b1:
+	jump b2
+b2:
+	halt
+`,
+	},
+	{
+		name:       "Function calls are folded unless they fail",
+		sourceCode: `var x : int := len("abc") + 1; var c : string := charAt("abc", x); print x;`,
+		expectedDump: `b0:
+	%3 = charAt("abc", 4)
+	print 4
+	halt
+`,
+	},
+	{
+		name:       "Failing assertions are kept",
+		sourceCode: `assert(1 = 2);`,
+		expectedDump: `b0:
+	assert false
+	halt
+`,
+	},
+}
+
+func TestOptimize(t *testing.T) {
+	for _, testCase := range optimizeTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			root, symbols := compile(t, testCase.sourceCode)
+
+			program := Lower(root, symbols)
+			Optimize(program)
+
+			if actual := program.String(); actual != testCase.expectedDump {
+				t.Errorf("Expected:\n%s\ngot:\n%s", testCase.expectedDump, actual)
+			}
+		})
+	}
+}
+
+var semanticsTestCases = []struct {
+	name       string
+	sourceCode string
+	userInput  string
+}{
+	{
+		name: "Factorial program",
+		sourceCode: `
+		print "Give a number";
+		var n : int;
+		read n;
+		var v : int := 1;
+		var i : int;
+		for i in 1..n do
+			v := v * i;
+		end for;
+		print "The result is: ";
+		print v;
+		`,
+		userInput: "5\n",
+	},
+	{
+		name: "Nested loops with constant bounds",
+		sourceCode: `
+		var i : int;
+		var j : int;
+		var total : int := 0;
+		var step : int := 2;
+		var x : int;
+		for i in 0..4 do
+			for j in i..4 do
+				x := step * j;
+				total := total + x;
+			end for;
+		end for;
+		print total;
+		print i;
+		print j;
+		`,
+	},
+	{
+		name: "Strings and booleans",
+		sourceCode: `
+		var s : string;
+		read s;
+		var greeting : string := "Hello, " + s;
+		var t : bool := greeting = "Hello, world\n";
+		var f : bool := !t;
+		print greeting;
+		print t & (!f);
+		print 1 < 2;
+		`,
+		userInput: "world\n",
+	},
+	{
+		name: "Comparisons and short-circuit evaluation",
+		sourceCode: `
+		var z : int := 0;
+		print (z = 0) | ((1 / z) = 1);
+		print (z <> 0) & ((1 / z) = 1);
+		print "abc" < "abd";
+		print "b" >= "a";
+		print true > false;
+		print z != 0;
+		print (z + 1) <= z;
+		print -z - -3;
+		`,
+	},
+	{
+		name: "Builtin functions",
+		sourceCode: `
+		var s : string;
+		read s;
+		var i : int;
+		for i in 0..len(s) do
+			print toUpper(charAt(s, i));
+		end for;
+		print indexOf(s, "l") + toInt("10");
+		print toString(len(s)) + substring(s, 1, 3);
+		`,
+		userInput: "hello\n",
+	},
+	{
+		name: "Reassigned copies",
+		sourceCode: `
+		var a : int := 1;
+		var b : int := a;
+		a := 5;
+		print b;
+		b := a;
+		a := b + a;
+		print a;
+		print b;
+		`,
+	},
+}
+
+func TestOptimizePreservesSemantics(t *testing.T) {
+	for _, testCase := range semanticsTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			root, symbols := compile(t, testCase.sourceCode)
+
+			expected := &bytes.Buffer{}
+			interpreter.New(expected, strings.NewReader(testCase.userInput)).Run(root)
+
+			unoptimized := Lower(root, symbols)
+			optimized := Lower(root, symbols)
+			Optimize(optimized)
+
+			for _, program := range []*Program{unoptimized, optimized} {
+				w := &bytes.Buffer{}
+
+				err := NewInterpreter(w, strings.NewReader(testCase.userInput)).Run(program)
+				if err != nil {
+					t.Errorf("Expected no error, got %s", err)
+				}
+
+				if w.String() != expected.String() {
+					t.Errorf("Expected: %s\ngot: %s\nfor program:\n%s", expected, w, program)
+				}
+			}
+		})
+	}
+}
+
+func TestOptimizePreservesRuntimeErrors(t *testing.T) {
+	testCases := []struct {
+		sourceCode    string
+		expectedError string
+	}{
+		{"var x : int := 3; print x; assert(x < 2);", "1:28: runtime error: assert failed"},
+		{"var x : int := 0; print 1; print 1 / x;", "1:34: runtime error: division by zero"},
+		{"var b : bool; read b;", "1:15: runtime error: could not read user input"},
+		{`print 1; print toInt("x");`, `1:16: runtime error: toInt: cannot convert "x" to int`},
+	}
+
+	for _, testCase := range testCases {
+		root, symbols := compile(t, testCase.sourceCode)
+
+		unoptimized := Lower(root, symbols)
+		optimized := Lower(root, symbols)
+		Optimize(optimized)
+
+		for _, program := range []*Program{unoptimized, optimized} {
+			w := &bytes.Buffer{}
+
+			err := NewInterpreter(w, nil).Run(program)
+			if err == nil || err.Error() != testCase.expectedError {
+				t.Errorf("Expected error %s, got %v", testCase.expectedError, err)
+			}
+
+			if w.String() != "3" && w.String() != "1" && w.String() != "" {
+				t.Errorf("Unexpected output %s", w)
+			}
+		}
+	}
+}
+
+func compile(t *testing.T, sourceCode string) (ast.Prog, *symboltable.SymbolTable) {
+	t.Helper()
+
+	root, errors := parser.New(lexer.New(sourceCode)).Parse()
+	if len(errors) > 0 {
+		t.Fatalf("Failed to parse %s: %s", sourceCode, errors)
+	}
+
+	symbols, errors := (&symboltable.SymbolTableCreator{}).Create(root)
+	_, typeErrors := typechecker.New(symbols).CheckTypes(root)
+	errors = append(errors, typeErrors...)
+	if len(errors) > 0 {
+		t.Fatalf("Failed to check %s: %s", sourceCode, fmt.Sprint(errors))
+	}
+
+	return root, symbols
+}
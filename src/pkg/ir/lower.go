@@ -0,0 +1,292 @@
+package ir
+
+import (
+	"fmt"
+
+	"github.com/mjjs/minipl-go/pkg/ast"
+	"github.com/mjjs/minipl-go/pkg/stack"
+	"github.com/mjjs/minipl-go/pkg/symboltable"
+	"github.com/mjjs/minipl-go/pkg/token"
+	"github.com/mjjs/minipl-go/pkg/types"
+)
+
+// binaryOps maps the binary operator tokens of MiniPL into IR operations.
+var binaryOps = map[token.TokenTag]Op{
+	token.PLUS:        OpAdd,
+	token.MINUS:       OpSub,
+	token.MULTIPLY:    OpMul,
+	token.INTEGER_DIV: OpDiv,
+	token.LT:          OpLt,
+	token.LE:          OpLe,
+	token.GT:          OpGt,
+	token.GE:          OpGe,
+	token.EQ:          OpEq,
+	token.NE:          OpNe,
+}
+
+// unaryOps maps the unary operator tokens of MiniPL into IR operations.
+var unaryOps = map[token.TokenTag]Op{
+	token.NOT:   OpNot,
+	token.MINUS: OpNeg,
+}
+
+// lowerer is an ast.Visitor that translates the abstract syntax tree into
+// three-address code. Expressions leave their result operand on the stack.
+type lowerer struct {
+	program *Program
+	current *Block
+	symbols *symboltable.SymbolTable
+	stack   *stack.Stack
+	temps   int
+}
+
+// Lower translates a program that has passed the symbol table creation and
+// type checking phases into three-address code.
+func Lower(root ast.Prog, symbols *symboltable.SymbolTable) *Program {
+	l := &lowerer{
+		program: &Program{},
+		symbols: symbols,
+		stack:   stack.New(),
+	}
+
+	l.current = l.newBlock()
+	root.Accept(l)
+	l.current.Term = &Halt{}
+
+	return l.program
+}
+
+func (l *lowerer) newBlock() *Block {
+	block := &Block{ID: len(l.program.Blocks)}
+	l.program.Blocks = append(l.program.Blocks, block)
+	return block
+}
+
+func (l *lowerer) newTemp() Var {
+	l.temps++
+	return Var(fmt.Sprintf("%%%d", l.temps))
+}
+
+func (l *lowerer) emit(instr Instr) {
+	l.current.Instrs = append(l.current.Instrs, instr)
+}
+
+func (l *lowerer) expression(node ast.Node) Operand {
+	node.Accept(l)
+	return l.stack.Pop().(Operand)
+}
+
+func (l *lowerer) VisitProg(node ast.Prog) {
+	node.Statements.Accept(l)
+}
+
+func (l *lowerer) VisitStmts(node ast.Stmts) {
+	for _, stmt := range node.Statements {
+		stmt.Accept(l)
+	}
+}
+
+func (l *lowerer) VisitAssignStmt(node ast.AssignStmt) {
+	value := l.expression(node.Expression)
+
+	l.emit(Instr{
+		Op:   OpCopy,
+		Dst:  Var(node.Identifier.Id.Value()),
+		Args: []Operand{value},
+		Pos:  node.Position(),
+	})
+}
+
+func (l *lowerer) VisitDeclStmt(node ast.DeclStmt) {
+	var value Operand
+
+	if node.Expression != nil {
+		value = l.expression(node.Expression)
+	} else {
+		variableType := types.FromToken(node.VariableType.Type())
+
+		switch {
+		case types.Identical(variableType, types.Int):
+			value = Const{0}
+		case types.Identical(variableType, types.String):
+			value = Const{""}
+		default:
+			value = Const{false}
+		}
+	}
+
+	l.emit(Instr{
+		Op:   OpCopy,
+		Dst:  Var(node.Identifier.Value()),
+		Args: []Operand{value},
+		Pos:  node.Position(),
+	})
+}
+
+// VisitForStmt lowers a for loop into a condition block, a body block and an
+// exit block. The range bounds are evaluated only once before the loop and
+// the loop counter is kept in a temporary so that the index variable keeps
+// its last value after the loop, like in the tree-walking interpreter.
+func (l *lowerer) VisitForStmt(node ast.ForStmt) {
+	pos := node.Position()
+	index := Var(node.Index.Id.Value())
+
+	low := l.expression(node.Low)
+	high := l.expression(node.High)
+
+	counter := l.newTemp()
+	l.emit(Instr{Op: OpCopy, Dst: counter, Args: []Operand{low}, Pos: pos})
+
+	cond := l.newBlock()
+	body := l.newBlock()
+	exit := l.newBlock()
+
+	l.current.Term = &Jump{Target: cond}
+
+	l.current = cond
+	inRange := l.newTemp()
+	l.emit(Instr{Op: OpLt, Dst: inRange, Args: []Operand{counter, high}, Pos: pos})
+	l.current.Term = &Branch{Cond: inRange, Then: body, Else: exit}
+
+	l.current = body
+	l.emit(Instr{Op: OpCopy, Dst: index, Args: []Operand{counter}, Pos: pos})
+	node.Statements.Accept(l)
+	l.emit(Instr{Op: OpAdd, Dst: counter, Args: []Operand{counter, Const{1}}, Pos: pos})
+	l.current.Term = &Jump{Target: cond}
+
+	l.current = exit
+}
+
+func (l *lowerer) VisitReadStmt(node ast.ReadStmt) {
+	name := node.TargetIdentifier.Id.Value()
+	op := OpReadBool
+
+	if symbol, ok := l.symbols.Get(name); ok {
+		switch {
+		case types.Identical(symbol.Type(), types.Int):
+			op = OpReadInt
+		case types.Identical(symbol.Type(), types.String):
+			op = OpReadString
+		}
+	}
+
+	l.emit(Instr{Op: op, Dst: Var(name), Pos: node.Position()})
+}
+
+func (l *lowerer) VisitPrintStmt(node ast.PrintStmt) {
+	value := l.expression(node.Expression)
+	l.emit(Instr{Op: OpPrint, Args: []Operand{value}, Pos: node.Position()})
+}
+
+func (l *lowerer) VisitAssertStmt(node ast.AssertStmt) {
+	value := l.expression(node.Expression)
+	l.emit(Instr{Op: OpAssert, Args: []Operand{value}, Pos: node.Position()})
+}
+
+func (l *lowerer) VisitBinaryExpr(node ast.BinaryExpr) {
+	if operator := node.Operator.Type(); operator == token.AND || operator == token.OR {
+		l.logical(node)
+		return
+	}
+
+	op, ok := binaryOps[node.Operator.Type()]
+	if !ok {
+		panic(fmt.Sprintf("Encountered an unsupported operator %v", node.Operator.Type()))
+	}
+
+	left := l.expression(node.Left)
+	right := l.expression(node.Right)
+
+	dst := l.newTemp()
+	l.emit(Instr{Op: op, Dst: dst, Args: []Operand{left, right}, Pos: node.Position()})
+	l.stack.Push(dst)
+}
+
+// logical lowers & and | into a branch on the left operand. The right operand
+// is evaluated in a block of its own, which is skipped if the left operand is
+// false for & or true for |, and the result is the last operand evaluated.
+func (l *lowerer) logical(node ast.BinaryExpr) {
+	pos := node.Position()
+
+	left := l.expression(node.Left)
+
+	dst := l.newTemp()
+	l.emit(Instr{Op: OpCopy, Dst: dst, Args: []Operand{left}, Pos: pos})
+
+	branch := &Branch{Cond: dst}
+	l.current.Term = branch
+
+	right := l.newBlock()
+	l.current = right
+	value := l.expression(node.Right)
+	l.emit(Instr{Op: OpCopy, Dst: dst, Args: []Operand{value}, Pos: pos})
+
+	done := l.newBlock()
+	l.current.Term = &Jump{Target: done}
+	l.current = done
+
+	if node.Operator.Type() == token.AND {
+		branch.Then, branch.Else = right, done
+	} else {
+		branch.Then, branch.Else = done, right
+	}
+
+	l.stack.Push(dst)
+}
+
+func (l *lowerer) VisitUnaryExpr(node ast.UnaryExpr) {
+	op, ok := unaryOps[node.Unary.Type()]
+	if !ok {
+		panic(fmt.Sprintf("Unsupported unary type %v", node.Unary.Type()))
+	}
+
+	operand := l.expression(node.Operand)
+
+	dst := l.newTemp()
+	l.emit(Instr{Op: op, Dst: dst, Args: []Operand{operand}, Pos: node.Position()})
+	l.stack.Push(dst)
+}
+
+func (l *lowerer) VisitCallExpr(node ast.CallExpr) {
+	args := node.Args
+	operands := make([]Operand, len(args))
+	for i, arg := range args {
+		operands[i] = l.expression(arg)
+	}
+
+	dst := l.newTemp()
+	l.emit(Instr{Op: OpCall, Dst: dst, Func: node.Func.Value(), Args: operands, Pos: node.Position()})
+	l.stack.Push(dst)
+}
+
+func (l *lowerer) VisitImportStmt(node ast.ImportStmt) {
+	node.Statements.Accept(l)
+}
+
+func (l *lowerer) VisitBadStmt(node ast.BadStmt) {
+	panic(fmt.Sprintf("%s: cannot lower a statement with syntax errors", node.Position()))
+}
+
+func (l *lowerer) VisitBadExpr(node ast.BadExpr) {
+	panic(fmt.Sprintf("%s: cannot lower an expression with syntax errors", node.Position()))
+}
+
+func (l *lowerer) VisitNullaryExpr(node ast.NullaryExpr) {
+	node.Operand.Accept(l)
+}
+
+func (l *lowerer) VisitNumberOpnd(node ast.NumberOpnd) {
+	l.stack.Push(Const{node.Value})
+}
+
+func (l *lowerer) VisitStringOpnd(node ast.StringOpnd) {
+	l.stack.Push(Const{node.Value})
+}
+
+func (l *lowerer) VisitBoolOpnd(node ast.BoolOpnd) {
+	l.stack.Push(Const{node.Value})
+}
+
+func (l *lowerer) VisitIdent(node ast.Ident) {
+	l.stack.Push(Var(node.Id.Value()))
+}
@@ -0,0 +1,487 @@
+package ir
+
+// Pass is an optimization pass that transforms a program in place. It reports
+// whether the program was changed.
+type Pass func(*Program) bool
+
+// Passes are the optimization passes run by Optimize, in order.
+var Passes = []Pass{
+	PropagateConstants,
+	FoldConstants,
+	PropagateCopies,
+	EliminateDeadStores,
+}
+
+// Optimize runs all the optimization passes repeatedly until none of them
+// changes the program anymore.
+func Optimize(program *Program) {
+	for changed := true; changed; {
+		changed = false
+
+		for _, pass := range Passes {
+			if pass(program) {
+				changed = true
+			}
+		}
+	}
+}
+
+// lattice is a value of the constant propagation lattice. A variable missing
+// from a constState has not been assigned yet, a constant variable holds a
+// single known value and a variable that is not constant may hold different
+// values depending on the path taken.
+type lattice struct {
+	constant bool
+	value    interface{}
+}
+
+var notConstant = lattice{}
+
+// constState maps variables into their lattice values at a program point.
+type constState map[Var]lattice
+
+func (s constState) copy() constState {
+	c := make(constState, len(s))
+	for k, v := range s {
+		c[k] = v
+	}
+	return c
+}
+
+// meet merges the state flowing from another predecessor into s.
+func (s constState) meet(other constState) {
+	for v, x := range other {
+		y, ok := s[v]
+		if !ok {
+			s[v] = x
+			continue
+		}
+
+		if !y.constant || !x.constant || y.value != x.value {
+			s[v] = notConstant
+		}
+	}
+}
+
+func (s constState) equal(other constState) bool {
+	if len(s) != len(other) {
+		return false
+	}
+
+	for k, v := range s {
+		if w, ok := other[k]; !ok || w != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// substitute replaces an operand with a constant if it is known to be one.
+func (s constState) substitute(operand Operand) (Operand, bool) {
+	v, ok := operand.(Var)
+	if !ok {
+		return operand, false
+	}
+
+	if x, ok := s[v]; ok && x.constant {
+		return Const{x.value}, true
+	}
+
+	return operand, false
+}
+
+// transfer updates the state with the effect of a single instruction.
+func (s constState) transfer(instr Instr) {
+	if instr.Dst == "" {
+		return
+	}
+
+	if instr.Op.IsRead() {
+		s[instr.Dst] = notConstant
+		return
+	}
+
+	args := make([]interface{}, len(instr.Args))
+	for i, arg := range instr.Args {
+		arg, _ = s.substitute(arg)
+
+		c, ok := arg.(Const)
+		if !ok {
+			s[instr.Dst] = notConstant
+			return
+		}
+		args[i] = c.Value
+	}
+
+	x, err := evaluate(instr, args)
+	if err != nil {
+		s[instr.Dst] = notConstant
+		return
+	}
+
+	s[instr.Dst] = lattice{constant: true, value: x}
+}
+
+// PropagateConstants replaces uses of variables that hold the same constant
+// value on every path reaching them with the constant itself. The analysis is
+// global: the values are propagated through the whole control-flow graph, and
+// successors of branches on constant conditions are only considered along the
+// edge that is actually taken.
+func PropagateConstants(program *Program) bool {
+	preds := program.preds()
+	in := make(map[*Block]constState)
+	out := make(map[*Block]constState)
+
+	for changed := true; changed; {
+		changed = false
+
+		for _, block := range program.Blocks {
+			state := constState{}
+			reached := block == program.Entry()
+
+			for _, pred := range preds[block] {
+				if predOut, ok := out[pred]; ok && takesEdge(pred, block, predOut) {
+					state.meet(predOut)
+					reached = true
+				}
+			}
+
+			if !reached {
+				continue
+			}
+			in[block] = state.copy()
+
+			for _, instr := range block.Instrs {
+				state.transfer(instr)
+			}
+
+			if old, ok := out[block]; !ok || !old.equal(state) {
+				out[block] = state
+				changed = true
+			}
+		}
+	}
+
+	changed := false
+
+	for _, block := range program.Blocks {
+		state, ok := in[block]
+		if !ok {
+			continue
+		}
+
+		for i, instr := range block.Instrs {
+			for j, arg := range instr.Args {
+				if c, ok := state.substitute(arg); ok {
+					block.Instrs[i].Args[j] = c
+					changed = true
+				}
+			}
+			state.transfer(instr)
+		}
+
+		if branch, ok := block.Term.(*Branch); ok {
+			if c, ok := state.substitute(branch.Cond); ok {
+				branch.Cond = c
+				changed = true
+			}
+		}
+	}
+
+	return changed
+}
+
+// takesEdge reports whether control may flow from a block to its successor
+// when the variables at the end of the block have the given values.
+func takesEdge(from *Block, to *Block, state constState) bool {
+	branch, ok := from.Term.(*Branch)
+	if !ok {
+		return true
+	}
+
+	c, ok := state.substitute(branch.Cond)
+	if !ok {
+		return true
+	}
+
+	if c.(Const).Value.(bool) {
+		return to == branch.Then
+	}
+
+	return to == branch.Else
+}
+
+// FoldConstants evaluates instructions whose operands are all constants at
+// compile time, removes assertions that always hold and turns branches on a
+// constant condition into jumps. Blocks that become unreachable are removed.
+// Divisions by zero are never folded so that they still fail at runtime.
+func FoldConstants(program *Program) bool {
+	changed := false
+
+	for _, block := range program.Blocks {
+		instrs := block.Instrs[:0]
+
+		for _, instr := range block.Instrs {
+			args, ok := constantArgs(instr)
+
+			switch {
+			case !ok || instr.Op.IsRead() || instr.Op == OpPrint:
+
+			case instr.Op == OpAssert:
+				if args[0].(bool) {
+					changed = true
+					continue
+				}
+
+			case instr.Op != OpCopy:
+				if x, err := evaluate(instr, args); err == nil {
+					instr = Instr{Op: OpCopy, Dst: instr.Dst, Args: []Operand{Const{x}}, Pos: instr.Pos}
+					changed = true
+				}
+			}
+
+			instrs = append(instrs, instr)
+		}
+
+		block.Instrs = instrs
+
+		if branch, ok := block.Term.(*Branch); ok {
+			if c, ok := branch.Cond.(Const); ok {
+				if c.Value.(bool) {
+					block.Term = &Jump{Target: branch.Then}
+				} else {
+					block.Term = &Jump{Target: branch.Else}
+				}
+				changed = true
+			}
+		}
+	}
+
+	if removeUnreachableBlocks(program) {
+		changed = true
+	}
+
+	return changed
+}
+
+func constantArgs(instr Instr) ([]interface{}, bool) {
+	args := make([]interface{}, len(instr.Args))
+	for i, arg := range instr.Args {
+		c, ok := arg.(Const)
+		if !ok {
+			return nil, false
+		}
+		args[i] = c.Value
+	}
+
+	return args, true
+}
+
+func removeUnreachableBlocks(program *Program) bool {
+	reachable := map[*Block]bool{}
+	worklist := []*Block{program.Entry()}
+
+	for len(worklist) > 0 {
+		block := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+
+		if reachable[block] {
+			continue
+		}
+		reachable[block] = true
+		worklist = append(worklist, block.Succs()...)
+	}
+
+	if len(reachable) == len(program.Blocks) {
+		return false
+	}
+
+	blocks := program.Blocks[:0]
+	for _, block := range program.Blocks {
+		if reachable[block] {
+			blocks = append(blocks, block)
+		}
+	}
+	program.Blocks = blocks
+
+	return true
+}
+
+// PropagateCopies replaces uses of a variable that was copied from another
+// variable with the original variable, for as long as neither of them is
+// reassigned. The pass works within basic blocks only. Copies of a variable
+// into itself are removed.
+func PropagateCopies(program *Program) bool {
+	changed := false
+
+	for _, block := range program.Blocks {
+		copies := map[Var]Var{}
+		instrs := block.Instrs[:0]
+
+		for _, instr := range block.Instrs {
+			for j, arg := range instr.Args {
+				if v, ok := arg.(Var); ok {
+					if src, ok := copies[v]; ok {
+						instr.Args[j] = src
+						changed = true
+					}
+				}
+			}
+
+			if instr.Dst != "" {
+				delete(copies, instr.Dst)
+				for dst, src := range copies {
+					if src == instr.Dst {
+						delete(copies, dst)
+					}
+				}
+			}
+
+			if instr.Op == OpCopy {
+				if src, ok := instr.Args[0].(Var); ok {
+					if src == instr.Dst {
+						changed = true
+						continue
+					}
+					copies[instr.Dst] = src
+				}
+			}
+
+			instrs = append(instrs, instr)
+		}
+
+		block.Instrs = instrs
+
+		if branch, ok := block.Term.(*Branch); ok {
+			if v, ok := branch.Cond.(Var); ok {
+				if src, ok := copies[v]; ok {
+					branch.Cond = src
+					changed = true
+				}
+			}
+		}
+	}
+
+	return changed
+}
+
+// varSet is a set of variables.
+type varSet map[Var]struct{}
+
+func (s varSet) addUses(args ...Operand) {
+	for _, arg := range args {
+		if v, ok := arg.(Var); ok {
+			s[v] = struct{}{}
+		}
+	}
+}
+
+// liveness computes the variables that are live at the end of every block.
+func liveness(program *Program) map[*Block]varSet {
+	liveOut := make(map[*Block]varSet)
+	for _, block := range program.Blocks {
+		liveOut[block] = varSet{}
+	}
+
+	for changed := true; changed; {
+		changed = false
+
+		for i := len(program.Blocks) - 1; i >= 0; i-- {
+			block := program.Blocks[i]
+			out := liveOut[block]
+
+			for _, succ := range block.Succs() {
+				for v := range liveIn(succ, liveOut[succ]) {
+					if _, ok := out[v]; !ok {
+						out[v] = struct{}{}
+						changed = true
+					}
+				}
+			}
+		}
+	}
+
+	return liveOut
+}
+
+// liveIn computes the variables that are live at the start of the block given
+// the variables live at its end.
+func liveIn(block *Block, out varSet) varSet {
+	live := varSet{}
+	for v := range out {
+		live[v] = struct{}{}
+	}
+
+	if branch, ok := block.Term.(*Branch); ok {
+		live.addUses(branch.Cond)
+	}
+
+	for i := len(block.Instrs) - 1; i >= 0; i-- {
+		instr := block.Instrs[i]
+		if instr.Dst != "" {
+			delete(live, instr.Dst)
+		}
+		live.addUses(instr.Args...)
+	}
+
+	return live
+}
+
+// hasSideEffects reports whether an instruction must be kept even if the
+// value it produces is never used. Reads consume user input, calls of
+// builtin functions may fail at runtime and so may divisions unless the
+// divisor is a non-zero constant.
+func hasSideEffects(instr Instr) bool {
+	switch instr.Op {
+	case OpPrint, OpAssert, OpReadInt, OpReadString, OpReadBool, OpCall:
+		return true
+	case OpDiv:
+		c, ok := instr.Args[1].(Const)
+		return !ok || c.Value == 0
+	}
+
+	return false
+}
+
+// EliminateDeadStores removes assignments to variables that are never read
+// afterwards on any path. Instructions with side effects are kept.
+func EliminateDeadStores(program *Program) bool {
+	changed := false
+	liveOut := liveness(program)
+
+	for _, block := range program.Blocks {
+		live := varSet{}
+		for v := range liveOut[block] {
+			live[v] = struct{}{}
+		}
+
+		if branch, ok := block.Term.(*Branch); ok {
+			live.addUses(branch.Cond)
+		}
+
+		var kept []Instr
+
+		for i := len(block.Instrs) - 1; i >= 0; i-- {
+			instr := block.Instrs[i]
+
+			if instr.Dst != "" {
+				if _, ok := live[instr.Dst]; !ok && !hasSideEffects(instr) {
+					changed = true
+					continue
+				}
+				delete(live, instr.Dst)
+			}
+
+			live.addUses(instr.Args...)
+			kept = append(kept, instr)
+		}
+
+		for i, j := 0, len(kept)-1; i < j; i, j = i+1, j-1 {
+			kept[i], kept[j] = kept[j], kept[i]
+		}
+		block.Instrs = kept
+	}
+
+	return changed
+}
@@ -1,8 +1,12 @@
 package lexer
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/mjjs/minipl-go/pkg/token"
 )
@@ -20,56 +24,175 @@ var reservedKeywords map[string]token.Token = map[string]token.Token{
 	"string": token.New(token.STRING, ""),
 	"bool":   token.New(token.BOOLEAN, ""),
 	"assert": token.New(token.ASSERT, ""),
+	"import": token.New(token.IMPORT, ""),
+	"true":   token.New(token.BOOLEAN_LITERAL, "true"),
+	"false":  token.New(token.BOOLEAN_LITERAL, "false"),
+}
+
+// singleCharTags maps the characters that are tokens by themselves into the
+// tags of the tokens. The characters that are not tokens by themselves map to
+// the empty tag.
+var singleCharTags = [utf8.RuneSelf]token.TokenTag{
+	'+': token.PLUS,
+	'-': token.MINUS,
+	'*': token.MULTIPLY,
+	'/': token.INTEGER_DIV,
+	'<': token.LT,
+	'>': token.GT,
+	'=': token.EQ,
+	'&': token.AND,
+	'|': token.OR,
+	'!': token.NOT,
+	'(': token.LPAREN,
+	')': token.RPAREN,
+	';': token.SEMI,
+	':': token.COLON,
+	',': token.COMMA,
+}
+
+// Character classes of the ASCII characters.
+const (
+	classSpace = 1 << iota
+	classLetter
+	classDigit
+)
+
+var charClasses [utf8.RuneSelf]uint8
+
+func init() {
+	for c := rune(0); c < utf8.RuneSelf; c++ {
+		switch {
+		case unicode.IsSpace(c):
+			charClasses[c] = classSpace
+		case unicode.IsLetter(c):
+			charClasses[c] = classLetter
+		case unicode.IsDigit(c):
+			charClasses[c] = classDigit
+		}
+	}
+}
+
+// isSpace, isLetter and isDigit classify ASCII characters with charClasses
+// and fall back to the unicode package for the rest.
+func isSpace(c rune) bool {
+	if c < utf8.RuneSelf {
+		return charClasses[c]&classSpace != 0
+	}
+
+	return unicode.IsSpace(c)
+}
+
+func isLetter(c rune) bool {
+	if c < utf8.RuneSelf {
+		return charClasses[c]&classLetter != 0
+	}
+
+	return unicode.IsLetter(c)
+}
+
+func isDigit(c rune) bool {
+	if c < utf8.RuneSelf {
+		return charClasses[c]&classDigit != 0
+	}
+
+	return unicode.IsNumber(c)
 }
 
 // Lexer is the main structure of the lexer package. It takes in the source code
-// of the MiniPL application and turns it into tokens.
+// of the MiniPL application and turns it into tokens. The source code is read
+// as the tokens are requested, keeping only a small buffer of it in memory.
 type Lexer struct {
-	sourceCode  []rune
+	reader *bufio.Reader
+	// err is the error that stopped the reading, reported as an ERROR token.
+	err error
+
 	currentChar rune
-	pos         int
+	currentSize int
+	nextChar    rune
+	nextSize    int
 	eof         bool
 
+	// lexeme holds the lexeme of the token being read. It is reused for
+	// every token, so lexemes are built without reallocating.
+	lexeme []byte
+
 	tokenPos token.Position
 }
 
 // New returns a properly initialized pointer to a new Lexer instance using
 // sourceCode as the input program.
 func New(sourceCode string) *Lexer {
+	return NewReader("", strings.NewReader(sourceCode))
+}
+
+// NewFile is like New for the contents of a file. The positions of the tokens
+// carry the name of the file.
+func NewFile(file *token.File) *Lexer {
+	return NewReader(file.Name(), strings.NewReader(file.Source()))
+}
+
+// NewReader returns a Lexer that reads the input program from r as the
+// tokens are requested, so that the program does not need to fit in memory.
+// The positions of the tokens carry filename, which can be empty.
+func NewReader(filename string, r io.Reader) *Lexer {
 	lexer := &Lexer{
-		sourceCode: []rune(sourceCode),
-		tokenPos:   token.Position{Line: 1, Column: 1},
+		reader:   bufio.NewReader(r),
+		tokenPos: token.Position{Filename: filename, Line: 1, Column: 1},
 	}
 
-	if len(sourceCode) == 0 {
-		lexer.eof = true
-	} else {
-		lexer.currentChar = lexer.sourceCode[lexer.pos]
-	}
+	lexer.currentChar, lexer.currentSize = lexer.readRune()
+	lexer.eof = lexer.currentSize == 0
+	lexer.nextChar, lexer.nextSize = lexer.readRune()
 
 	return lexer
 }
 
+// readRune reads the next rune and its size in bytes from the input. The
+// size is 0 at the end of the input or after a read error.
+func (l *Lexer) readRune() (rune, int) {
+	if l.err != nil {
+		return 0, 0
+	}
+
+	r, size, err := l.reader.ReadRune()
+	if err != nil {
+		if err != io.EOF {
+			l.err = err
+		}
+
+		return 0, 0
+	}
+
+	return r, size
+}
+
 // GetNextToken returns the next token that the Lexer can parse from the
-// sourceCode given during initialization.
-func (l *Lexer) GetNextToken() (token.Token, token.Position) {
+// sourceCode given during initialization, along with the span of source code
+// it was read from. The span of the EOF token is empty.
+func (l *Lexer) GetNextToken() (token.Token, token.Span) {
+	tok, pos := l.scan()
+	return tok, token.Span{Start: pos, End: l.tokenPos}
+}
+
+// scan reads the next token and returns it with its starting position. The
+// lexer is left right after the token.
+func (l *Lexer) scan() (token.Token, token.Position) {
 	for !l.eof {
 		pos := l.tokenPos
+		c := l.currentChar
 
-		if unicode.IsSpace(l.currentChar) {
+		switch {
+		case isSpace(c):
 			l.skipWhitespace()
 			continue
-		}
-
-		if unicode.IsLetter(l.currentChar) {
+		case isLetter(c):
 			return l.ident()
-		}
-
-		if unicode.IsNumber(l.currentChar) {
+		case isDigit(c):
 			return l.number()
 		}
 
-		if l.currentChar == '/' {
+		switch c {
+		case '/':
 			next, eof := l.peek()
 			if !eof && next == '/' {
 				l.skipLineComment()
@@ -85,129 +208,105 @@ func (l *Lexer) GetNextToken() (token.Token, token.Position) {
 				continue
 			}
 
-			l.advance()
-			return token.New(token.INTEGER_DIV, ""), pos
-		}
-
-		if l.currentChar == '"' {
+		case '"':
 			return l.string()
-		}
 
-		if l.currentChar == ':' {
-			next, eof := l.peek()
-			if !eof && next == '=' {
-				l.advance()
-				l.advance()
-				return token.New(token.ASSIGN, ""), pos
-			}
+		case '`':
+			return l.rawString()
 
-			l.advance()
-			return token.New(token.COLON, ""), pos
-		}
+		case ':', '<', '>', '!':
+			if tag, ok := l.twoCharOperator(); ok {
+				return token.New(tag, ""), pos
+			}
 
-		if l.currentChar == '.' {
-			next, eof := l.peek()
-			if !eof && next == '.' {
+		case '.':
+			if next, eof := l.peek(); !eof && next == '.' {
 				l.advance()
 				l.advance()
 				return token.New(token.RANGE, ""), pos
 			}
 		}
 
-		if l.currentChar == ';' {
+		if c < utf8.RuneSelf && singleCharTags[c] != "" {
 			l.advance()
-			return token.New(token.SEMI, ""), pos
+			return token.New(singleCharTags[c], ""), pos
 		}
 
-		if l.currentChar == '!' {
-			l.advance()
-			return token.New(token.NOT, ""), pos
-		}
-
-		if l.currentChar == '+' {
-			l.advance()
-			return token.New(token.PLUS, ""), pos
-		}
+		errorToken := token.New(token.ERROR,
+			fmt.Sprintf("unrecognized character '%c'", c))
 
-		if l.currentChar == '-' {
-			l.advance()
-			return token.New(token.MINUS, ""), pos
-		}
+		l.advance()
 
-		if l.currentChar == '*' {
-			l.advance()
-			return token.New(token.MULTIPLY, ""), pos
-		}
+		return errorToken, pos
+	}
 
-		if l.currentChar == '<' {
-			l.advance()
-			return token.New(token.LT, ""), pos
-		}
+	if l.err != nil {
+		errorToken := token.New(token.ERROR, fmt.Sprintf("cannot read source code: %v", l.err))
+		l.err = nil
 
-		if l.currentChar == '=' {
-			l.advance()
-			return token.New(token.EQ, ""), pos
-		}
+		return errorToken, l.tokenPos
+	}
 
-		if l.currentChar == '&' {
-			l.advance()
-			return token.New(token.AND, ""), pos
-		}
+	return token.New(token.EOF, ""), l.tokenPos
+}
 
-		if l.currentChar == '(' {
-			l.advance()
-			return token.New(token.LPAREN, ""), pos
-		}
+// twoCharOperators maps the operators that are two characters long into their
+// tags.
+var twoCharOperators = map[[2]rune]token.TokenTag{
+	{':', '='}: token.ASSIGN,
+	{'<', '='}: token.LE,
+	{'<', '>'}: token.NE,
+	{'>', '='}: token.GE,
+	{'!', '='}: token.NE,
+}
 
-		if l.currentChar == ')' {
-			l.advance()
-			return token.New(token.RPAREN, ""), pos
-		}
+// twoCharOperator reads an operator made of the current and the next
+// character, if they make up one.
+func (l *Lexer) twoCharOperator() (token.TokenTag, bool) {
+	next, eof := l.peek()
+	if eof {
+		return "", false
+	}
 
+	tag, ok := twoCharOperators[[2]rune{l.currentChar, next}]
+	if ok {
+		l.advance()
 		l.advance()
-
-		errorToken := token.New(token.ERROR,
-			fmt.Sprintf("unrecognized character '%c'", l.currentChar))
-
-		return errorToken, pos
 	}
 
-	return token.New(token.EOF, ""), l.tokenPos
+	return tag, ok
 }
 
 // advance moves the position of the lexer forward one character and sets the
 // EOF flag to true if we have reached the end of the input program.
 func (l *Lexer) advance() {
-	l.pos++
+	if l.eof {
+		return
+	}
 
-	if l.pos > len(l.sourceCode)-1 {
-		l.eof = true
+	l.tokenPos.Offset += l.currentSize
+	if l.currentChar == '\n' {
+		l.tokenPos.Line++
+		l.tokenPos.Column = 1
 	} else {
-		if l.currentChar == '\n' {
-			l.tokenPos.Line++
-			l.tokenPos.Column = 1
-		} else {
-			l.tokenPos.Column++
-		}
-
-		l.currentChar = rune(l.sourceCode[l.pos])
+		l.tokenPos.Column++
 	}
+
+	l.currentChar, l.currentSize = l.nextChar, l.nextSize
+	l.eof = l.currentSize == 0
+	l.nextChar, l.nextSize = l.readRune()
 }
 
 // peek returns the next rune of the source code without advancing the position
 // of the lexer. The returned boolean indicates whether we have reached EOF
 // or not. If EOF is reached, the returned rune should be discarded.
 func (l *Lexer) peek() (rune, bool) {
-	if l.pos+1 > len(l.sourceCode)-1 {
-		return 0, true
-	}
-
-	return l.sourceCode[l.pos+1], false
+	return l.nextChar, l.nextSize == 0
 }
 
 // skipWhitespace advances the lexer until the next non-whitespace character.
 func (l *Lexer) skipWhitespace() {
-	for !l.eof && unicode.IsSpace(l.currentChar) {
+	for !l.eof && isSpace(l.currentChar) {
 		l.advance()
 	}
 }
@@ -238,15 +337,13 @@ func (l *Lexer) skipBlockComment() (*token.Token, token.Position) {
 			continue
 		}
 
-		next, eof := l.peek()
-
-		if !eof && next == '/' {
+		if next, eof := l.peek(); !eof && next == '/' {
 			l.advance()
 			l.advance()
 			return nil, pos
-		} else if !eof {
-			l.advance()
 		}
+
+		l.advance()
 	}
 
 	tok := token.New(token.ERROR, "Unterminated block comment")
@@ -258,83 +355,243 @@ func (l *Lexer) skipBlockComment() (*token.Token, token.Position) {
 // token with the read string as a lexeme.
 func (l *Lexer) ident() (token.Token, token.Position) {
 	pos := l.tokenPos
+	l.lexeme = l.lexeme[:0]
 
-	id := ""
-
-	// TODO: Check if unicode.X is allowed
-	for !l.eof && unicode.In(l.currentChar, unicode.Number, unicode.Letter) || l.currentChar == '_' {
-		id += string(l.currentChar)
-		l.advance()
+	for !l.eof && (isLetter(l.currentChar) || isDigit(l.currentChar) || l.currentChar == '_') {
+		l.appendCurrent()
 	}
 
-	t, ok := reservedKeywords[id]
+	// Indexing a map with a converted byte slice does not allocate.
+	t, ok := reservedKeywords[string(l.lexeme)]
 	if ok {
 		return t, pos
 	}
 
-	return token.New(token.IDENT, id), pos
+	return token.New(token.IDENT, string(l.lexeme)), pos
 }
 
 // number reads a number from the input program and returns an INTEGER_LITERAL
 // token with the number as a lexeme. MiniPL only supports integers, so we
-// do not consider floating point numbers.
+// do not consider floating point numbers. The letters, digits and underscores
+// that follow the first digit are all part of the literal, and an ERROR token
+// is returned if they do not make up a valid literal, for example for 0x1G or
+// for a literal that does not fit in an int.
 func (l *Lexer) number() (token.Token, token.Position) {
 	pos := l.tokenPos
-	numString := ""
+	l.lexeme = l.lexeme[:0]
 
-	for !l.eof && unicode.IsNumber(l.currentChar) {
-		numString += string(l.currentChar)
-		l.advance()
+	for !l.eof && (isLetter(l.currentChar) || isDigit(l.currentChar) || l.currentChar == '_') {
+		l.appendCurrent()
+	}
+
+	lexeme := string(l.lexeme)
+	if _, err := token.ParseInt(lexeme); err != nil {
+		return token.New(token.ERROR, err.Error()), pos
 	}
 
-	return token.New(token.INTEGER_LITERAL, numString), pos
+	return token.New(token.INTEGER_LITERAL, lexeme), pos
 }
 
 // string reads a string from the input program and returns a STRING_LITERAL
-// token containing the read string as a lexeme.
+// token containing the read string as a lexeme. The string cannot span
+// several lines, and the escape sequences \n, \t, \r, \0, \\ and \" stand for
+// the characters as in Go, \xHH for the ASCII character with the hexadecimal
+// code HH and \u{H...} for the Unicode character with the code of one to six
+// hexadecimal digits. An ERROR token is returned for the first invalid escape
+// sequence once the whole string has been read.
 func (l *Lexer) string() (token.Token, token.Position) {
 	pos := l.tokenPos
-	str := ""
+	l.lexeme = l.lexeme[:0]
+
+	var invalidEscape string
 
 	l.advance()
 
 	for !l.eof {
 		if l.currentChar == '\\' {
-			l.advance()
-
-			switch l.currentChar {
-			case 'n':
-				str += "\n"
-			case 't':
-				str += "\t"
-			case 'r':
-				str += "\r"
-			default:
-				str += string(l.currentChar)
+			if err := l.escape(); err != "" && invalidEscape == "" {
+				invalidEscape = err
 			}
 
-			l.advance()
 			continue
 		}
 
 		if l.currentChar == '"' {
 			l.advance()
-			return token.New(token.STRING_LITERAL, str), pos
+
+			if invalidEscape != "" {
+				return token.New(token.ERROR, invalidEscape), pos
+			}
+
+			return token.New(token.STRING_LITERAL, string(l.lexeme)), pos
 		}
 
 		if l.currentChar == '\n' || l.currentChar == '\r' {
 			break
 		}
 
-		str += string(l.currentChar)
-		l.advance()
+		l.appendCurrent()
 	}
 
 	tok := token.New(
 		token.ERROR,
-		fmt.Sprintf("unterminated string literal %s", str),
+		fmt.Sprintf("unterminated string literal %s", l.lexeme),
 	)
 
 	return tok, pos
+}
+
+// escape reads an escape sequence that starts with the current backslash and
+// appends the character it stands for to the lexeme. A description of the
+// error is returned if the escape sequence is invalid. The lexer is left after
+// the escape sequence, or at the character that makes it invalid if that
+// character can end the string.
+func (l *Lexer) escape() string {
+	l.advance()
+	if l.eof {
+		return ""
+	}
+
+	c := l.currentChar
+
+	switch c {
+	case 'n':
+		l.lexeme = append(l.lexeme, '\n')
+	case 't':
+		l.lexeme = append(l.lexeme, '\t')
+	case 'r':
+		l.lexeme = append(l.lexeme, '\r')
+	case '0':
+		l.lexeme = append(l.lexeme, 0)
+	case '\\', '"':
+		l.lexeme = appendRune(l.lexeme, c)
+
+	case 'x':
+		l.advance()
+
+		code, digits := l.hexDigits(2)
+		if len(digits) != 2 {
+			return invalidEscape(`\x`+digits, "expected two hexadecimal digits")
+		}
+
+		if code >= utf8.RuneSelf {
+			return invalidEscape(`\x`+digits, "not an ASCII character, use \\u{"+digits+"}")
+		}
+
+		l.lexeme = append(l.lexeme, byte(code))
+		return ""
+
+	case 'u':
+		l.advance()
+
+		if l.eof || l.currentChar != '{' {
+			return invalidEscape(`\u`, "expected '{'")
+		}
+		l.advance()
+
+		code, digits := l.hexDigits(6)
+		if len(digits) == 0 || l.eof || l.currentChar != '}' {
+			return invalidEscape(`\u{`+digits, "expected one to six hexadecimal digits and '}'")
+		}
+		l.advance()
+
+		if !utf8.ValidRune(rune(code)) {
+			return invalidEscape(`\u{`+digits+"}", "not a Unicode character")
+		}
+
+		l.lexeme = appendRune(l.lexeme, rune(code))
+		return ""
+
+	case '\n', '\r':
+		return invalidEscape(`\`, "expected a character after the backslash")
+
+	default:
+		l.advance()
+		return invalidEscape(`\`+string(c), "unknown escape sequence")
+	}
+
+	l.advance()
+
+	return ""
+}
+
+// hexDigits reads at most max hexadecimal digits and returns their value and
+// the digits that were read.
+func (l *Lexer) hexDigits(max int) (int, string) {
+	value := 0
+	var digits []rune
+
+	for len(digits) < max && !l.eof {
+		d, ok := hexValue(l.currentChar)
+		if !ok {
+			break
+		}
+
+		value = value*16 + d
+		digits = append(digits, l.currentChar)
+		l.advance()
+	}
+
+	return value, string(digits)
+}
+
+func hexValue(c rune) (int, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0'), true
+	case 'a' <= c && c <= 'f':
+		return int(c - 'a' + 10), true
+	case 'A' <= c && c <= 'F':
+		return int(c - 'A' + 10), true
+	}
+
+	return 0, false
+}
+
+func invalidEscape(sequence string, reason string) string {
+	return fmt.Sprintf("invalid escape sequence '%s' in string literal: %s", sequence, reason)
+}
+
+// rawString reads a raw string literal, which is enclosed in backquotes and
+// returned as a STRING_LITERAL token. The characters between the backquotes
+// are taken as they are, without escape sequences, and the string can span
+// several lines. Carriage returns are left out so that the value of the string
+// does not depend on the line endings of the source file.
+func (l *Lexer) rawString() (token.Token, token.Position) {
+	pos := l.tokenPos
+	l.lexeme = l.lexeme[:0]
+
+	l.advance()
+
+	for !l.eof {
+		switch l.currentChar {
+		case '`':
+			l.advance()
+			return token.New(token.STRING_LITERAL, string(l.lexeme)), pos
+		case '\r':
+			l.advance()
+		default:
+			l.appendCurrent()
+		}
+	}
+
+	return token.New(token.ERROR, "unterminated raw string literal"), pos
+}
+
+// appendCurrent appends the current character to the lexeme and advances the
+// lexer.
+func (l *Lexer) appendCurrent() {
+	l.lexeme = appendRune(l.lexeme, l.currentChar)
+	l.advance()
+}
+
+func appendRune(b []byte, c rune) []byte {
+	if c < utf8.RuneSelf {
+		return append(b, byte(c))
+	}
+
+	var buf [utf8.UTFMax]byte
+	n := utf8.EncodeRune(buf[:], c)
 
+	return append(b, buf[:n]...)
 }
@@ -1,6 +1,10 @@
 package lexer
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
 	"testing"
 
 	"github.com/mjjs/minipl-go/pkg/token"
@@ -48,6 +52,40 @@ var getNextTokenTestCases = []struct {
 		expectedTokens:    []token.Token{token.New(token.INTEGER_LITERAL, "0")},
 		expectedPositions: []token.Position{{Line: 1, Column: 1}},
 	},
+	{
+		name:  "Integer literals in other bases",
+		input: "0xFF 0b1_01 0o17 010 1_000",
+		expectedTokens: []token.Token{
+			token.New(token.INTEGER_LITERAL, "0xFF"),
+			token.New(token.INTEGER_LITERAL, "0b1_01"),
+			token.New(token.INTEGER_LITERAL, "0o17"),
+			token.New(token.INTEGER_LITERAL, "010"),
+			token.New(token.INTEGER_LITERAL, "1_000"),
+		},
+		expectedPositions: []token.Position{
+			{Line: 1, Column: 1},
+			{Offset: 5, Line: 1, Column: 6},
+			{Offset: 12, Line: 1, Column: 13},
+			{Offset: 17, Line: 1, Column: 18},
+			{Offset: 21, Line: 1, Column: 22},
+		},
+	},
+	{
+		name:  "Malformed integer literals",
+		input: "0x1G;\n99999999999999999999 ٣",
+		expectedTokens: []token.Token{
+			token.New(token.ERROR, "invalid digit 'G' in hexadecimal literal 0x1G"),
+			token.New(token.SEMI, ""),
+			token.New(token.ERROR, "integer literal 99999999999999999999 out of range"),
+			token.New(token.ERROR, "invalid digit '٣' in integer literal ٣"),
+		},
+		expectedPositions: []token.Position{
+			{Line: 1, Column: 1},
+			{Offset: 4, Line: 1, Column: 5},
+			{Offset: 6, Line: 2, Column: 1},
+			{Offset: 27, Line: 2, Column: 22},
+		},
+	},
 	{
 		name:              "String literal",
 		input:             "\"C-beams\"",
@@ -63,7 +101,7 @@ var getNextTokenTestCases = []struct {
 		},
 		expectedPositions: []token.Position{
 			{Line: 1, Column: 1},
-			{Line: 2, Column: 1},
+			{Offset: 18, Line: 2, Column: 1},
 		},
 	},
 	{
@@ -96,6 +134,82 @@ var getNextTokenTestCases = []struct {
 		},
 		expectedPositions: []token.Position{{Line: 1, Column: 1}},
 	},
+	{
+		name:  "String literal with every escape sequence",
+		input: `"\\ \" \0 \x41\x7e \u{e9} \u{1F98A}"`,
+		expectedTokens: []token.Token{
+			token.New(token.STRING_LITERAL, "\\ \" \x00 A~ é 🦊"),
+		},
+		expectedPositions: []token.Position{{Line: 1, Column: 1}},
+	},
+	{
+		name:  "Invalid escape sequences",
+		input: `"a\q" "\x4" "\xFF" "\u41" "\u{}" "\u{1234567}" "\u{D800}" "\q\z" print`,
+		expectedTokens: []token.Token{
+			token.New(token.ERROR, `invalid escape sequence '\q' in string literal: unknown escape sequence`),
+			token.New(token.ERROR, `invalid escape sequence '\x4' in string literal: expected two hexadecimal digits`),
+			token.New(token.ERROR, `invalid escape sequence '\xFF' in string literal: not an ASCII character, use \u{FF}`),
+			token.New(token.ERROR, `invalid escape sequence '\u' in string literal: expected '{'`),
+			token.New(token.ERROR, `invalid escape sequence '\u{' in string literal: expected one to six hexadecimal digits and '}'`),
+			token.New(token.ERROR, `invalid escape sequence '\u{123456' in string literal: expected one to six hexadecimal digits and '}'`),
+			token.New(token.ERROR, `invalid escape sequence '\u{D800}' in string literal: not a Unicode character`),
+			token.New(token.ERROR, `invalid escape sequence '\q' in string literal: unknown escape sequence`),
+			token.New(token.PRINT, ""),
+		},
+		expectedPositions: []token.Position{
+			{Line: 1, Column: 1},
+			{Offset: 6, Line: 1, Column: 7},
+			{Offset: 12, Line: 1, Column: 13},
+			{Offset: 19, Line: 1, Column: 20},
+			{Offset: 26, Line: 1, Column: 27},
+			{Offset: 33, Line: 1, Column: 34},
+			{Offset: 47, Line: 1, Column: 48},
+			{Offset: 58, Line: 1, Column: 59},
+			{Offset: 65, Line: 1, Column: 66},
+		},
+	},
+	{
+		name:  "Backslash at the end of a line",
+		input: "\"a\\\nprint",
+		expectedTokens: []token.Token{
+			token.New(token.ERROR, "unterminated string literal a"),
+			token.New(token.PRINT, ""),
+		},
+		expectedPositions: []token.Position{
+			{Line: 1, Column: 1},
+			{Offset: 4, Line: 2, Column: 1},
+		},
+	},
+	{
+		name:  "Empty string literals",
+		input: "\"\" ``",
+		expectedTokens: []token.Token{
+			token.New(token.STRING_LITERAL, ""),
+			token.New(token.STRING_LITERAL, ""),
+		},
+		expectedPositions: []token.Position{
+			{Line: 1, Column: 1},
+			{Offset: 3, Line: 1, Column: 4},
+		},
+	},
+	{
+		name:  "Raw string literal",
+		input: "`first\r\n\tsecond \\n \"x\"\n` print",
+		expectedTokens: []token.Token{
+			token.New(token.STRING_LITERAL, "first\n\tsecond \\n \"x\"\n"),
+			token.New(token.PRINT, ""),
+		},
+		expectedPositions: []token.Position{
+			{Line: 1, Column: 1},
+			{Offset: 25, Line: 3, Column: 3},
+		},
+	},
+	{
+		name:              "Unterminated raw string literal",
+		input:             "`a\nb",
+		expectedTokens:    []token.Token{token.New(token.ERROR, "unterminated raw string literal")},
+		expectedPositions: []token.Position{{Line: 1, Column: 1}},
+	},
 	{
 		name:              "Unsupported character",
 		input:             "🦊",
@@ -152,6 +266,30 @@ var getNextTokenTestCases = []struct {
 		expectedTokens:    []token.Token{token.New(token.AND, "")},
 		expectedPositions: []token.Position{{Line: 1, Column: 1}},
 	},
+	{
+		name:  "Comparison and logical or operators",
+		input: "<= < > >= <> != ! |",
+		expectedTokens: []token.Token{
+			token.New(token.LE, ""),
+			token.New(token.LT, ""),
+			token.New(token.GT, ""),
+			token.New(token.GE, ""),
+			token.New(token.NE, ""),
+			token.New(token.NE, ""),
+			token.New(token.NOT, ""),
+			token.New(token.OR, ""),
+		},
+		expectedPositions: []token.Position{
+			{Line: 1, Column: 1},
+			{Offset: 3, Line: 1, Column: 4},
+			{Offset: 5, Line: 1, Column: 6},
+			{Offset: 7, Line: 1, Column: 8},
+			{Offset: 10, Line: 1, Column: 11},
+			{Offset: 13, Line: 1, Column: 14},
+			{Offset: 16, Line: 1, Column: 17},
+			{Offset: 18, Line: 1, Column: 19},
+		},
+	},
 	{
 		name:              "Logical not operator",
 		input:             "!",
@@ -188,6 +326,12 @@ var getNextTokenTestCases = []struct {
 		expectedTokens:    []token.Token{token.New(token.COLON, "")},
 		expectedPositions: []token.Position{{Line: 1, Column: 1}},
 	},
+	{
+		name:              "Comma",
+		input:             ",",
+		expectedTokens:    []token.Token{token.New(token.COMMA, "")},
+		expectedPositions: []token.Position{{Line: 1, Column: 1}},
+	},
 	{
 		name:              "For keyword",
 		input:             "for",
@@ -242,6 +386,20 @@ var getNextTokenTestCases = []struct {
 		expectedTokens:    []token.Token{token.New(token.PRINT, "")},
 		expectedPositions: []token.Position{{Line: 1, Column: 1}},
 	},
+	{
+		name:  "Boolean literals",
+		input: "true false truth",
+		expectedTokens: []token.Token{
+			token.New(token.BOOLEAN_LITERAL, "true"),
+			token.New(token.BOOLEAN_LITERAL, "false"),
+			token.New(token.IDENT, "truth"),
+		},
+		expectedPositions: []token.Position{
+			{Line: 1, Column: 1},
+			{Offset: 5, Line: 1, Column: 6},
+			{Offset: 11, Line: 1, Column: 12},
+		},
+	},
 	{
 		name:  "Whitespace is skipped",
 		input: "first   \n    second",
@@ -251,7 +409,7 @@ var getNextTokenTestCases = []struct {
 		},
 		expectedPositions: []token.Position{
 			{Line: 1, Column: 1},
-			{Line: 2, Column: 5},
+			{Offset: 13, Line: 2, Column: 5},
 		},
 	},
 	{
@@ -279,23 +437,23 @@ var getNextTokenTestCases = []struct {
 		},
 		expectedPositions: []token.Position{
 			{Line: 1, Column: 1},
-			{Line: 1, Column: 5},
-			{Line: 1, Column: 7},
-			{Line: 1, Column: 10},
-			{Line: 1, Column: 11},
-			{Line: 1, Column: 13},
-			{Line: 1, Column: 15},
+			{Offset: 4, Line: 1, Column: 5},
+			{Offset: 6, Line: 1, Column: 7},
+			{Offset: 9, Line: 1, Column: 10},
+			{Offset: 10, Line: 1, Column: 11},
+			{Offset: 12, Line: 1, Column: 13},
+			{Offset: 14, Line: 1, Column: 15},
 
-			{Line: 2, Column: 2},
-			{Line: 2, Column: 4},
-			{Line: 2, Column: 7},
-			{Line: 2, Column: 9},
-			{Line: 2, Column: 11},
-			{Line: 2, Column: 12},
+			{Offset: 18, Line: 2, Column: 2},
+			{Offset: 20, Line: 2, Column: 4},
+			{Offset: 23, Line: 2, Column: 7},
+			{Offset: 25, Line: 2, Column: 9},
+			{Offset: 27, Line: 2, Column: 11},
+			{Offset: 28, Line: 2, Column: 12},
 
-			{Line: 3, Column: 1},
-			{Line: 3, Column: 5},
-			{Line: 3, Column: 8},
+			{Offset: 30, Line: 3, Column: 1},
+			{Offset: 34, Line: 3, Column: 5},
+			{Offset: 37, Line: 3, Column: 8},
 		},
 	},
 	{
@@ -308,8 +466,8 @@ var getNextTokenTestCases = []struct {
 		},
 		expectedPositions: []token.Position{
 			{Line: 1, Column: 1},
-			{Line: 1, Column: 2},
-			{Line: 1, Column: 4},
+			{Offset: 1, Line: 1, Column: 2},
+			{Offset: 3, Line: 1, Column: 4},
 		},
 	},
 	{
@@ -326,12 +484,12 @@ var getNextTokenTestCases = []struct {
 		},
 		expectedPositions: []token.Position{
 			{Line: 1, Column: 1},
-			{Line: 1, Column: 5},
-			{Line: 1, Column: 7},
-			{Line: 1, Column: 9},
-			{Line: 1, Column: 13},
-			{Line: 1, Column: 16},
-			{Line: 1, Column: 17},
+			{Offset: 4, Line: 1, Column: 5},
+			{Offset: 6, Line: 1, Column: 7},
+			{Offset: 8, Line: 1, Column: 9},
+			{Offset: 12, Line: 1, Column: 13},
+			{Offset: 15, Line: 1, Column: 16},
+			{Offset: 16, Line: 1, Column: 17},
 		},
 	},
 	{
@@ -351,15 +509,15 @@ var getNextTokenTestCases = []struct {
 		},
 		expectedPositions: []token.Position{
 			{Line: 1, Column: 1},
-			{Line: 1, Column: 5},
-			{Line: 1, Column: 15},
-			{Line: 1, Column: 17},
-			{Line: 1, Column: 23},
+			{Offset: 4, Line: 1, Column: 5},
+			{Offset: 14, Line: 1, Column: 15},
+			{Offset: 16, Line: 1, Column: 17},
+			{Offset: 22, Line: 1, Column: 23},
 
-			{Line: 2, Column: 1},
-			{Line: 2, Column: 11},
-			{Line: 2, Column: 14},
-			{Line: 2, Column: 26},
+			{Offset: 24, Line: 2, Column: 1},
+			{Offset: 34, Line: 2, Column: 11},
+			{Offset: 37, Line: 2, Column: 14},
+			{Offset: 49, Line: 2, Column: 26},
 		},
 	},
 	{
@@ -375,13 +533,13 @@ var getNextTokenTestCases = []struct {
 			token.New(token.SEMI, ""),
 		},
 		expectedPositions: []token.Position{
-			{Line: 1, Column: 13},
-			{Line: 1, Column: 23},
-			{Line: 1, Column: 24},
-			{Line: 1, Column: 30},
-			{Line: 1, Column: 40},
-			{Line: 1, Column: 53},
-			{Line: 1, Column: 57},
+			{Offset: 12, Line: 1, Column: 13},
+			{Offset: 22, Line: 1, Column: 23},
+			{Offset: 23, Line: 1, Column: 24},
+			{Offset: 29, Line: 1, Column: 30},
+			{Offset: 39, Line: 1, Column: 40},
+			{Offset: 52, Line: 1, Column: 53},
+			{Offset: 56, Line: 1, Column: 57},
 		},
 	},
 	{
@@ -400,14 +558,14 @@ var getNextTokenTestCases = []struct {
 		},
 		expectedPositions: []token.Position{
 			{Line: 1, Column: 1},
-			{Line: 1, Column: 3},
-			{Line: 1, Column: 5},
-			{Line: 1, Column: 6},
+			{Offset: 2, Line: 1, Column: 3},
+			{Offset: 4, Line: 1, Column: 5},
+			{Offset: 5, Line: 1, Column: 6},
 
-			{Line: 2, Column: 1},
-			{Line: 2, Column: 3},
-			{Line: 2, Column: 5},
-			{Line: 2, Column: 6},
+			{Offset: 28, Line: 2, Column: 1},
+			{Offset: 30, Line: 2, Column: 3},
+			{Offset: 32, Line: 2, Column: 5},
+			{Offset: 33, Line: 2, Column: 6},
 		},
 	},
 	{
@@ -425,13 +583,13 @@ var getNextTokenTestCases = []struct {
 		},
 		expectedPositions: []token.Position{
 			{Line: 1, Column: 1},
-			{Line: 1, Column: 3},
-			{Line: 1, Column: 5},
-			{Line: 1, Column: 6},
-			{Line: 1, Column: 30},
-			{Line: 1, Column: 32},
-			{Line: 1, Column: 34},
-			{Line: 1, Column: 35},
+			{Offset: 2, Line: 1, Column: 3},
+			{Offset: 4, Line: 1, Column: 5},
+			{Offset: 5, Line: 1, Column: 6},
+			{Offset: 29, Line: 1, Column: 30},
+			{Offset: 31, Line: 1, Column: 32},
+			{Offset: 33, Line: 1, Column: 34},
+			{Offset: 34, Line: 1, Column: 35},
 		},
 	},
 	{
@@ -444,7 +602,7 @@ var getNextTokenTestCases = []struct {
 			token.New(token.INTEGER_LITERAL, "1"),
 		},
 		expectedPositions: []token.Position{
-			{Line: 4, Column: 1},
+			{Offset: 8, Line: 4, Column: 1},
 		},
 	},
 	{
@@ -465,14 +623,14 @@ func TestGetNextToken(t *testing.T) {
 			lexer := New(testCase.input)
 
 			for i, expectedToken := range testCase.expectedTokens {
-				actualToken, actualPos := lexer.GetNextToken()
+				actualToken, actualSpan := lexer.GetNextToken()
 
 				if actualToken != expectedToken {
 					t.Errorf("Expected %v, got %v", expectedToken, actualToken)
 				}
 
-				if expectedPos := testCase.expectedPositions[i]; actualPos != expectedPos {
-					t.Errorf("Expected %v, got %v", expectedPos, actualPos)
+				if expectedPos := testCase.expectedPositions[i]; actualSpan.Start != expectedPos {
+					t.Errorf("Expected %#v, got %#v", expectedPos, actualSpan.Start)
 				}
 			}
 
@@ -482,3 +640,191 @@ func TestGetNextToken(t *testing.T) {
 		})
 	}
 }
+
+func TestGetNextTokenSpans(t *testing.T) {
+	testCases := []struct {
+		name          string
+		input         string
+		expectedSpans []token.Span
+	}{
+		{
+			name:  "Empty input has an empty EOF span",
+			input: "",
+			expectedSpans: []token.Span{
+				{Start: token.Position{Line: 1, Column: 1}, End: token.Position{Line: 1, Column: 1}},
+			},
+		},
+		{
+			name:  "Spans end after the last character of the token",
+			input: "x := 42;",
+			expectedSpans: []token.Span{
+				{Start: token.Position{Offset: 0, Line: 1, Column: 1}, End: token.Position{Offset: 1, Line: 1, Column: 2}},
+				{Start: token.Position{Offset: 2, Line: 1, Column: 3}, End: token.Position{Offset: 4, Line: 1, Column: 5}},
+				{Start: token.Position{Offset: 5, Line: 1, Column: 6}, End: token.Position{Offset: 7, Line: 1, Column: 8}},
+				{Start: token.Position{Offset: 7, Line: 1, Column: 8}, End: token.Position{Offset: 8, Line: 1, Column: 9}},
+				{Start: token.Position{Offset: 8, Line: 1, Column: 9}, End: token.Position{Offset: 8, Line: 1, Column: 9}},
+			},
+		},
+		{
+			name:  "Offsets count bytes and columns count characters",
+			input: "\"ä🦊\"\nåb",
+			expectedSpans: []token.Span{
+				{Start: token.Position{Offset: 0, Line: 1, Column: 1}, End: token.Position{Offset: 8, Line: 1, Column: 5}},
+				{Start: token.Position{Offset: 9, Line: 2, Column: 1}, End: token.Position{Offset: 12, Line: 2, Column: 3}},
+				{Start: token.Position{Offset: 12, Line: 2, Column: 3}, End: token.Position{Offset: 12, Line: 2, Column: 3}},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			lexer := New(testCase.input)
+
+			for _, expectedSpan := range testCase.expectedSpans {
+				_, actualSpan := lexer.GetNextToken()
+
+				if actualSpan != expectedSpan {
+					t.Errorf("Expected %#v, got %#v", expectedSpan, actualSpan)
+				}
+			}
+		})
+	}
+}
+
+func TestNewFilePositionsCarryTheFileName(t *testing.T) {
+	lexer := NewFile(token.NewFileSet().AddFile("lib.mpl", "print x;"))
+
+	lexer.GetNextToken()
+	_, span := lexer.GetNextToken()
+
+	expected := token.Span{
+		Start: token.Position{Filename: "lib.mpl", Offset: 6, Line: 1, Column: 7},
+		End:   token.Position{Filename: "lib.mpl", Offset: 7, Line: 1, Column: 8},
+	}
+	if span != expected {
+		t.Errorf("Expected %#v, got %#v", expected, span)
+	}
+}
+
+// repeatReader repeats a string forever.
+type repeatReader struct {
+	s   string
+	pos int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = r.s[r.pos%len(r.s)]
+		r.pos++
+	}
+
+	return len(p), nil
+}
+
+func TestNewReaderReadsIncrementally(t *testing.T) {
+	reader := &repeatReader{s: "print x;\n"}
+	lexer := NewReader("", reader)
+
+	for line := 1; line <= 10000; line++ {
+		for _, expected := range []token.Token{
+			token.New(token.PRINT, ""),
+			token.New(token.IDENT, "x"),
+			token.New(token.SEMI, ""),
+		} {
+			if tok, span := lexer.GetNextToken(); tok != expected || span.Start.Line != line {
+				t.Fatalf("Expected %v on line %d, got %v at %v", expected, line, tok, span.Start)
+			}
+		}
+	}
+
+	if reader.pos > 100000 {
+		t.Errorf("Expected the input to be read incrementally, read %d bytes", reader.pos)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("disk on fire")
+}
+
+func TestNewReaderReportsReadErrors(t *testing.T) {
+	lexer := NewReader("", io.MultiReader(strings.NewReader("print"), failingReader{}))
+
+	if tok, _ := lexer.GetNextToken(); tok != token.New(token.PRINT, "") {
+		t.Errorf("Expected PRINT, got %v", tok)
+	}
+
+	expected := token.New(token.ERROR, "cannot read source code: disk on fire")
+	if tok, _ := lexer.GetNextToken(); tok != expected {
+		t.Errorf("Expected %v, got %v", expected, tok)
+	}
+
+	if tok, _ := lexer.GetNextToken(); tok.Type() != token.EOF {
+		t.Errorf("Expected EOF, got %v", tok)
+	}
+}
+
+// generateProgram returns a program of the given number of lines with every
+// kind of token in it.
+func generateProgram(lines int) string {
+	var sb strings.Builder
+
+	for i := 0; i < lines; i++ {
+		switch i % 5 {
+		case 0:
+			fmt.Fprintf(&sb, "var variable%d : int := %d * (counter + 12345);\n", i, i)
+		case 1:
+			fmt.Fprintf(&sb, "for index in 0..%d do print \"line %d\\n\"; end for;\n", i, i)
+		case 2:
+			fmt.Fprintf(&sb, "assert(!(variable%d < 100) & counter = %d); // a comment\n", i-2, i)
+		case 3:
+			fmt.Fprintf(&sb, "/* a block comment */ read name%d;\n", i)
+		case 4:
+			fmt.Fprintf(&sb, "counter := counter / 2 - %d;\n", i)
+		}
+	}
+
+	return sb.String()
+}
+
+func benchmarkLexer(b *testing.B, newLexer func(source string) *Lexer) {
+	source := generateProgram(10000)
+
+	b.SetBytes(int64(len(source)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		lexer := newLexer(source)
+
+		for tok, _ := lexer.GetNextToken(); tok.Type() != token.EOF; tok, _ = lexer.GetNextToken() {
+			if tok.Type() == token.ERROR {
+				b.Fatalf("Unexpected error token %v", tok)
+			}
+		}
+	}
+}
+
+func BenchmarkGetNextToken(b *testing.B) {
+	benchmarkLexer(b, New)
+}
+
+func BenchmarkGetNextTokenFromReader(b *testing.B) {
+	benchmarkLexer(b, func(source string) *Lexer {
+		return NewReader("", strings.NewReader(source))
+	})
+}
+
+func BenchmarkLongLexemes(b *testing.B) {
+	source := "\"" + strings.Repeat("a", 100000) + "\" " + strings.Repeat("b", 100000)
+
+	b.SetBytes(int64(len(source)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		lexer := New(source)
+		lexer.GetNextToken()
+		lexer.GetNextToken()
+	}
+}
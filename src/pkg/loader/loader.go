@@ -0,0 +1,154 @@
+// Package loader reads a program that is split into several files and
+// resolves its imports.
+package loader
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/mjjs/minipl-go/pkg/ast"
+	"github.com/mjjs/minipl-go/pkg/lexer"
+	"github.com/mjjs/minipl-go/pkg/parser"
+	"github.com/mjjs/minipl-go/pkg/token"
+)
+
+type loader struct {
+	fset *token.FileSet
+	// loaded holds the absolute paths of the files that have been imported.
+	loaded map[string]bool
+	// importing holds the files whose imports are being resolved, the
+	// importing file before the files it imports.
+	importing []file
+
+	errors []error
+}
+
+type file struct {
+	name string
+	key  string
+}
+
+// Load parses the program in path and the files it imports, and adds them to
+// fset. The path of an import is relative to the directory of the importing
+// file. The statements of an imported file are stored in the first
+// ast.ImportStmt that imports it; a file is run only once, so later imports of
+// the same file are left empty. Errors are reported for files that cannot be
+// read, for import cycles and for the syntax errors of every file.
+func Load(fset *token.FileSet, path string) (ast.Prog, []error) {
+	l := &loader{
+		fset:   fset,
+		loaded: make(map[string]bool),
+	}
+
+	statements, err := l.load(path)
+	if err != nil {
+		return ast.Prog{}, []error{err}
+	}
+
+	return ast.Prog{Statements: statements}, l.errors
+}
+
+// LoadReader is like Load for a program that is read from r as it is parsed,
+// for example from the standard input. The name is used as the file name in
+// positions, and imports are relative to the directory of name. The source
+// code of the program is not kept, so it is not added to fset.
+func LoadReader(fset *token.FileSet, name string, r io.Reader) (ast.Prog, []error) {
+	l := &loader{
+		fset:   fset,
+		loaded: make(map[string]bool),
+	}
+
+	statements, err := l.parse(name, lexer.NewReader(name, r))
+	if err != nil {
+		return ast.Prog{}, []error{err}
+	}
+
+	return ast.Prog{Statements: statements}, l.errors
+}
+
+// load parses the file in path and resolves its imports.
+func (l *loader) load(path string) (ast.Stmts, error) {
+	source, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ast.Stmts{}, err
+	}
+
+	return l.parse(path, lexer.NewFile(l.fset.AddFile(path, string(source))))
+}
+
+// parse parses the file in path from lex and resolves its imports.
+func (l *loader) parse(path string, lex parser.Lexer) (ast.Stmts, error) {
+	key, err := filepath.Abs(path)
+	if err != nil {
+		return ast.Stmts{}, err
+	}
+
+	l.loaded[key] = true
+	l.importing = append(l.importing, file{name: path, key: key})
+	defer func() { l.importing = l.importing[:len(l.importing)-1] }()
+
+	root, syntaxErrors := parser.New(lex).Parse()
+	l.errors = append(l.errors, syntaxErrors...)
+
+	for i, stmt := range root.Statements.Statements {
+		if imp, ok := stmt.(ast.ImportStmt); ok {
+			root.Statements.Statements[i] = l.resolve(path, imp)
+		}
+	}
+
+	return root.Statements, nil
+}
+
+// resolve loads the file imported by imp in the file importer.
+func (l *loader) resolve(importer string, imp ast.ImportStmt) ast.ImportStmt {
+	path := imp.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(importer), path)
+	}
+
+	key, err := filepath.Abs(path)
+	if err != nil {
+		l.errors = append(l.errors, token.Errorf(imp.Pos, "cannot import %s: %v", path, err))
+		return imp
+	}
+
+	for i, f := range l.importing {
+		if f.key == key {
+			l.errors = append(l.errors, token.Errorf(imp.Pos, "import cycle: %s", cycle(l.importing[i:], path)))
+			return imp
+		}
+	}
+
+	if l.loaded[key] {
+		return imp
+	}
+
+	statements, err := l.load(path)
+	if err != nil {
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			err = pathErr.Err
+		}
+
+		l.errors = append(l.errors, token.Errorf(imp.Pos, "cannot import %s: %v", path, err))
+		return imp
+	}
+
+	imp.Statements = statements
+
+	return imp
+}
+
+// cycle describes an import cycle, for example "a.mpl -> b.mpl -> a.mpl".
+func cycle(files []file, path string) string {
+	names := make([]string, 0, len(files)+1)
+	for _, f := range files {
+		names = append(names, f.name)
+	}
+
+	return strings.Join(append(names, path), " -> ")
+}
@@ -0,0 +1,165 @@
+package loader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mjjs/minipl-go/internal/testfiles"
+	"github.com/mjjs/minipl-go/pkg/ast"
+	"github.com/mjjs/minipl-go/pkg/token"
+)
+
+func TestLoad(t *testing.T) {
+	dir := testfiles.Write(t, map[string]string{
+		"main.mpl":       "import \"lib/a.mpl\";\nimport \"lib/b.mpl\";\nprint a + b;",
+		"lib/a.mpl":      "import \"common.mpl\";\nvar a : int := c;",
+		"lib/b.mpl":      "import \"common.mpl\";\nvar b : int := c;",
+		"lib/common.mpl": "var c : int := 1;",
+	})
+	main := filepath.Join(dir, "main.mpl")
+
+	fset := token.NewFileSet()
+	root, errors := Load(fset, main)
+	if len(errors) != 0 {
+		t.Fatalf("Expected no errors, got %v", errors)
+	}
+
+	statements := root.Statements.Statements
+	a := statements[0].(ast.ImportStmt)
+	b := statements[1].(ast.ImportStmt)
+
+	common := a.Statements.Statements[0].(ast.ImportStmt)
+	if len(common.Statements.Statements) != 1 {
+		t.Errorf("Expected the first import of common.mpl to be resolved, got %v", common)
+	}
+
+	if again := b.Statements.Statements[0].(ast.ImportStmt); len(again.Statements.Statements) != 0 {
+		t.Errorf("Expected the second import of common.mpl to be empty, got %v", again)
+	}
+
+	expectedPos := token.Position{
+		Filename: filepath.Join(dir, "lib", "common.mpl"),
+		Offset:   0,
+		Line:     1,
+		Column:   1,
+	}
+	if pos := common.Statements.Position(); pos != expectedPos {
+		t.Errorf("Expected %#v, got %#v", expectedPos, pos)
+	}
+
+	var names []string
+	for _, f := range fset.Files() {
+		names = append(names, f.Name())
+	}
+
+	expectedNames := []string{
+		main,
+		filepath.Join(dir, "lib", "a.mpl"),
+		filepath.Join(dir, "lib", "common.mpl"),
+		filepath.Join(dir, "lib", "b.mpl"),
+	}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Errorf("Expected files %v, got %v", expectedNames, names)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	testCases := []struct {
+		name           string
+		files          map[string]string
+		expectedErrors []string
+	}{
+		{
+			name: "Import cycle",
+			files: map[string]string{
+				"main.mpl": "import \"a.mpl\";",
+				"a.mpl":    "print 1;\nimport \"b.mpl\";",
+				"b.mpl":    "import \"a.mpl\";",
+			},
+			expectedErrors: []string{
+				"{dir}/b.mpl:1:1: import cycle: {dir}/a.mpl -> {dir}/b.mpl -> {dir}/a.mpl",
+			},
+		},
+		{
+			name: "File importing itself",
+			files: map[string]string{
+				"main.mpl": "print 1;\nimport \"main.mpl\";",
+			},
+			expectedErrors: []string{
+				"{dir}/main.mpl:2:1: import cycle: {dir}/main.mpl -> {dir}/main.mpl",
+			},
+		},
+		{
+			name: "Missing file",
+			files: map[string]string{
+				"main.mpl": "import \"missing.mpl\";",
+			},
+			expectedErrors: []string{
+				"{dir}/main.mpl:1:1: cannot import {dir}/missing.mpl: no such file or directory",
+			},
+		},
+		{
+			name: "Syntax errors name the file they are in",
+			files: map[string]string{
+				"main.mpl": "import \"a.mpl\";\nprint ;",
+				"a.mpl":    "var x : int\nprint x;",
+			},
+			expectedErrors: []string{
+				"{dir}/main.mpl:2:7: syntax error: expected expression after 'print', found ';'",
+				"{dir}/a.mpl:2:1: syntax error: expected ';' or ':=' after type, found 'print' (missing ';' at the end of line 1?)",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			dir := testfiles.Write(t, testCase.files)
+
+			_, errors := Load(token.NewFileSet(), filepath.Join(dir, "main.mpl"))
+
+			var actual []string
+			for _, err := range errors {
+				actual = append(actual, err.Error())
+			}
+
+			var expected []string
+			for _, e := range testCase.expectedErrors {
+				expected = append(expected, strings.ReplaceAll(e, "{dir}", dir))
+			}
+
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("Expected:\n%v\ngot:\n%v", expected, actual)
+			}
+		})
+	}
+}
+
+func TestLoadMissingMainFile(t *testing.T) {
+	_, errors := Load(token.NewFileSet(), filepath.Join(os.TempDir(), "does-not-exist.mpl"))
+
+	if len(errors) != 1 || !os.IsNotExist(errors[0]) {
+		t.Errorf("Expected a file not found error, got %v", errors)
+	}
+}
+
+func TestLoadReader(t *testing.T) {
+	dir := testfiles.Write(t, map[string]string{"lib.mpl": "var x := 1;"})
+	name := filepath.Join(dir, "<stdin>")
+
+	fset := token.NewFileSet()
+	root, errors := LoadReader(fset, name, strings.NewReader("import \"lib.mpl\";\nprint x;"))
+	if len(errors) != 0 {
+		t.Fatalf("Expected no errors, got %v", errors)
+	}
+
+	if pos := root.Statements.Statements[1].Position(); pos.Filename != name {
+		t.Errorf("Expected the positions to carry %s, got %v", name, pos)
+	}
+
+	if files := fset.Files(); len(files) != 1 || files[0].Name() != filepath.Join(dir, "lib.mpl") {
+		t.Errorf("Expected only the imported file in the file set, got %v", files)
+	}
+}
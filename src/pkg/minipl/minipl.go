@@ -0,0 +1,346 @@
+// Package minipl embeds MiniPL as a scripting language in Go programs.
+//
+// A program is compiled once with Compile and can then be run any number of
+// times with Run. The host program can give the script variables of its own
+// and functions that are called like the builtin ones:
+//
+//	program, diagnostics := minipl.Compile(`total := total + twice(n);`)
+//	if len(diagnostics) > 0 {
+//		// Report the syntax errors.
+//	}
+//
+//	vars := map[string]interface{}{"total": 1, "n": 20}
+//	err := program.Run(ctx, minipl.Options{
+//		Vars: vars,
+//		Funcs: map[string]minipl.Func{
+//			"twice": {
+//				Signature: types.NewFunction([]types.Type{types.Int}, types.Int),
+//				Impl: func(args []interface{}) (interface{}, error) {
+//					return 2 * args[0].(int), nil
+//				},
+//			},
+//		},
+//	})
+//	// vars["total"] is now 41.
+//
+// The types of the host variables and the signatures of the host functions
+// are only known when the program is run, so the semantic errors of a
+// program, such as type errors, are reported by Check and Run rather than by
+// Compile.
+package minipl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/mjjs/minipl-go/pkg/ast"
+	"github.com/mjjs/minipl-go/pkg/builtin"
+	"github.com/mjjs/minipl-go/pkg/compile"
+	"github.com/mjjs/minipl-go/pkg/interpreter"
+	"github.com/mjjs/minipl-go/pkg/lexer"
+	"github.com/mjjs/minipl-go/pkg/parser"
+	"github.com/mjjs/minipl-go/pkg/symboltable"
+	"github.com/mjjs/minipl-go/pkg/token"
+	"github.com/mjjs/minipl-go/pkg/types"
+)
+
+// Diagnostic is an error found in a program before it is run. Only the line
+// and the column of Pos are set, and Pos is the zero Position for errors that
+// are not about a particular place in the program, such as an unsupported
+// host variable.
+type Diagnostic struct {
+	Pos     token.Position
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	if d.Pos.Line == 0 {
+		return d.Message
+	}
+
+	return fmt.Sprintf("%s: %s", d.Pos, d.Message)
+}
+
+// Diagnostics is the error returned by Run for a program that has errors.
+type Diagnostics []Diagnostic
+
+func (d Diagnostics) Error() string {
+	lines := make([]string, len(d))
+	for i, diagnostic := range d {
+		lines[i] = diagnostic.String()
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Program is a compiled MiniPL program.
+type Program struct {
+	root ast.Prog
+}
+
+// Compile parses the source code of a program. Imports are not supported, as
+// an embedded program does not come from a file. The syntax errors are
+// returned as diagnostics, and the Program is nil if there are any. Compile
+// does not panic on any source code; a crash of the parser is reported as a
+// diagnostic too.
+func Compile(src string) (program *Program, diagnostics []Diagnostic) {
+	defer func() {
+		if r := recover(); r != nil {
+			program = nil
+			diagnostics = []Diagnostic{{Message: fmt.Sprintf("internal compiler error: %v", r)}}
+		}
+	}()
+
+	root, errors := parser.New(lexer.New(src)).Parse()
+
+	diagnostics = diagnose(errors)
+
+	ast.Inspect(root, func(node ast.Node) bool {
+		if imp, ok := node.(ast.ImportStmt); ok {
+			diagnostics = append(diagnostics, Diagnostic{
+				Pos:     token.Position{Line: imp.Pos.Line, Column: imp.Pos.Column},
+				Message: "import is not supported in embedded programs",
+			})
+		}
+
+		return true
+	})
+
+	if len(diagnostics) > 0 {
+		return nil, diagnostics
+	}
+
+	return &Program{root: root}, nil
+}
+
+// Func is a function provided by the host program. The arguments passed to
+// Impl and its result are Go values of the corresponding MiniPL types: int,
+// string or bool. An error returned by Impl stops the program with a runtime
+// error.
+type Func struct {
+	Signature *types.Function
+	Impl      func(args []interface{}) (interface{}, error)
+}
+
+// Limits bounds the resources a run of a program may use. A zero limit means
+// that there is no limit.
+type Limits = interpreter.Limits
+
+// RuntimeError is the error that stops a program at runtime.
+type RuntimeError = interpreter.RuntimeError
+
+// The errors wrapped by the RuntimeError of a run that exceeds one of its
+// limits.
+var (
+	ErrStepLimit   = interpreter.ErrStepLimit
+	ErrOutputLimit = interpreter.ErrOutputLimit
+	ErrStringLimit = interpreter.ErrStringLimit
+)
+
+// Options configures a run of a program.
+type Options struct {
+	// Stdin is read by the read statements. A nil Stdin is empty.
+	Stdin io.Reader
+	// Stdout receives the output of the print statements. The output is
+	// discarded if Stdout is nil.
+	Stdout io.Writer
+	// Vars holds the host variables, which the program can use without
+	// declaring them. The type of each variable is the type of its value,
+	// which must be an int, a string or a bool. When the run ends, also with
+	// an error, Vars holds the final values of the variables.
+	Vars map[string]interface{}
+	// Funcs holds the host functions by name. They cannot have the name of a
+	// builtin function.
+	Funcs map[string]Func
+	// Limits bounds the number of statements executed, the size of the output
+	// and the length of strings.
+	Limits Limits
+}
+
+// Check runs the semantic analysis of the program with the host variables
+// and functions of opts and returns the errors found.
+func (p *Program) Check(opts Options) []Diagnostic {
+	_, diagnostics := p.check(opts)
+	return diagnostics
+}
+
+// check returns the functions the program can call in addition to the
+// builtin ones together with the errors.
+func (p *Program) check(opts Options) (builtin.Set, []Diagnostic) {
+	var diagnostics []Diagnostic
+
+	symbols := symboltable.NewSymbolTable()
+	for _, name := range sortedKeys(opts.Vars) {
+		t, ok := typeOf(opts.Vars[name])
+		if !ok {
+			diagnostics = append(diagnostics, Diagnostic{
+				Message: fmt.Sprintf("host variable %s has the unsupported Go type %T", name, opts.Vars[name]),
+			})
+			continue
+		}
+
+		symbols.Insert(name, t)
+	}
+
+	funcs := builtin.Set{}
+	for _, name := range sortedFuncNames(opts.Funcs) {
+		f := opts.Funcs[name]
+		if _, ok := builtin.Lookup(name); ok {
+			diagnostics = append(diagnostics, Diagnostic{
+				Message: fmt.Sprintf("host function %s has the name of a builtin function", name),
+			})
+			continue
+		}
+
+		if f.Signature == nil || f.Signature.Result() == nil || f.Impl == nil {
+			diagnostics = append(diagnostics, Diagnostic{
+				Message: fmt.Sprintf("host function %s must have a signature with a result and an implementation", name),
+			})
+			continue
+		}
+
+		funcs[name] = builtin.Func{Name: name, Signature: f.Signature, Impl: checkResult(f)}
+	}
+
+	_, errors := compile.Check(p.root, compile.Options{Symbols: symbols, Funcs: funcs})
+
+	diagnostics = append(diagnostics, diagnose(errors)...)
+
+	return funcs, diagnostics
+}
+
+// Run checks the program and runs it if there are no errors, which are
+// returned as Diagnostics otherwise. A runtime error, such as a failing
+// assertion or an exceeded limit, stops the program and is returned as a
+// *RuntimeError. Run returns the error of ctx if it is done before the
+// program starts, and stops the program with a RuntimeError wrapping it if it
+// is done while the program runs. Run does not panic; a crash of the
+// interpreter, or a panic in a host function, is returned as an internal
+// error.
+func (p *Program) Run(ctx context.Context, opts Options) error {
+	funcs, diagnostics := p.check(opts)
+	if len(diagnostics) > 0 {
+		return Diagnostics(diagnostics)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	stdin, stdout := opts.Stdin, opts.Stdout
+	if stdin == nil {
+		stdin = strings.NewReader("")
+	}
+	if stdout == nil {
+		stdout = ioutil.Discard
+	}
+
+	i := interpreter.New(stdout, stdin).
+		WithFunctions(funcs).
+		WithContext(ctx).
+		WithLimits(opts.Limits)
+	for name, value := range opts.Vars {
+		i.SetVariable(name, value)
+	}
+
+	err := run(i, p.root)
+
+	for name := range opts.Vars {
+		opts.Vars[name], _ = i.Variable(name)
+	}
+
+	return err
+}
+
+// run runs the program with the interpreter and recovers from a crash of the
+// interpreter, which only recovers from runtime errors itself.
+func run(i *interpreter.Interpreter, root ast.Prog) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("internal interpreter error: %v", r)
+		}
+	}()
+
+	return i.Run(root)
+}
+
+// typeOf returns the MiniPL type of a Go value.
+func typeOf(value interface{}) (types.Type, bool) {
+	switch value.(type) {
+	case int:
+		return types.Int, true
+	case string:
+		return types.String, true
+	case bool:
+		return types.Bool, true
+	}
+
+	return nil, false
+}
+
+// checkResult wraps the implementation of a host function so that a result
+// of the wrong type is reported as a runtime error instead of crashing the
+// interpreter.
+func checkResult(f Func) builtin.Impl {
+	return func(args []interface{}) (interface{}, error) {
+		result, err := f.Impl(args)
+		if err != nil {
+			return nil, err
+		}
+
+		if t, ok := typeOf(result); !ok || !types.Identical(t, f.Signature.Result()) {
+			return nil, fmt.Errorf("host function returned %T, not %s", result, f.Signature.Result())
+		}
+
+		return result, nil
+	}
+}
+
+// diagnose converts errors into diagnostics. The errors at a position in the
+// program are *token.Error values; the others are not about a particular
+// place in the program.
+func diagnose(errs []error) []Diagnostic {
+	var diagnostics []Diagnostic
+
+	for _, err := range errs {
+		var positioned *token.Error
+		if !errors.As(err, &positioned) {
+			diagnostics = append(diagnostics, Diagnostic{Message: err.Error()})
+			continue
+		}
+
+		diagnostics = append(diagnostics, Diagnostic{
+			Pos:     token.Position{Line: positioned.Pos.Line, Column: positioned.Pos.Column},
+			Message: positioned.Msg,
+		})
+	}
+
+	return diagnostics
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func sortedFuncNames(m map[string]Func) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
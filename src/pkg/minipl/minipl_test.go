@@ -0,0 +1,349 @@
+package minipl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mjjs/minipl-go/pkg/token"
+	"github.com/mjjs/minipl-go/pkg/types"
+)
+
+var twice = Func{
+	Signature: types.NewFunction([]types.Type{types.Int}, types.Int),
+	Impl: func(args []interface{}) (interface{}, error) {
+		return 2 * args[0].(int), nil
+	},
+}
+
+func TestRun(t *testing.T) {
+	program, diagnostics := Compile(`
+	var name : string;
+	read name;
+	print greeting + name;
+	total := total + twice(n);
+	done := true;
+	`)
+	if len(diagnostics) > 0 {
+		t.Fatalf("Expected no diagnostics, got %v", diagnostics)
+	}
+
+	vars := map[string]interface{}{
+		"greeting": "Hello, ",
+		"total":    1,
+		"n":        20,
+		"done":     false,
+	}
+	out := &bytes.Buffer{}
+
+	err := program.Run(context.Background(), Options{
+		Stdin:  strings.NewReader("world\n"),
+		Stdout: out,
+		Vars:   vars,
+		Funcs:  map[string]Func{"twice": twice},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if out.String() != "Hello, world\n" {
+		t.Errorf("Expected the output Hello, world, got %q", out)
+	}
+
+	expected := map[string]interface{}{
+		"greeting": "Hello, ",
+		"total":    41,
+		"n":        20,
+		"done":     true,
+	}
+	if !reflect.DeepEqual(vars, expected) {
+		t.Errorf("Expected the variables %v, got %v", expected, vars)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	_, diagnostics := Compile("print 1 +;\nimport \"lib.mpl\";")
+
+	expected := []Diagnostic{
+		{Pos: token.Position{Line: 1, Column: 10}, Message: "syntax error: expected operand after '+', found ';'"},
+		{Pos: token.Position{Line: 2, Column: 1}, Message: "import is not supported in embedded programs"},
+	}
+	if !reflect.DeepEqual(diagnostics, expected) {
+		t.Errorf("Expected:\n%v\ngot:\n%v", expected, diagnostics)
+	}
+}
+
+func TestCompileDoesNotPanic(t *testing.T) {
+	sources := []string{
+		`print "";`,
+		"print ``;",
+		`var s := "" + "";`,
+		`print "abc`,
+		"print `abc",
+		"var x : int := 0x;",
+		"print 99999999999999999999999;",
+		"for",
+		")))",
+		"/*",
+		"print \x00;",
+		"var s : string := ;",
+		"",
+	}
+
+	for _, src := range sources {
+		program, diagnostics := Compile(src)
+		if program == nil && len(diagnostics) == 0 {
+			t.Errorf("Expected a program or diagnostics for %q", src)
+		}
+
+		if program != nil {
+			program.Check(Options{})
+		}
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	diagnostics := diagnose([]error{
+		token.Errorf(token.Position{Filename: "lib.mpl", Offset: 9, Line: 2, Column: 3}, "syntax error: expected ';'"),
+		fmt.Errorf("wrapped: %w", token.Errorf(token.Position{Line: 4, Column: 1}, "x: y")),
+		errors.New("1:2: not at a position"),
+	})
+
+	expected := []Diagnostic{
+		{Pos: token.Position{Line: 2, Column: 3}, Message: "syntax error: expected ';'"},
+		{Pos: token.Position{Line: 4, Column: 1}, Message: "x: y"},
+		{Message: "1:2: not at a position"},
+	}
+	if !reflect.DeepEqual(diagnostics, expected) {
+		t.Errorf("Expected:\n%v\ngot:\n%v", expected, diagnostics)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	program, _ := Compile("var n : int := len(s) + twice(s);\nx := 1;")
+
+	noResult := Func{Signature: types.NewFunction(nil, nil), Impl: twice.Impl}
+
+	diagnostics := program.Check(Options{
+		Vars:  map[string]interface{}{"s": "abc", "f": 1.5},
+		Funcs: map[string]Func{"twice": twice, "len": twice, "log": noResult},
+	})
+
+	expected := []string{
+		"host variable f has the unsupported Go type float64",
+		"host function len has the name of a builtin function",
+		"host function log must have a signature with a result and an implementation",
+		"2:1: variable x used before declaration",
+		"1:31: argument 1 of twice must be int, not string",
+	}
+
+	var actual []string
+	for _, diagnostic := range diagnostics {
+		actual = append(actual, diagnostic.String())
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected:\n%v\ngot:\n%v", expected, actual)
+	}
+
+	err := program.Run(context.Background(), Options{})
+
+	var errs Diagnostics
+	if !errors.As(err, &errs) || len(errs) != 4 {
+		t.Errorf("Expected the diagnostics to be returned by Run, got %v", err)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	testCases := []struct {
+		name           string
+		sourceCode     string
+		funcs          map[string]Func
+		expectedError  string
+		expectedOutput string
+	}{
+		{
+			name:           "Failing assertion",
+			sourceCode:     "print 1; assert(n > 5); print 2;",
+			expectedError:  "1:10: runtime error: assert failed",
+			expectedOutput: "1",
+		},
+		{
+			name:          "Division by zero",
+			sourceCode:    "n := 1 / (n - 3);",
+			expectedError: "1:6: runtime error: division by zero",
+		},
+		{
+			name:       "Host function error",
+			sourceCode: "print fail(n);",
+			funcs: map[string]Func{"fail": {
+				Signature: twice.Signature,
+				Impl: func(args []interface{}) (interface{}, error) {
+					return nil, errors.New("out of luck")
+				},
+			}},
+			expectedError: "1:7: runtime error: fail: out of luck",
+		},
+		{
+			name:       "Host function result of the wrong type",
+			sourceCode: "print wrong(n);",
+			funcs: map[string]Func{"wrong": {
+				Signature: twice.Signature,
+				Impl: func(args []interface{}) (interface{}, error) {
+					return "3", nil
+				},
+			}},
+			expectedError: "1:7: runtime error: wrong: host function returned string, not int",
+		},
+		{
+			name:       "Host function panic",
+			sourceCode: "print 1; n := crash(n);",
+			funcs: map[string]Func{"crash": {
+				Signature: twice.Signature,
+				Impl: func(args []interface{}) (interface{}, error) {
+					panic("boom")
+				},
+			}},
+			expectedError:  "internal interpreter error: boom",
+			expectedOutput: "1",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			program, diagnostics := Compile(testCase.sourceCode)
+			if len(diagnostics) > 0 {
+				t.Fatalf("Expected no diagnostics, got %v", diagnostics)
+			}
+
+			out := &bytes.Buffer{}
+			err := program.Run(context.Background(), Options{
+				Stdout: out,
+				Vars:   map[string]interface{}{"n": 3},
+				Funcs:  testCase.funcs,
+			})
+
+			if err == nil || err.Error() != testCase.expectedError {
+				t.Errorf("Expected error %s, got %v", testCase.expectedError, err)
+			}
+
+			if out.String() != testCase.expectedOutput {
+				t.Errorf("Expected output %q, got %q", testCase.expectedOutput, out)
+			}
+		})
+	}
+}
+
+func TestRunWithCancelledContext(t *testing.T) {
+	program, _ := Compile("print 1;")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	out := &bytes.Buffer{}
+	if err := program.Run(ctx, Options{Stdout: out}); err != context.Canceled {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+
+	if out.Len() != 0 {
+		t.Errorf("Expected no output, got %q", out)
+	}
+}
+
+func TestRunLimits(t *testing.T) {
+	testCases := []struct {
+		name           string
+		sourceCode     string
+		limits         Limits
+		expectedErr    error
+		expectedError  string
+		expectedOutput string
+	}{
+		{
+			name:          "Step limit",
+			sourceCode:    "var i : int;\nfor i in 0..1000000000 do\n  n := n + 1;\nend for;",
+			limits:        Limits{MaxSteps: 100},
+			expectedErr:   ErrStepLimit,
+			expectedError: "3:3: runtime error: step limit exceeded (100 statements)",
+		},
+		{
+			name:           "Output limit",
+			sourceCode:     "print \"abc\";\nprint \"defgh\";\nprint \"ijk\";",
+			limits:         Limits{MaxOutputBytes: 6},
+			expectedErr:    ErrOutputLimit,
+			expectedError:  "2:1: runtime error: output limit exceeded (6 bytes)",
+			expectedOutput: "abcdef",
+		},
+		{
+			name:          "String length limit",
+			sourceCode:    "var s := \"ab\";\nvar i : int;\nfor i in 0..10 do\n  s := s + s;\nend for;",
+			limits:        Limits{MaxStringBytes: 64},
+			expectedErr:   ErrStringLimit,
+			expectedError: "4:8: runtime error: string length limit exceeded (64 bytes)",
+		},
+		{
+			name:          "String length limit on function results",
+			sourceCode:    "print toString(1234567);",
+			limits:        Limits{MaxStringBytes: 4},
+			expectedErr:   ErrStringLimit,
+			expectedError: "1:7: runtime error: string length limit exceeded (4 bytes)",
+		},
+		{
+			name:           "Programs within the limits run to the end",
+			sourceCode:     "var i : int;\nfor i in 0..3 do\n  print i;\nend for;",
+			limits:         Limits{MaxSteps: 5, MaxOutputBytes: 3, MaxStringBytes: 1},
+			expectedOutput: "012",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			program, diagnostics := Compile(testCase.sourceCode)
+			if len(diagnostics) > 0 {
+				t.Fatalf("Expected no diagnostics, got %v", diagnostics)
+			}
+
+			out := &bytes.Buffer{}
+			err := program.Run(context.Background(), Options{
+				Stdout: out,
+				Vars:   map[string]interface{}{"n": 0},
+				Limits: testCase.limits,
+			})
+
+			if testCase.expectedErr == nil {
+				if err != nil {
+					t.Errorf("Expected no error, got %s", err)
+				}
+			} else if !errors.Is(err, testCase.expectedErr) || err.Error() != testCase.expectedError {
+				t.Errorf("Expected error %s, got %v", testCase.expectedError, err)
+			}
+
+			if out.String() != testCase.expectedOutput {
+				t.Errorf("Expected output %q, got %q", testCase.expectedOutput, out)
+			}
+		})
+	}
+}
+
+func TestRunStopsWhenContextIsDone(t *testing.T) {
+	program, _ := Compile("var i : int;\nfor i in 0..1000000000 do\n  n := n + 1;\nend for;")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := program.Run(ctx, Options{Vars: map[string]interface{}{"n": 0}})
+
+	var runtimeError *RuntimeError
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.As(err, &runtimeError) {
+		t.Fatalf("Expected a runtime error for the deadline, got %v", err)
+	}
+
+	if runtimeError.Pos.Line != 3 {
+		t.Errorf("Expected the error to be in the loop body, got %s", runtimeError.Pos)
+	}
+}
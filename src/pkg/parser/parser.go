@@ -2,13 +2,14 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/mjjs/minipl-go/pkg/ast"
 	"github.com/mjjs/minipl-go/pkg/token"
 )
 
 type Lexer interface {
-	GetNextToken() (token.Token, token.Position)
+	GetNextToken() (token.Token, token.Span)
 }
 
 // Parser is the main struct of the parser package. The Parser should be
@@ -17,6 +18,10 @@ type Parser struct {
 	lexer        Lexer
 	currentToken token.Token
 	currentPos   token.Position
+	currentEnd   token.Position
+	previousPos  token.Position
+	previousEnd  token.Position
+	loopDepth    int
 
 	errors []error
 }
@@ -27,342 +32,367 @@ func New(lexer Lexer) *Parser {
 		panic("Attempting to construct a Parser with a nil Lexer")
 	}
 
-	tok, pos := lexer.GetNextToken()
+	tok, span := lexer.GetNextToken()
 
 	return &Parser{
 		lexer:        lexer,
 		currentToken: tok,
-		currentPos:   pos,
+		currentPos:   span.Start,
+		currentEnd:   span.End,
 	}
 }
 
 // Parse reads tokens from the lexer and verifies that the program is
 // syntactically valid. An abstract syntax tree and an optional error is
-// returned.
+// returned. The tree is well-formed even if there are errors: statements and
+// expressions that could not be parsed are replaced with ast.BadStmt and
+// ast.BadExpr nodes, and the rest of the program is parsed normally.
 func (p *Parser) Parse() (ast.Prog, []error) {
 	statements := p.parseStatements()
 
-	p.eat(token.EOF)
+	p.eat(token.EOF, "")
 
 	return ast.Prog{Statements: statements}, p.errors
 }
 
 // parseStatements goes through all the statements of the lexer and parses
 // them returning a Stmts node indicating the root of the abstract syntax tree.
+// The statements end at the end of the file or, inside a for loop, at the
+// “end” of the loop.
 func (p *Parser) parseStatements() ast.Stmts {
 	statements := []ast.Stmt{}
 
 	statements = append(statements, p.parseStatement())
 
-	for p.currentToken.IsStatement() {
+	for !p.atEndOfStatements() {
 		statements = append(statements, p.parseStatement())
 	}
 
 	return ast.Stmts{Statements: statements}
 }
 
+func (p *Parser) atEndOfStatements() bool {
+	return p.currentToken.Type() == token.EOF ||
+		p.currentToken.Type() == token.END && p.loopDepth > 0
+}
+
 // parseStatement parses a statement using the following grammar rules.
 //
 // <stmt> ::= “var” <var_ident> “:” <type> [ “:=” <expr> ]
+//            | “var” <var_ident> “:=” <expr>
 //            | <var_ident> “:=” <expr>
 //            | “for” <var_ident> “in” <expr> “..” <expr> “do”
 //              <stmts> “end” “for”
 //            | “read” <var_ident>
 //            | “print” <expr>
 //            | “assert” “(” <expr> “)”
+//            | “import” <string>
 func (p *Parser) parseStatement() ast.Stmt {
-	var statement ast.Stmt
-
 	switch p.currentToken.Type() {
 	case token.VAR:
-		statement = p.parseDeclaration()
+		return p.parseDeclaration()
 	case token.IDENT:
-		statement = p.parseAssignment()
+		return p.parseAssignment()
 	case token.FOR:
-		statement = p.parseForStatement()
+		return p.parseForStatement()
 	case token.READ:
-		statement = p.parseReadStatement()
+		return p.parseReadStatement()
 	case token.PRINT:
-		statement = p.parsePrintStatement()
+		return p.parsePrintStatement()
 	case token.ASSERT:
-		statement = p.parseAssertStatement()
+		return p.parseAssertStatement()
+	case token.IMPORT:
+		return p.parseImportStatement()
+	}
 
-	default:
-		err := fmt.Errorf(
-			"%s: syntax error: unexpected %v",
-			p.currentPos, p.currentToken.Type(),
-		)
+	pos := p.currentPos
+	p.errorExpected([]string{"statement"}, "")
 
-		p.errors = append(p.errors, err)
+	if p.atEndOfStatements() {
+		return ast.BadStmt{Pos: pos, EndPos: pos}
 	}
 
-	return statement
+	// An “end for” outside of a loop is skipped as a whole.
+	if p.currentToken.Type() == token.END {
+		p.advance()
+		if p.currentToken.Type() == token.FOR {
+			p.advance()
+		}
+	} else {
+		p.advance()
+	}
+
+	p.synchronize()
+
+	return ast.BadStmt{Pos: pos, EndPos: p.previousEnd}
 }
 
-func (p *Parser) parseDeclaration() ast.DeclStmt {
+func (p *Parser) parseDeclaration() ast.Stmt {
 	pos := p.currentPos
+	p.advance()
 
-	if !p.eat(token.VAR) {
-		p.skipStatement()
-		return ast.DeclStmt{}
+	ident := p.currentToken
+	if !p.eat(token.IDENT, "'var'") {
+		return p.badStmt(pos)
 	}
 
-	ident := p.currentToken
-	if !p.eat(token.IDENT) {
-		p.skipStatement()
-		return ast.DeclStmt{}
+	if p.currentToken.Type() == token.ASSIGN {
+		return p.parseInferredDeclaration(ident, pos)
 	}
-	if !p.eat(token.COLON) {
-		p.skipStatement()
-		return ast.DeclStmt{}
+
+	if p.currentToken.Type() != token.COLON {
+		p.errorExpected(describe(token.COLON, token.ASSIGN), "variable name")
+		return p.badStmt(pos)
 	}
 
+	p.advance()
+
 	variableType := p.currentToken
 	if !p.currentToken.IsType() {
-		err := fmt.Errorf(
-			"Syntax error: expected a type, got %v",
-			p.currentToken.Type(),
-		)
-
-		p.errors = append(p.errors, err)
-		p.skipStatement()
-		return ast.DeclStmt{}
+		p.errorExpected(describe(token.INTEGER, token.STRING, token.BOOLEAN), "':'")
+		return p.badStmt(pos)
 	}
 
-	if !p.eat(p.currentToken.Type()) {
-		p.skipStatement()
-		return ast.DeclStmt{}
-	}
+	p.advance()
 
 	if p.currentToken.Type() != token.ASSIGN {
-		p.eat(token.SEMI)
+		if p.currentToken.Type() == token.SEMI {
+			p.advance()
+		} else {
+			p.errorExpected(describe(token.SEMI, token.ASSIGN), "type")
+			p.synchronize()
+		}
 
 		return ast.DeclStmt{
 			Identifier:   ident,
 			VariableType: variableType,
 			Pos:          pos,
+			EndPos:       p.previousEnd,
 		}
 	}
 
-	if !p.eat(token.ASSIGN) {
-		p.skipStatement()
-		return ast.DeclStmt{}
-	}
+	p.advance()
 
-	expr := p.parseExpression()
-	if expr == nil {
-		p.skipStatement()
-		return ast.DeclStmt{}
-	}
-
-	p.eat(token.SEMI)
+	expr := p.parseExpression("':='")
+	p.endStatement(expr)
 
 	return ast.DeclStmt{
 		Identifier:   ident,
 		VariableType: variableType,
 		Expression:   expr,
 		Pos:          pos,
+		EndPos:       p.previousEnd,
 	}
 }
 
-func (p *Parser) parseAssignment() ast.AssignStmt {
-	pos := p.currentPos
+// parseInferredDeclaration parses the rest of a declaration whose type is
+// inferred from the initializer, starting from the assignment operator.
+func (p *Parser) parseInferredDeclaration(ident token.Token, pos token.Position) ast.Stmt {
+	p.advance()
 
-	ident := p.currentToken
+	expr := p.parseExpression("':='")
+	p.endStatement(expr)
 
-	if !p.eat(token.IDENT) {
-		p.skipStatement()
-		return ast.AssignStmt{}
+	return ast.DeclStmt{
+		Identifier: ident,
+		Expression: expr,
+		Pos:        pos,
+		EndPos:     p.previousEnd,
 	}
+}
 
-	if !p.eat(token.ASSIGN) {
-		p.skipStatement()
-		return ast.AssignStmt{}
+func (p *Parser) parseAssignment() ast.Stmt {
+	pos := p.currentPos
+
+	ident := ast.Ident{
+		Id:     p.currentToken,
+		Pos:    pos,
+		EndPos: p.currentEnd,
 	}
+	p.advance()
 
-	expr := p.parseExpression()
-	if expr == nil {
-		p.skipStatement()
-		return ast.AssignStmt{}
+	if !p.eat(token.ASSIGN, "identifier") {
+		return p.badStmt(pos)
 	}
 
-	p.eat(token.SEMI)
+	expr := p.parseExpression("':='")
+	p.endStatement(expr)
 
 	return ast.AssignStmt{
-		Identifier: ast.Ident{
-			Id:  ident,
-			Pos: pos,
-		},
+		Identifier: ident,
 		Expression: expr,
 		Pos:        pos,
+		EndPos:     p.previousEnd,
 	}
 }
 
-func (p *Parser) parseForStatement() ast.ForStmt {
+// parseForStatement parses a for loop. If the header of the loop is broken,
+// the whole loop including any nested loops is skipped. Errors in the body
+// are recovered from statement by statement.
+func (p *Parser) parseForStatement() ast.Stmt {
 	pos := p.currentPos
+	p.advance()
 
-	if !p.eat(token.FOR) {
-		p.skipForBlock()
-		return ast.ForStmt{}
+	index := ast.Ident{
+		Id:     p.currentToken,
+		Pos:    p.currentPos,
+		EndPos: p.currentEnd,
 	}
 
-	ident := p.currentToken
-	identPos := p.currentPos
-
-	if !p.eat(token.IDENT) {
-		p.skipForBlock()
-		return ast.ForStmt{}
-	}
-	if !p.eat(token.IN) {
-		p.skipForBlock()
-		return ast.ForStmt{}
-	}
-
-	low := p.parseExpression()
-	if low == nil {
-		p.skipForBlock()
-		return ast.ForStmt{}
+	if !p.eat(token.IDENT, "'for'") {
+		return p.badForStmt(pos)
 	}
-
-	if !p.eat(token.RANGE) {
-		p.skipForBlock()
-		return ast.ForStmt{}
+	if !p.eat(token.IN, "loop variable") {
+		return p.badForStmt(pos)
 	}
 
-	high := p.parseExpression()
-	if high == nil {
-		p.skipForBlock()
-		return ast.ForStmt{}
+	low := p.parseExpression("'in'")
+	if isBad(low) || !p.eatAfterExpression(token.RANGE, low) {
+		return p.badForStmt(pos)
 	}
 
-	if !p.eat(token.DO) {
-		p.skipForBlock()
-		return ast.ForStmt{}
+	high := p.parseExpression("'..'")
+	if isBad(high) || !p.eatAfterExpression(token.DO, high) {
+		return p.badForStmt(pos)
 	}
 
+	p.loopDepth++
 	statements := p.parseStatements()
+	p.loopDepth--
 
-	if !p.eat(token.END) {
-		p.skipForBlock()
-		return ast.ForStmt{}
-	}
-
-	if !p.eat(token.FOR) {
-		p.skipForBlock()
-		return ast.ForStmt{}
-	}
-
-	p.eat(token.SEMI)
-
-	return ast.ForStmt{
-		Index: ast.Ident{
-			Id:  ident,
-			Pos: identPos,
-		},
+	statement := ast.ForStmt{
+		Index:      index,
 		Low:        low,
 		High:       high,
 		Statements: statements,
 		Pos:        pos,
 	}
-}
-
-func (p *Parser) parseReadStatement() ast.ReadStmt {
-	pos := p.currentPos
 
-	if !p.eat(token.READ) {
-		p.skipStatement()
-		return ast.ReadStmt{}
+	if !p.eat(token.END, "loop body") || !p.eat(token.FOR, "'end'") {
+		p.synchronize()
+	} else {
+		if !p.eat(token.SEMI, "'end for'") {
+			p.synchronize()
+		}
 	}
 
-	identPos := p.currentPos
+	statement.EndPos = p.previousEnd
+
+	return statement
+}
+
+func (p *Parser) parseReadStatement() ast.Stmt {
+	pos := p.currentPos
+	p.advance()
 
 	statement := ast.ReadStmt{
 		TargetIdentifier: ast.Ident{
-			Id:  p.currentToken,
-			Pos: identPos,
+			Id:     p.currentToken,
+			Pos:    p.currentPos,
+			EndPos: p.currentEnd,
 		},
 		Pos: pos,
 	}
 
-	if !p.eat(token.IDENT) {
-		p.skipStatement()
-		return ast.ReadStmt{}
+	if !p.eat(token.IDENT, "'read'") {
+		return p.badStmt(pos)
 	}
 
-	p.eat(token.SEMI)
+	if !p.eat(token.SEMI, "identifier") {
+		p.synchronize()
+	}
+	statement.EndPos = p.previousEnd
 
 	return statement
 }
 
-func (p *Parser) parsePrintStatement() ast.PrintStmt {
+func (p *Parser) parsePrintStatement() ast.Stmt {
 	pos := p.currentPos
-	if !p.eat(token.PRINT) {
-		p.skipStatement()
-		return ast.PrintStmt{}
-	}
-
-	expr := p.parseExpression()
-	if expr == nil {
-		p.skipStatement()
-		return ast.PrintStmt{}
-	}
+	p.advance()
 
-	p.eat(token.SEMI)
+	expr := p.parseExpression("'print'")
+	p.endStatement(expr)
 
 	return ast.PrintStmt{
 		Expression: expr,
 		Pos:        pos,
+		EndPos:     p.previousEnd,
 	}
 }
 
-func (p *Parser) parseAssertStatement() ast.AssertStmt {
+func (p *Parser) parseAssertStatement() ast.Stmt {
 	pos := p.currentPos
+	p.advance()
 
-	if !p.eat(token.ASSERT) {
-		p.skipStatement()
-		return ast.AssertStmt{}
+	if !p.eat(token.LPAREN, "'assert'") {
+		return p.badStmt(pos)
 	}
 
-	if !p.eat(token.LPAREN) {
-		p.skipStatement()
-		return ast.AssertStmt{}
-	}
-
-	expr := p.parseExpression()
-	if expr == nil {
-		p.skipStatement()
-		return ast.AssertStmt{}
-	}
+	expr := p.parseExpression("'('")
 
 	statement := ast.AssertStmt{
 		Expression: expr,
 		Pos:        pos,
 	}
 
-	if !p.eat(token.RPAREN) {
-		p.skipStatement()
-		return ast.AssertStmt{}
+	if isBad(expr) || !p.eatAfterExpression(token.RPAREN, expr) {
+		p.synchronize()
+	} else {
+		if !p.eat(token.SEMI, "')'") {
+			p.synchronize()
+		}
 	}
 
-	p.eat(token.SEMI)
+	statement.EndPos = p.previousEnd
 
 	return statement
 }
 
+// parseImportStatement parses an import. Imports are only allowed outside of
+// for loops, so that every file is run at most once.
+func (p *Parser) parseImportStatement() ast.Stmt {
+	pos := p.currentPos
+	p.advance()
+
+	if p.loopDepth > 0 {
+		p.errors = append(p.errors, token.Errorf(pos, "syntax error: import inside a for loop"))
+	}
+
+	path := p.currentToken
+	if !p.eat(token.STRING_LITERAL, "'import'") {
+		return p.badStmt(pos)
+	}
+
+	if !p.eat(token.SEMI, "import path") {
+		p.synchronize()
+	}
+
+	return ast.ImportStmt{
+		Path:   path.Lexeme(),
+		Pos:    pos,
+		EndPos: p.previousEnd,
+	}
+}
+
 // parseExpression parses an expression with the following grammar rules.
+// An ast.BadExpr is returned if the expression contains a syntax error. The
+// description of the token before the expression is used in error messages.
+// A unary minus belongs to the operand that follows it, so -a * b is (-a) * b
+// and a - -b subtracts -b, while an expression that starts with ! cannot
+// have a binary operator.
 //
 // <expr> ::= <opnd> <op> <opnd>
 //            | [ <unary_opnd> ] <opnd>
-func (p *Parser) parseExpression() ast.Expr {
+func (p *Parser) parseExpression(after string) ast.Expr {
 	pos := p.currentPos
 
 	if p.currentToken.Type() == token.NOT {
 		unary := p.currentToken
-		p.eat(token.NOT)
+		p.advance()
 
-		operand := p.parseOperand()
-		if operand == nil {
-			return nil
+		operand := p.parseOperand("'!'")
+		if isBad(operand) {
+			return p.badExpr(pos)
 		}
 
 		return ast.UnaryExpr{
@@ -372,9 +402,14 @@ func (p *Parser) parseExpression() ast.Expr {
 		}
 	}
 
-	left := p.parseOperand()
-	if left == nil {
-		return nil
+	if !startsOperand(p.currentToken) {
+		p.errorExpected([]string{"expression"}, after)
+		return p.badExpr(pos)
+	}
+
+	left := p.parseOperand(after)
+	if isBad(left) {
+		return p.badExpr(pos)
 	}
 
 	if !p.currentToken.IsOperator() {
@@ -383,143 +418,330 @@ func (p *Parser) parseExpression() ast.Expr {
 		}
 	}
 
-	operand := p.currentToken
-
-	if !p.currentToken.IsOperator() {
-		err := fmt.Errorf(
-			"Syntax error: expected an operator, got %v",
-			p.currentToken.Type(),
-		)
-		p.errors = append(p.errors, err)
-		return nil
-	}
-
-	p.eat(p.currentToken.Type())
+	operator := p.currentToken
+	p.advance()
 
-	right := p.parseOperand()
-	if right == nil {
-		return nil
+	right := p.parseOperand(token.Describe(operator.Type()))
+	if isBad(right) {
+		return p.badExpr(pos)
 	}
 
 	return ast.BinaryExpr{
 		Left:     left,
-		Operator: operand,
+		Operator: operator,
 		Right:    right,
 	}
 }
 
 // parseOperand parses a valid operand with the following grammar rules.
+// An ast.BadExpr is returned if the operand contains a syntax error.
 //
 // <opnd> ::= <int>
 //            | <string>
+//            | <bool>
+//            | “-” <opnd>
 //            | <var_ident>
+//            | <call>
 //            | “(” <expr> “)”
 //
 // <var_ident> ::= <ident>
-func (p *Parser) parseOperand() ast.Node {
+func (p *Parser) parseOperand(after string) ast.Node {
 	pos := p.currentPos
 
 	switch p.currentToken.Type() {
 	case token.INTEGER_LITERAL:
 		val := p.currentToken.ValueInt()
-
-		if !p.eat(token.INTEGER_LITERAL) {
-			return nil
-		}
+		p.advance()
 
 		return ast.NumberOpnd{
-			Value: val,
-			Pos:   pos,
+			Value:  val,
+			Pos:    pos,
+			EndPos: p.previousEnd,
 		}
 
 	case token.STRING_LITERAL:
-		val := p.currentToken.Value()
+		val := p.currentToken.Lexeme()
+		p.advance()
 
-		if !p.eat(token.STRING_LITERAL) {
-			return nil
+		return ast.StringOpnd{
+			Value:  val,
+			Pos:    pos,
+			EndPos: p.previousEnd,
 		}
 
-		return ast.StringOpnd{
-			Value: val,
-			Pos:   pos,
+	case token.BOOLEAN_LITERAL:
+		val := p.currentToken.ValueBool()
+		p.advance()
+
+		return ast.BoolOpnd{
+			Value:  val,
+			Pos:    pos,
+			EndPos: p.previousEnd,
 		}
 
 	case token.IDENT:
 		t := p.currentToken
-		if !p.eat(token.IDENT) {
-			return nil
+		p.advance()
+
+		if p.currentToken.Type() == token.LPAREN {
+			return p.parseCall(t, pos)
 		}
 
 		return ast.Ident{
-			Id:  t,
-			Pos: pos,
+			Id:     t,
+			Pos:    pos,
+			EndPos: p.previousEnd,
 		}
 
-	case token.LPAREN:
-		if !p.eat(token.LPAREN) {
-			return nil
+	case token.MINUS:
+		unary := p.currentToken
+		p.advance()
+
+		operand := p.parseOperand("'-'")
+		if isBad(operand) {
+			return operand
 		}
 
-		expr := p.parseExpression()
-		if expr == nil {
-			return nil
+		return ast.UnaryExpr{
+			Unary:   unary,
+			Operand: operand,
+			Pos:     pos,
 		}
 
-		if !p.eat(token.RPAREN) {
-			return nil
+	case token.LPAREN:
+		p.advance()
+
+		expr := p.parseExpression("'('")
+		if isBad(expr) {
+			return expr
+		}
+
+		if !p.eatAfterExpression(token.RPAREN, expr) {
+			return p.badExpr(pos)
 		}
 
 		return expr
 
 	default:
-		err := fmt.Errorf(
-			"%s: syntax error: unexpected %v",
-			pos, p.currentToken.Type(),
-		)
+		p.errorExpected([]string{"operand"}, after)
+		return p.badExpr(pos)
+	}
+}
+
+// parseCall parses the arguments of a call of the function name, which
+// starts at pos. The function is not looked up until type checking.
+//
+// <call> ::= <ident> “(” [ <expr> { “,” <expr> } ] “)”
+func (p *Parser) parseCall(name token.Token, pos token.Position) ast.Expr {
+	p.advance()
+
+	args := []ast.Expr{}
+	after := "'('"
+
+	for p.currentToken.Type() != token.RPAREN || len(args) > 0 {
+		arg := p.parseExpression(after)
+		if isBad(arg) {
+			return p.badExpr(pos)
+		}
+
+		args = append(args, arg)
 
-		p.errors = append(p.errors, err)
-		return nil
+		if p.currentToken.Type() == token.RPAREN {
+			break
+		}
+
+		if p.currentToken.Type() != token.COMMA {
+			expected := describe(token.COMMA, token.RPAREN)
+			if _, ok := arg.(ast.NullaryExpr); ok {
+				expected = append(expected, "operator")
+			}
+
+			p.errorExpected(expected, "argument")
+
+			return p.badExpr(pos)
+		}
+
+		p.advance()
+		after = "','"
+	}
+
+	p.advance()
+
+	return ast.CallExpr{
+		Func:   name,
+		Args:   args,
+		Pos:    pos,
+		EndPos: p.previousEnd,
 	}
 }
 
-// eat checks that the given tokenType corresponds to the currently held token
-// and consumes it. If the tokens do not match, eat panics.
-func (p *Parser) eat(tokenType token.TokenTag) bool {
-	pos := p.currentPos
+func startsOperand(t token.Token) bool {
+	switch t.Type() {
+	case token.INTEGER_LITERAL, token.STRING_LITERAL, token.BOOLEAN_LITERAL, token.IDENT, token.MINUS, token.LPAREN:
+		return true
+	}
+
+	return false
+}
+
+func isBad(node ast.Node) bool {
+	_, bad := node.(ast.BadExpr)
+	return bad
+}
 
+// eat consumes the current token if it has the given tag. Otherwise a syntax
+// error is reported, mentioning what comes before the expected token unless
+// after is empty.
+func (p *Parser) eat(tokenType token.TokenTag, after string) bool {
 	if p.currentToken.Type() == tokenType {
-		p.currentToken, p.currentPos = p.lexer.GetNextToken()
+		p.advance()
 		return true
 	}
 
-	err := fmt.Errorf(
-		"%s: syntax error: expected %v got %v",
-		pos, tokenType, p.currentToken.Type(),
-	)
+	p.errorExpected(describe(tokenType), after)
 
-	p.errors = append(p.errors, err)
+	return false
+}
+
+// eatAfterExpression is like eat for the token that follows expr. An operator
+// is also accepted after an expression without one, which is mentioned in the
+// error message.
+func (p *Parser) eatAfterExpression(tokenType token.TokenTag, expr ast.Expr) bool {
+	if p.currentToken.Type() == tokenType {
+		p.advance()
+		return true
+	}
+
+	expected := describe(tokenType)
+	if _, ok := expr.(ast.NullaryExpr); ok {
+		expected = append(expected, "operator")
+	}
+
+	p.errorExpected(expected, "expression")
 
 	return false
 }
 
-func (p *Parser) skipTo(tokens ...token.TokenTag) {
+// advance moves on to the next token.
+func (p *Parser) advance() {
+	p.previousPos = p.currentPos
+	p.previousEnd = p.currentEnd
+
+	var span token.Span
+	p.currentToken, span = p.lexer.GetNextToken()
+	p.currentPos = span.Start
+	p.currentEnd = span.End
+}
+
+func describe(tags ...token.TokenTag) []string {
+	descriptions := make([]string, len(tags))
+	for i, tag := range tags {
+		descriptions[i] = token.Describe(tag)
+	}
+
+	return descriptions
+}
+
+// errorExpected reports that the current token is not one of the expected
+// ones, for example "expected ';' or operator after expression, found
+// identifier 'x'". If a “;” is expected and the current token is on a later
+// line or starts a new statement, the message suggests that it is missing.
+// An ERROR token from the lexer is never expected, so its message is reported
+// as it is instead, for example "1:7: hexadecimal literal 0x without digits".
+func (p *Parser) errorExpected(expected []string, after string) {
+	if p.currentToken.Type() == token.ERROR {
+		p.errors = append(p.errors, &token.Error{Pos: p.currentPos, Msg: p.currentToken.Lexeme()})
+		return
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString("syntax error: expected ")
+
+	for i, e := range expected {
+		switch {
+		case i == 0:
+		case i == len(expected)-1:
+			sb.WriteString(" or ")
+		default:
+			sb.WriteString(", ")
+		}
+		sb.WriteString(e)
+	}
+
+	if after != "" {
+		fmt.Fprintf(&sb, " after %s", after)
+	}
+
+	fmt.Fprintf(&sb, ", found %s", p.currentToken.Describe())
+
+	if expected[0] == token.Describe(token.SEMI) && p.currentToken.Type() != token.EOF {
+		if p.currentPos.Line > p.previousPos.Line {
+			fmt.Fprintf(&sb, " (missing ';' at the end of line %d?)", p.previousPos.Line)
+		} else if p.currentToken.IsStatement() {
+			sb.WriteString(" (missing ';'?)")
+		}
+	}
+
+	p.errors = append(p.errors, &token.Error{Pos: p.currentPos, Msg: sb.String()})
+}
+
+// synchronize skips tokens after a syntax error until the parser reaches a
+// point where parsing can continue: past the next “;”, or at a keyword that
+// starts a statement, at the “end” of a for loop or at the end of the file.
+func (p *Parser) synchronize() {
 	for {
-		if p.currentToken.Type() == token.EOF {
+		switch p.currentToken.Type() {
+		case token.SEMI:
+			p.advance()
+			return
+		case token.VAR, token.FOR, token.READ, token.PRINT, token.ASSERT, token.IMPORT, token.END, token.EOF:
 			return
 		}
 
-		for _, t := range tokens {
-			if p.currentToken.Type() == t {
-				p.currentToken, p.currentPos = p.lexer.GetNextToken()
-				return
-			}
-		}
+		p.advance()
+	}
+}
+
+// endStatement ends a statement that ends with expr. If the expression could
+// not be parsed or is not followed by a “;”, the rest of the statement is
+// skipped, so that the error is not reported again for the following tokens.
+func (p *Parser) endStatement(expr ast.Expr) {
+	if isBad(expr) {
+		p.synchronize()
+		return
+	}
+
+	if !p.eatAfterExpression(token.SEMI, expr) {
+		p.synchronize()
+	}
+}
+
+// badStmt skips the rest of a statement with a syntax error and returns a
+// placeholder for it.
+func (p *Parser) badStmt(pos token.Position) ast.Stmt {
+	p.synchronize()
+	return ast.BadStmt{Pos: pos, EndPos: p.previousEnd}
+}
 
-		p.currentToken, p.currentPos = p.lexer.GetNextToken()
+// badExpr returns a placeholder for an expression with a syntax error that
+// starts at pos. The expression ends with the last token consumed, or is
+// empty if the error is at its first token.
+func (p *Parser) badExpr(pos token.Position) ast.Expr {
+	end := p.previousEnd
+	if end.Offset < pos.Offset {
+		end = pos
 	}
+
+	return ast.BadExpr{Pos: pos, EndPos: end}
 }
 
-func (p *Parser) skipStatement() { p.skipTo(token.SEMI) }
+// badForStmt skips the rest of a for loop with a syntax error in its header
+// and returns a placeholder for it.
+func (p *Parser) badForStmt(pos token.Position) ast.Stmt {
+	p.skipForBlock()
+	return ast.BadStmt{Pos: pos, EndPos: p.previousEnd}
+}
 
 func (p *Parser) skipForBlock() {
 	for {
@@ -528,19 +750,19 @@ func (p *Parser) skipForBlock() {
 		}
 
 		if p.currentToken.Type() == token.FOR {
-			p.currentToken, p.currentPos = p.lexer.GetNextToken()
+			p.advance()
 			p.skipForBlock()
 			continue
 		}
 
 		if p.currentToken.Type() == token.END {
-			p.currentToken, p.currentPos = p.lexer.GetNextToken()
+			p.advance()
 
 			if p.currentToken.Type() == token.FOR {
-				p.currentToken, p.currentPos = p.lexer.GetNextToken()
+				p.advance()
 
 				if p.currentToken.Type() == token.SEMI {
-					p.currentToken, p.currentPos = p.lexer.GetNextToken()
+					p.advance()
 					return
 				}
 			}
@@ -548,6 +770,6 @@ func (p *Parser) skipForBlock() {
 			continue
 		}
 
-		p.currentToken, p.currentPos = p.lexer.GetNextToken()
+		p.advance()
 	}
 }
@@ -2,10 +2,12 @@ package parser
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
 	"testing"
 
 	"github.com/mjjs/minipl-go/pkg/ast"
+	"github.com/mjjs/minipl-go/pkg/lexer"
 	"github.com/mjjs/minipl-go/pkg/token"
 )
 
@@ -41,6 +43,29 @@ var parseTestCases = []struct {
 			},
 		},
 	},
+	{
+		name: "Declaration with inferred type",
+		lexerOutput: []positionedToken{
+			{token.New(token.VAR, ""), token.Position{Line: 1, Column: 1}},
+			{token.New(token.IDENT, "x"), token.Position{Line: 1, Column: 5}},
+			{token.New(token.ASSIGN, ""), token.Position{Line: 1, Column: 7}},
+			{token.New(token.STRING_LITERAL, "foo"), token.Position{Line: 1, Column: 10}},
+			{token.New(token.SEMI, ""), token.Position{Line: 1, Column: 15}},
+		},
+		expectedAST: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.DeclStmt{
+						Identifier: token.New(token.IDENT, "x"),
+						Expression: ast.NullaryExpr{
+							Operand: ast.StringOpnd{Value: "foo", Pos: token.Position{Line: 1, Column: 10}},
+						},
+						Pos: token.Position{Line: 1, Column: 1},
+					},
+				},
+			},
+		},
+	},
 	{
 		name: "Declaration without assignment",
 		lexerOutput: []positionedToken{
@@ -227,6 +252,24 @@ var parseTestCases = []struct {
 			},
 		},
 	},
+	{
+		name: "Import statement",
+		lexerOutput: []positionedToken{
+			{token.New(token.IMPORT, ""), token.Position{Line: 1, Column: 1}},
+			{token.New(token.STRING_LITERAL, "lib/util.mpl"), token.Position{Line: 1, Column: 8}},
+			{token.New(token.SEMI, ""), token.Position{Line: 1, Column: 22}},
+		},
+		expectedAST: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.ImportStmt{
+						Path: "lib/util.mpl",
+						Pos:  token.Position{Line: 1, Column: 1},
+					},
+				},
+			},
+		},
+	},
 	{
 		name: "Parenthesised expressions",
 		lexerOutput: []positionedToken{
@@ -441,9 +484,154 @@ var parseTestCases = []struct {
 			},
 		},
 	},
+	{
+		name: "Boolean literals",
+		lexerOutput: []positionedToken{
+			// assert(true & (!false));
+			{token.New(token.ASSERT, ""), token.Position{Line: 1, Column: 1}},
+			{token.New(token.LPAREN, ""), token.Position{Line: 1, Column: 7}},
+			{token.New(token.BOOLEAN_LITERAL, "true"), token.Position{Line: 1, Column: 8}},
+			{token.New(token.AND, ""), token.Position{Line: 1, Column: 13}},
+			{token.New(token.LPAREN, ""), token.Position{Line: 1, Column: 15}},
+			{token.New(token.NOT, ""), token.Position{Line: 1, Column: 16}},
+			{token.New(token.BOOLEAN_LITERAL, "false"), token.Position{Line: 1, Column: 17}},
+			{token.New(token.RPAREN, ""), token.Position{Line: 1, Column: 22}},
+			{token.New(token.RPAREN, ""), token.Position{Line: 1, Column: 23}},
+			{token.New(token.SEMI, ""), token.Position{Line: 1, Column: 24}},
+		},
+		expectedAST: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.AssertStmt{
+						Expression: ast.BinaryExpr{
+							Left:     ast.BoolOpnd{Value: true, Pos: token.Position{Line: 1, Column: 8}},
+							Operator: token.New(token.AND, ""),
+							Right: ast.UnaryExpr{
+								Unary:   token.New(token.NOT, ""),
+								Operand: ast.BoolOpnd{Value: false, Pos: token.Position{Line: 1, Column: 17}},
+								Pos:     token.Position{Line: 1, Column: 16},
+							},
+						},
+						Pos: token.Position{Line: 1, Column: 1},
+					},
+				},
+			},
+		},
+	},
+	{
+		name: "Unary minus",
+		lexerOutput: []positionedToken{
+			// x := -5 * - -y;
+			{token.New(token.IDENT, "x"), token.Position{Line: 1, Column: 1}},
+			{token.New(token.ASSIGN, ""), token.Position{Line: 1, Column: 3}},
+			{token.New(token.MINUS, ""), token.Position{Line: 1, Column: 6}},
+			{token.New(token.INTEGER_LITERAL, "5"), token.Position{Line: 1, Column: 7}},
+			{token.New(token.MULTIPLY, ""), token.Position{Line: 1, Column: 9}},
+			{token.New(token.MINUS, ""), token.Position{Line: 1, Column: 11}},
+			{token.New(token.MINUS, ""), token.Position{Line: 1, Column: 13}},
+			{token.New(token.IDENT, "y"), token.Position{Line: 1, Column: 14}},
+			{token.New(token.SEMI, ""), token.Position{Line: 1, Column: 15}},
+		},
+		expectedAST: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.AssignStmt{
+						Identifier: ast.Ident{
+							Id:  token.New(token.IDENT, "x"),
+							Pos: token.Position{Line: 1, Column: 1},
+						},
+						Expression: ast.BinaryExpr{
+							Left: ast.UnaryExpr{
+								Unary:   token.New(token.MINUS, ""),
+								Operand: ast.NumberOpnd{Value: 5, Pos: token.Position{Line: 1, Column: 7}},
+								Pos:     token.Position{Line: 1, Column: 6},
+							},
+							Operator: token.New(token.MULTIPLY, ""),
+							Right: ast.UnaryExpr{
+								Unary: token.New(token.MINUS, ""),
+								Operand: ast.UnaryExpr{
+									Unary: token.New(token.MINUS, ""),
+									Operand: ast.Ident{
+										Id:  token.New(token.IDENT, "y"),
+										Pos: token.Position{Line: 1, Column: 14},
+									},
+									Pos: token.Position{Line: 1, Column: 13},
+								},
+								Pos: token.Position{Line: 1, Column: 11},
+							},
+						},
+						Pos: token.Position{Line: 1, Column: 1},
+					},
+				},
+			},
+		},
+	},
+	{
+		name: "Function calls",
+		lexerOutput: []positionedToken{
+			// print substring(s, 1, len(s) - 1);
+			{token.New(token.PRINT, ""), token.Position{Line: 1, Column: 1}},
+			{token.New(token.IDENT, "substring"), token.Position{Line: 1, Column: 7}},
+			{token.New(token.LPAREN, ""), token.Position{Line: 1, Column: 16}},
+			{token.New(token.IDENT, "s"), token.Position{Line: 1, Column: 17}},
+			{token.New(token.COMMA, ""), token.Position{Line: 1, Column: 18}},
+			{token.New(token.INTEGER_LITERAL, "1"), token.Position{Line: 1, Column: 20}},
+			{token.New(token.COMMA, ""), token.Position{Line: 1, Column: 21}},
+			{token.New(token.IDENT, "len"), token.Position{Line: 1, Column: 23}},
+			{token.New(token.LPAREN, ""), token.Position{Line: 1, Column: 26}},
+			{token.New(token.IDENT, "s"), token.Position{Line: 1, Column: 27}},
+			{token.New(token.RPAREN, ""), token.Position{Line: 1, Column: 28}},
+			{token.New(token.MINUS, ""), token.Position{Line: 1, Column: 30}},
+			{token.New(token.INTEGER_LITERAL, "1"), token.Position{Line: 1, Column: 32}},
+			{token.New(token.RPAREN, ""), token.Position{Line: 1, Column: 33}},
+			{token.New(token.SEMI, ""), token.Position{Line: 1, Column: 34}},
+		},
+		expectedAST: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.PrintStmt{
+						Expression: ast.NullaryExpr{
+							Operand: ast.CallExpr{
+								Func: token.New(token.IDENT, "substring"),
+								Args: []ast.Expr{
+									ast.NullaryExpr{
+										Operand: ast.Ident{
+											Id:  token.New(token.IDENT, "s"),
+											Pos: token.Position{Line: 1, Column: 17},
+										},
+									},
+									ast.NullaryExpr{
+										Operand: ast.NumberOpnd{Value: 1, Pos: token.Position{Line: 1, Column: 20}},
+									},
+									ast.BinaryExpr{
+										Left: ast.CallExpr{
+											Func: token.New(token.IDENT, "len"),
+											Args: []ast.Expr{
+												ast.NullaryExpr{
+													Operand: ast.Ident{
+														Id:  token.New(token.IDENT, "s"),
+														Pos: token.Position{Line: 1, Column: 27},
+													},
+												},
+											},
+											Pos: token.Position{Line: 1, Column: 23},
+										},
+										Operator: token.New(token.MINUS, ""),
+										Right:    ast.NumberOpnd{Value: 1, Pos: token.Position{Line: 1, Column: 32}},
+									},
+								},
+								Pos: token.Position{Line: 1, Column: 7},
+							},
+						},
+						Pos: token.Position{Line: 1, Column: 1},
+					},
+				},
+			},
+		},
+	},
 	// ERRORS
 	{
-		name: "Error if no EOF is returned by lexer when expected",
+		name: "Error on tokens after the last statement",
 		lexerOutput: []positionedToken{
 			{token.New(token.PRINT, ""), token.Position{Line: 1, Column: 1}},
 			{token.New(token.INTEGER_LITERAL, "22"), token.Position{Line: 1, Column: 2}},
@@ -451,7 +639,7 @@ var parseTestCases = []struct {
 			{token.New(token.SEMI, ""), token.Position{Line: 1, Column: 4}},
 		},
 		expectedErrors: []error{
-			errors.New("1:4: syntax error: expected EOF got SEMI"),
+			errors.New("1:4: syntax error: expected statement, found ';'"),
 		},
 	},
 	{
@@ -471,7 +659,7 @@ var parseTestCases = []struct {
 			{token.New(token.SEMI, ""), token.Position{Line: 1, Column: 12}},
 		},
 		expectedErrors: []error{
-			errors.New("1:4: syntax error: unexpected RANGE"),
+			errors.New("1:4: syntax error: expected expression after 'in', found '..'"),
 		},
 	},
 	{
@@ -495,9 +683,9 @@ var parseTestCases = []struct {
 			{token.New(token.SEMI, ""), token.Position{Line: 1, Column: 12}},
 		},
 		expectedErrors: []error{
-			errors.New("1:9: syntax error: unexpected SEMI"),
-			errors.New("1:23: syntax error: unexpected SEMI"),
-			errors.New("1:45: syntax error: unexpected SEMI"),
+			errors.New("1:9: syntax error: expected expression after 'print', found ';'"),
+			errors.New("1:23: syntax error: expected expression after 'print', found ';'"),
+			errors.New("1:45: syntax error: expected expression after 'print', found ';'"),
 		},
 	},
 	{
@@ -531,18 +719,207 @@ var parseTestCases = []struct {
 			{token.New(token.SEMI, ""), token.Position{Line: 1, Column: 12}},
 		},
 		expectedErrors: []error{
-			errors.New("1:4: syntax error: unexpected RANGE"),
+			errors.New("1:4: syntax error: expected expression after 'in', found '..'"),
+		},
+	},
+	{
+		name: "Empty string literal",
+		lexerOutput: []positionedToken{
+			{token.New(token.PRINT, ""), token.Position{Line: 1, Column: 1}},
+			{token.New(token.STRING_LITERAL, ""), token.Position{Line: 1, Column: 7}},
+			{token.New(token.SEMI, ""), token.Position{Line: 1, Column: 9}},
+		},
+		expectedAST: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.PrintStmt{
+						Expression: ast.NullaryExpr{
+							Operand: ast.StringOpnd{Value: "", Pos: token.Position{Line: 1, Column: 7}},
+						},
+						Pos: token.Position{Line: 1, Column: 1},
+					},
+				},
+			},
 		},
 	},
 	{
 		name:        "Error when no statements are present",
 		lexerOutput: []positionedToken{},
 		expectedErrors: []error{
-			errors.New("99:99: syntax error: unexpected EOF"),
+			errors.New("99:99: syntax error: expected statement, found end of file"),
 		},
 	},
 }
 
+var recoveryTestCases = []struct {
+	name           string
+	lexerOutput    []positionedToken
+	expectedAST    ast.Prog
+	expectedErrors []error
+}{
+	{
+		name: "Bad expression is kept in its statement",
+		lexerOutput: []positionedToken{
+			{token.New(token.PRINT, ""), token.Position{Line: 1, Column: 1}},
+			{token.New(token.INTEGER_LITERAL, "1"), token.Position{Line: 1, Column: 7}},
+			{token.New(token.PLUS, ""), token.Position{Line: 1, Column: 9}},
+			{token.New(token.SEMI, ""), token.Position{Line: 1, Column: 10}},
+			{token.New(token.READ, ""), token.Position{Line: 2, Column: 1}},
+			{token.New(token.IDENT, "x"), token.Position{Line: 2, Column: 6}},
+			{token.New(token.SEMI, ""), token.Position{Line: 2, Column: 7}},
+		},
+		expectedAST: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.PrintStmt{
+						Expression: ast.BadExpr{Pos: token.Position{Line: 1, Column: 7}},
+						Pos:        token.Position{Line: 1, Column: 1},
+					},
+					ast.ReadStmt{
+						TargetIdentifier: ast.Ident{
+							Id:  token.New(token.IDENT, "x"),
+							Pos: token.Position{Line: 2, Column: 6},
+						},
+						Pos: token.Position{Line: 2, Column: 1},
+					},
+				},
+			},
+		},
+		expectedErrors: []error{
+			errors.New("1:10: syntax error: expected operand after '+', found ';'"),
+		},
+	},
+	{
+		name: "Parsing continues at the next statement keyword",
+		lexerOutput: []positionedToken{
+			{token.New(token.RPAREN, ""), token.Position{Line: 1, Column: 1}},
+			{token.New(token.IDENT, "x"), token.Position{Line: 1, Column: 2}},
+			{token.New(token.PRINT, ""), token.Position{Line: 1, Column: 4}},
+			{token.New(token.STRING_LITERAL, "a"), token.Position{Line: 1, Column: 10}},
+			{token.New(token.SEMI, ""), token.Position{Line: 1, Column: 13}},
+		},
+		expectedAST: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.BadStmt{Pos: token.Position{Line: 1, Column: 1}},
+					ast.PrintStmt{
+						Expression: ast.NullaryExpr{
+							Operand: ast.StringOpnd{Value: "a", Pos: token.Position{Line: 1, Column: 10}},
+						},
+						Pos: token.Position{Line: 1, Column: 4},
+					},
+				},
+			},
+		},
+		expectedErrors: []error{
+			errors.New("1:1: syntax error: expected statement, found ')'"),
+		},
+	},
+	{
+		name: "Broken statement in a for loop body",
+		lexerOutput: []positionedToken{
+			{token.New(token.FOR, ""), token.Position{Line: 1, Column: 1}},
+			{token.New(token.IDENT, "i"), token.Position{Line: 1, Column: 5}},
+			{token.New(token.IN, ""), token.Position{Line: 1, Column: 7}},
+			{token.New(token.INTEGER_LITERAL, "0"), token.Position{Line: 1, Column: 10}},
+			{token.New(token.RANGE, ""), token.Position{Line: 1, Column: 11}},
+			{token.New(token.INTEGER_LITERAL, "2"), token.Position{Line: 1, Column: 13}},
+			{token.New(token.DO, ""), token.Position{Line: 1, Column: 15}},
+			{token.New(token.VAR, ""), token.Position{Line: 2, Column: 1}},
+			{token.New(token.SEMI, ""), token.Position{Line: 2, Column: 4}},
+			{token.New(token.END, ""), token.Position{Line: 3, Column: 1}},
+			{token.New(token.FOR, ""), token.Position{Line: 3, Column: 5}},
+			{token.New(token.SEMI, ""), token.Position{Line: 3, Column: 8}},
+		},
+		expectedAST: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.ForStmt{
+						Index: ast.Ident{
+							Id:  token.New(token.IDENT, "i"),
+							Pos: token.Position{Line: 1, Column: 5},
+						},
+						Low: ast.NullaryExpr{
+							Operand: ast.NumberOpnd{Value: 0, Pos: token.Position{Line: 1, Column: 10}},
+						},
+						High: ast.NullaryExpr{
+							Operand: ast.NumberOpnd{Value: 2, Pos: token.Position{Line: 1, Column: 13}},
+						},
+						Statements: ast.Stmts{
+							Statements: []ast.Stmt{
+								ast.BadStmt{Pos: token.Position{Line: 2, Column: 1}},
+							},
+						},
+						Pos: token.Position{Line: 1, Column: 1},
+					},
+				},
+			},
+		},
+		expectedErrors: []error{
+			errors.New("2:4: syntax error: expected identifier after 'var', found ';'"),
+		},
+	},
+	{
+		name: "End for outside of a loop",
+		lexerOutput: []positionedToken{
+			{token.New(token.END, ""), token.Position{Line: 1, Column: 1}},
+			{token.New(token.FOR, ""), token.Position{Line: 1, Column: 5}},
+			{token.New(token.SEMI, ""), token.Position{Line: 1, Column: 8}},
+			{token.New(token.PRINT, ""), token.Position{Line: 2, Column: 1}},
+			{token.New(token.INTEGER_LITERAL, "1"), token.Position{Line: 2, Column: 7}},
+			{token.New(token.SEMI, ""), token.Position{Line: 2, Column: 8}},
+		},
+		expectedAST: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.BadStmt{Pos: token.Position{Line: 1, Column: 1}},
+					ast.PrintStmt{
+						Expression: ast.NullaryExpr{
+							Operand: ast.NumberOpnd{Value: 1, Pos: token.Position{Line: 2, Column: 7}},
+						},
+						Pos: token.Position{Line: 2, Column: 1},
+					},
+				},
+			},
+		},
+		expectedErrors: []error{
+			errors.New("1:1: syntax error: expected statement, found 'end'"),
+		},
+	},
+	{
+		name:        "Missing statements",
+		lexerOutput: []positionedToken{},
+		expectedAST: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.BadStmt{Pos: token.Position{Line: 99, Column: 99}, EndPos: token.Position{Line: 99, Column: 99}},
+				},
+			},
+		},
+		expectedErrors: []error{
+			errors.New("99:99: syntax error: expected statement, found end of file"),
+		},
+	},
+}
+
+// TestParseRecovery checks the trees produced for programs with syntax
+// errors as well as the errors.
+func TestParseRecovery(t *testing.T) {
+	for _, testCase := range recoveryTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual, errors := New(newMockLexer(testCase.lexerOutput)).Parse()
+
+			if fmt.Sprint(errors) != fmt.Sprint(testCase.expectedErrors) {
+				t.Errorf("Expected errors:\n%s\ngot:\n%s", testCase.expectedErrors, errors)
+			}
+
+			if !reflect.DeepEqual(actual, testCase.expectedAST) {
+				t.Errorf("Expected:\n%+#v\ngot:\n%+#v", testCase.expectedAST, actual)
+			}
+		})
+	}
+}
+
 func TestParsePanicsWithNilLexer(t *testing.T) {
 	defer func() {
 		r := recover()
@@ -616,14 +993,187 @@ func newMockLexer(output []positionedToken) *mockLexer {
 	return &mockLexer{tokens: tokens, positions: positions}
 }
 
-func (m *mockLexer) GetNextToken() (token.Token, token.Position) {
+func (m *mockLexer) GetNextToken() (token.Token, token.Span) {
 	if m.pos > len(m.tokens)-1 {
-		return token.New(token.EOF, ""), token.Position{Line: 99, Column: 99}
+		return token.New(token.EOF, ""), token.Span{Start: token.Position{Line: 99, Column: 99}}
 	}
 
-	token := m.tokens[m.pos]
+	tok := m.tokens[m.pos]
 	pos := m.positions[m.pos]
 	m.pos++
 
-	return token, pos
+	return tok, token.Span{Start: pos}
+}
+
+var syntaxErrorTestCases = []struct {
+	name          string
+	sourceCode    string
+	expectedError string
+}{
+	{
+		name:          "Missing semicolon after an operand",
+		sourceCode:    "print 1 x := 2;",
+		expectedError: "1:9: syntax error: expected ';' or operator after expression, found identifier 'x' (missing ';'?)",
+	},
+	{
+		name:          "Missing semicolon at the end of a line",
+		sourceCode:    "var x : int := 1 + 2\nprint x;",
+		expectedError: "2:1: syntax error: expected ';' after expression, found 'print' (missing ';' at the end of line 1?)",
+	},
+	{
+		name:          "Missing semicolon after a type",
+		sourceCode:    "var x : int\nprint x;",
+		expectedError: "2:1: syntax error: expected ';' or ':=' after type, found 'print' (missing ';' at the end of line 1?)",
+	},
+	{
+		name:          "Extra operand after an expression",
+		sourceCode:    "var x : bool := 1 < 2 3;\nprint x;",
+		expectedError: "1:23: syntax error: expected ';' after expression, found integer literal 3",
+	},
+	{
+		name:          "Extra token after a type",
+		sourceCode:    "var x : int 5;\nprint x;",
+		expectedError: "1:13: syntax error: expected ';' or ':=' after type, found integer literal 5",
+	},
+	{
+		name:          "Extra token after a read statement",
+		sourceCode:    "read x 5;\nprint x;",
+		expectedError: "1:8: syntax error: expected ';' after identifier, found integer literal 5",
+	},
+	{
+		name:          "Missing type",
+		sourceCode:    "var x : 5;",
+		expectedError: "1:9: syntax error: expected 'int', 'string' or 'bool' after ':', found integer literal 5",
+	},
+	{
+		name:          "Missing range in for loop",
+		sourceCode:    "for i in 0 do print i; end for;",
+		expectedError: "1:12: syntax error: expected '..' or operator after expression, found 'do'",
+	},
+	{
+		name:          "Unclosed parenthesis",
+		sourceCode:    `print (1 + 2;`,
+		expectedError: "1:13: syntax error: expected ')' after expression, found ';'",
+	},
+	{
+		name:          "Missing end of loop",
+		sourceCode:    "for i in 0..1 do print i;",
+		expectedError: "1:26: syntax error: expected 'end' after loop body, found end of file",
+	},
+	{
+		name:          "String literal as a statement",
+		sourceCode:    `"foo";`,
+		expectedError: `1:1: syntax error: expected statement, found string literal "foo"`,
+	},
+	{
+		name:          "Boolean literal as a variable name",
+		sourceCode:    "var true : bool;",
+		expectedError: "1:5: syntax error: expected identifier after 'var', found boolean literal true",
+	},
+	{
+		name:          "Malformed integer literal",
+		sourceCode:    "var x : int := 0b12;",
+		expectedError: "1:16: invalid digit '2' in binary literal 0b12",
+	},
+	{
+		name:          "Integer literal without digits",
+		sourceCode:    "print 1;\nprint 0x;",
+		expectedError: "2:7: hexadecimal literal 0x without digits",
+	},
+	{
+		name:          "Unrecognized character",
+		sourceCode:    "print 1 # 2;",
+		expectedError: "1:9: unrecognized character '#'",
+	},
+	{
+		name:          "Unary minus without an operand",
+		sourceCode:    "print 1 - -;",
+		expectedError: "1:12: syntax error: expected operand after '-', found ';'",
+	},
+	{
+		name:          "Missing comma between arguments",
+		sourceCode:    "print substring(s 1, 2);",
+		expectedError: "1:19: syntax error: expected ',', ')' or operator after argument, found integer literal 1",
+	},
+	{
+		name:          "Missing argument after a comma",
+		sourceCode:    "print toUpper(s,);",
+		expectedError: "1:17: syntax error: expected expression after ',', found ')'",
+	},
+	{
+		name:          "Import without a path",
+		sourceCode:    `import lib;`,
+		expectedError: "1:8: syntax error: expected string literal after 'import', found identifier 'lib'",
+	},
+	{
+		name:          "Import inside a for loop",
+		sourceCode:    `for i in 0..1 do import "lib.mpl"; end for;`,
+		expectedError: "1:18: syntax error: import inside a for loop",
+	},
+}
+
+func TestSyntaxErrorMessages(t *testing.T) {
+	for _, testCase := range syntaxErrorTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, errors := New(lexer.New(testCase.sourceCode)).Parse()
+
+			if len(errors) != 1 || errors[0].Error() != testCase.expectedError {
+				t.Errorf("Expected:\n%s\ngot:\n%s", testCase.expectedError, errors)
+			}
+		})
+	}
+}
+
+func TestNodeSpans(t *testing.T) {
+	root, errors := New(lexer.New("var s := \"ä\";\nprint (s + \"b\") = s;")).Parse()
+	if len(errors) != 0 {
+		t.Fatalf("Expected no errors, got %v", errors)
+	}
+
+	decl := root.Statements.Statements[0]
+	printStmt := root.Statements.Statements[1].(ast.PrintStmt)
+
+	testCases := []struct {
+		name          string
+		node          ast.Node
+		expectedStart token.Position
+		expectedEnd   token.Position
+	}{
+		{
+			name:          "Statement spans include the semicolon",
+			node:          decl,
+			expectedStart: token.Position{Offset: 0, Line: 1, Column: 1},
+			expectedEnd:   token.Position{Offset: 14, Line: 1, Column: 14},
+		},
+		{
+			name:          "Binary expression spans from its left to its right operand",
+			node:          printStmt.Expression,
+			expectedStart: token.Position{Offset: 22, Line: 2, Column: 8},
+			expectedEnd:   token.Position{Offset: 34, Line: 2, Column: 20},
+		},
+		{
+			name:          "Operand spans cover the whole literal",
+			node:          printStmt.Expression.(ast.BinaryExpr).Left.(ast.BinaryExpr).Right,
+			expectedStart: token.Position{Offset: 26, Line: 2, Column: 12},
+			expectedEnd:   token.Position{Offset: 29, Line: 2, Column: 15},
+		},
+		{
+			name:          "Program spans all the statements",
+			node:          root,
+			expectedStart: token.Position{Offset: 0, Line: 1, Column: 1},
+			expectedEnd:   token.Position{Offset: 35, Line: 2, Column: 21},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if start := testCase.node.Position(); start != testCase.expectedStart {
+				t.Errorf("Expected start %#v, got %#v", testCase.expectedStart, start)
+			}
+
+			if end := testCase.node.End(); end != testCase.expectedEnd {
+				t.Errorf("Expected end %#v, got %#v", testCase.expectedEnd, end)
+			}
+		})
+	}
 }
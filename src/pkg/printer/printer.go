@@ -0,0 +1,233 @@
+// Package printer turns abstract syntax trees back into MiniPL source code.
+package printer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mjjs/minipl-go/pkg/ast"
+	"github.com/mjjs/minipl-go/pkg/token"
+)
+
+// printer is an ast.Visitor that writes the source code of the visited nodes
+// into a strings.Builder. Statements are written without a trailing newline,
+// the statements of a Stmts node are written one per line.
+type printer struct {
+	sb     strings.Builder
+	indent int
+}
+
+// Fprint writes the source code of node into w.
+func Fprint(w io.Writer, node ast.Node) error {
+	_, err := io.WriteString(w, Sprint(node))
+	return err
+}
+
+// Sprint returns the source code of node.
+func Sprint(node ast.Node) string {
+	p := &printer{}
+	node.Accept(p)
+	return p.sb.String()
+}
+
+func (p *printer) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&p.sb, format, args...)
+}
+
+// operand prints an operand of an operator, wrapping it in parentheses if it
+// is an expression with an operator of its own. A unary minus is part of its
+// operand in the grammar, so it needs no parentheses.
+func (p *printer) operand(node ast.Node) {
+	if unary, ok := node.(ast.UnaryExpr); ok && unary.Unary.Type() == token.MINUS {
+		node.Accept(p)
+		return
+	}
+
+	switch node.(type) {
+	case ast.BinaryExpr, ast.UnaryExpr:
+		p.printf("(")
+		node.Accept(p)
+		p.printf(")")
+	default:
+		node.Accept(p)
+	}
+}
+
+func (p *printer) VisitProg(node ast.Prog) {
+	node.Statements.Accept(p)
+}
+
+func (p *printer) VisitStmts(node ast.Stmts) {
+	for _, stmt := range node.Statements {
+		p.printf("%s", strings.Repeat("\t", p.indent))
+		stmt.Accept(p)
+		p.printf("\n")
+	}
+}
+
+func (p *printer) VisitAssignStmt(node ast.AssignStmt) {
+	node.Identifier.Accept(p)
+	p.printf(" := ")
+	node.Expression.Accept(p)
+	p.printf(";")
+}
+
+func (p *printer) VisitDeclStmt(node ast.DeclStmt) {
+	p.printf("var %s", node.Identifier.Value())
+
+	if !node.Inferred() {
+		p.printf(" : %s", token.Spelling(node.VariableType.Type()))
+	}
+
+	if node.Expression != nil {
+		p.printf(" := ")
+		node.Expression.Accept(p)
+	}
+
+	p.printf(";")
+}
+
+func (p *printer) VisitForStmt(node ast.ForStmt) {
+	p.printf("for ")
+	node.Index.Accept(p)
+	p.printf(" in ")
+	node.Low.Accept(p)
+	p.printf("..")
+	node.High.Accept(p)
+	p.printf(" do\n")
+
+	p.indent++
+	node.Statements.Accept(p)
+	p.indent--
+
+	p.printf("%send for;", strings.Repeat("\t", p.indent))
+}
+
+func (p *printer) VisitReadStmt(node ast.ReadStmt) {
+	p.printf("read ")
+	node.TargetIdentifier.Accept(p)
+	p.printf(";")
+}
+
+func (p *printer) VisitPrintStmt(node ast.PrintStmt) {
+	p.printf("print ")
+	node.Expression.Accept(p)
+	p.printf(";")
+}
+
+func (p *printer) VisitAssertStmt(node ast.AssertStmt) {
+	p.printf("assert(")
+	node.Expression.Accept(p)
+	p.printf(");")
+}
+
+// VisitImportStmt prints the import itself, the statements of the imported
+// file stay in their own file.
+func (p *printer) VisitImportStmt(node ast.ImportStmt) {
+	p.printf("import %s;", Quote(node.Path))
+}
+
+func (p *printer) VisitBinaryExpr(node ast.BinaryExpr) {
+	p.operand(node.Left)
+	p.printf(" %s ", token.Spelling(node.Operator.Type()))
+	p.operand(node.Right)
+}
+
+// VisitUnaryExpr prints a unary expression. An operand with a unary operator
+// of its own is wrapped in parentheses, so that -(-x) is not printed as --x.
+func (p *printer) VisitUnaryExpr(node ast.UnaryExpr) {
+	p.printf("%s", token.Spelling(node.Unary.Type()))
+
+	operand := node.Operand
+	if nullary, ok := operand.(ast.NullaryExpr); ok {
+		operand = nullary.Operand
+	}
+
+	if _, ok := operand.(ast.UnaryExpr); ok {
+		p.printf("(")
+		operand.Accept(p)
+		p.printf(")")
+		return
+	}
+
+	p.operand(node.Operand)
+}
+
+func (p *printer) VisitBadStmt(node ast.BadStmt) {
+	p.printf("BadStmt;")
+}
+
+func (p *printer) VisitBadExpr(node ast.BadExpr) {
+	p.printf("BadExpr")
+}
+
+func (p *printer) VisitNullaryExpr(node ast.NullaryExpr) {
+	node.Operand.Accept(p)
+}
+
+func (p *printer) VisitCallExpr(node ast.CallExpr) {
+	p.printf("%s(", node.Func.Value())
+	for i, arg := range node.Args {
+		if i > 0 {
+			p.printf(", ")
+		}
+		arg.Accept(p)
+	}
+	p.printf(")")
+}
+
+func (p *printer) VisitNumberOpnd(node ast.NumberOpnd) {
+	p.printf("%d", node.Value)
+}
+
+func (p *printer) VisitStringOpnd(node ast.StringOpnd) {
+	p.printf("%s", Quote(node.Value))
+}
+
+func (p *printer) VisitBoolOpnd(node ast.BoolOpnd) {
+	p.printf("%t", node.Value)
+}
+
+func (p *printer) VisitIdent(node ast.Ident) {
+	p.printf("%s", node.Id.Value())
+}
+
+// Quote returns s as a MiniPL string literal, escaping the characters that
+// cannot appear in a string literal as such and the characters that are not
+// printable.
+func Quote(s string) string {
+	var sb strings.Builder
+
+	sb.WriteByte('"')
+	for _, c := range s {
+		switch c {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case 0:
+			sb.WriteString(`\0`)
+		default:
+			switch {
+			case unicode.IsPrint(c):
+				sb.WriteRune(c)
+			case c < utf8.RuneSelf:
+				fmt.Fprintf(&sb, `\x%02X`, c)
+			default:
+				fmt.Fprintf(&sb, `\u{%X}`, c)
+			}
+		}
+	}
+	sb.WriteByte('"')
+
+	return sb.String()
+}
@@ -0,0 +1,103 @@
+package printer
+
+import (
+	"testing"
+
+	"github.com/mjjs/minipl-go/pkg/lexer"
+	"github.com/mjjs/minipl-go/pkg/parser"
+)
+
+var printTestCases = []struct {
+	name           string
+	sourceCode     string
+	expectedOutput string
+}{
+	{
+		name:           "Declarations",
+		sourceCode:     `var x : int; var s:string:="a";var b : bool := (1=1);`,
+		expectedOutput: "var x : int;\nvar s : string := \"a\";\nvar b : bool := 1 = 1;\n",
+	},
+	{
+		name:           "Boolean literals",
+		sourceCode:     `var b:=true; assert(!false);`,
+		expectedOutput: "var b := true;\nassert(!false);\n",
+	},
+	{
+		name:           "Declarations with inferred types",
+		sourceCode:     `var x := 1 + 2; var s:="a";`,
+		expectedOutput: "var x := 1 + 2;\nvar s := \"a\";\n",
+	},
+	{
+		name:           "Expressions keep their grouping",
+		sourceCode:     `x := (1 + 2) * (3 - x); print !(x < 5); assert(!b);`,
+		expectedOutput: "x := (1 + 2) * (3 - x);\nprint !(x < 5);\nassert(!b);\n",
+	},
+	{
+		name:           "Unary minus needs no parentheses",
+		sourceCode:     `x := -1 * (-(-x) - (!b)); print - -1;`,
+		expectedOutput: "x := -1 * (-(-x) - (!b));\nprint -(-1);\n",
+	},
+	{
+		name:           "Function calls",
+		sourceCode:     `print substring( s,1 , len(s)-1 ) + toString(-x);`,
+		expectedOutput: "print substring(s, 1, len(s) - 1) + toString(-x);\n",
+	},
+	{
+		name:           "Imports",
+		sourceCode:     `import "lib/util.mpl";print 1;`,
+		expectedOutput: "import \"lib/util.mpl\";\nprint 1;\n",
+	},
+	{
+		name:           "Strings are escaped",
+		sourceCode:     `print "a\n\t\"b\"\\";`,
+		expectedOutput: "print \"a\\n\\t\\\"b\\\"\\\\\";\n",
+	},
+	{
+		name:           "Unprintable characters are escaped",
+		sourceCode:     `print "\0\x01\u{7F}\u{200B}é";`,
+		expectedOutput: `print "\0\x01\x7F\u{200B}é";` + "\n",
+	},
+	{
+		name:           "Raw strings are quoted",
+		sourceCode:     "print `a\\b\n\"c\"`;",
+		expectedOutput: `print "a\\b\n\"c\"";` + "\n",
+	},
+	{
+		name: "Nested for loops are indented",
+		sourceCode: `read n; for i in 0..n do for j in i..n do print j; end for;
+		print i; end for;`,
+		expectedOutput: `read n;
+for i in 0..n do
+	for j in i..n do
+		print j;
+	end for;
+	print i;
+end for;
+`,
+	},
+}
+
+func TestSprint(t *testing.T) {
+	for _, testCase := range printTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			root, errors := parser.New(lexer.New(testCase.sourceCode)).Parse()
+			if len(errors) > 0 {
+				t.Fatalf("Expected no errors, got %s", errors)
+			}
+
+			actual := Sprint(root)
+			if actual != testCase.expectedOutput {
+				t.Errorf("Expected:\n%s\ngot:\n%s", testCase.expectedOutput, actual)
+			}
+
+			reparsed, errors := parser.New(lexer.New(actual)).Parse()
+			if len(errors) > 0 {
+				t.Fatalf("Expected printed program to parse, got %s", errors)
+			}
+
+			if again := Sprint(reparsed); again != actual {
+				t.Errorf("Expected printing to be stable, got:\n%s", again)
+			}
+		})
+	}
+}
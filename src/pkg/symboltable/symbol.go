@@ -1,26 +1,19 @@
 package symboltable
 
-type SymbolType int
-
-const (
-	INTEGER SymbolType = iota
-	STRING
-	BOOLEAN
-)
-
-func (s SymbolType) String() string {
-	switch s {
-	case INTEGER:
-		return "int"
-	case STRING:
-		return "string"
-	default:
-		return "bool"
-	}
-}
+import "github.com/mjjs/minipl-go/pkg/types"
 
 type Symbol struct {
-	symbolType SymbolType
+	name       string
+	symbolType types.Type
+	inferred   bool
 }
 
-func (s Symbol) Type() SymbolType { return s.symbolType }
+func (s Symbol) Name() string { return s.name }
+
+// Type returns the type of the symbol. The type of a symbol inserted with
+// InsertInferred is nil until it has been inferred.
+func (s Symbol) Type() types.Type { return s.symbolType }
+
+// Inferred reports whether the type of the symbol was inferred from the
+// initializer of its declaration instead of being written out.
+func (s Symbol) Inferred() bool { return s.inferred }
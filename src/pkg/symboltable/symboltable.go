@@ -1,7 +1,14 @@
 package symboltable
 
+import (
+	"fmt"
+
+	"github.com/mjjs/minipl-go/pkg/types"
+)
+
 type SymbolTable struct {
 	symbols map[string]Symbol
+	names   []string
 }
 
 func NewSymbolTable() *SymbolTable {
@@ -11,12 +18,48 @@ func NewSymbolTable() *SymbolTable {
 	return st
 }
 
-func (s *SymbolTable) Insert(name string, symbolType SymbolType) *SymbolTable {
-	s.symbols[name] = Symbol{symbolType: symbolType}
+func (s *SymbolTable) Insert(name string, symbolType types.Type) *SymbolTable {
+	return s.insert(Symbol{name: name, symbolType: symbolType})
+}
+
+// InsertInferred inserts a symbol whose type is unknown until it is given
+// with Infer.
+func (s *SymbolTable) InsertInferred(name string) *SymbolTable {
+	return s.insert(Symbol{name: name, inferred: true})
+}
+
+func (s *SymbolTable) insert(symbol Symbol) *SymbolTable {
+	if _, exists := s.symbols[symbol.name]; !exists {
+		s.names = append(s.names, symbol.name)
+	}
+
+	s.symbols[symbol.name] = symbol
 	return s
 }
 
+// Infer records the inferred type of a symbol inserted with InsertInferred.
+// It panics if the symbol does not exist or its type was written out.
+func (s *SymbolTable) Infer(name string, symbolType types.Type) {
+	symbol, ok := s.symbols[name]
+	if !ok || !symbol.inferred {
+		panic(fmt.Sprintf("cannot infer the type of symbol %s", name))
+	}
+
+	symbol.symbolType = symbolType
+	s.symbols[name] = symbol
+}
+
 func (s *SymbolTable) Get(name string) (Symbol, bool) {
 	x, ok := s.symbols[name]
 	return x, ok
 }
+
+// Symbols returns all the symbols in the order they were declared.
+func (s *SymbolTable) Symbols() []Symbol {
+	symbols := make([]Symbol, len(s.names))
+	for i, name := range s.names {
+		symbols[i] = s.symbols[name]
+	}
+
+	return symbols
+}
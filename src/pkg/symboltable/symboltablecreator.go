@@ -1,13 +1,18 @@
 package symboltable
 
 import (
-	"fmt"
-
 	"github.com/mjjs/minipl-go/pkg/ast"
 	"github.com/mjjs/minipl-go/pkg/token"
+	"github.com/mjjs/minipl-go/pkg/types"
 )
 
+// SymbolTableCreator creates the symbol table of a program and checks that
+// every variable is declared exactly once before it is used. Only the nodes
+// that declare or use variables are handled, the rest of the tree is walked
+// by the embedded ast.BaseVisitor.
 type SymbolTableCreator struct {
+	ast.BaseVisitor
+
 	symbols       *SymbolTable
 	lockedSymbols map[string]struct{}
 
@@ -15,41 +20,45 @@ type SymbolTableCreator struct {
 }
 
 func (stc *SymbolTableCreator) Create(root ast.Node) (*SymbolTable, []error) {
-	stc.symbols = NewSymbolTable()
+	return stc.CreateIn(root, NewSymbolTable())
+}
+
+// CreateIn is like Create, but the symbols of the program are inserted into
+// symbols. The variables already in symbols are visible to the whole program
+// and cannot be declared again.
+func (stc *SymbolTableCreator) CreateIn(root ast.Node, symbols *SymbolTable) (*SymbolTable, []error) {
+	stc.symbols = symbols
 	stc.lockedSymbols = make(map[string]struct{})
+	stc.Self = stc
 
 	root.Accept(stc)
 
 	return stc.symbols, stc.errors
 }
 
-func (stc *SymbolTableCreator) VisitProg(node ast.Prog) {
-	node.Statements.Accept(stc)
-}
-
-func (stc *SymbolTableCreator) VisitStmts(node ast.Stmts) {
-	for _, stmt := range node.Statements {
-		stmt.Accept(stc)
+// VisitDeclStmt inserts the declared variable into the symbol table. The
+// initializer is visited first so that it cannot refer to the variable being
+// declared. The type of a variable declared without one is left for the
+// type checker to infer.
+func (stc *SymbolTableCreator) VisitDeclStmt(node ast.DeclStmt) {
+	if node.Expression != nil {
+		node.Expression.Accept(stc)
 	}
-}
 
-func (stc *SymbolTableCreator) VisitDeclStmt(node ast.DeclStmt) {
 	name := node.Identifier.Value()
 	_, exists := stc.symbols.Get(name)
 	if exists {
-		err := fmt.Errorf("%s: redeclaration of variable %s", node.Position(), name)
+		err := token.Errorf(node.Position(), "redeclaration of variable %s", name)
 		stc.errors = append(stc.errors, err)
 		return
 	}
 
-	switch node.VariableType.Type() {
-	case token.INTEGER:
-		stc.symbols.Insert(name, INTEGER)
-	case token.STRING:
-		stc.symbols.Insert(name, STRING)
-	case token.BOOLEAN:
-		stc.symbols.Insert(name, BOOLEAN)
+	if node.Inferred() {
+		stc.symbols.InsertInferred(name)
+		return
 	}
+
+	stc.symbols.Insert(name, types.FromToken(node.VariableType.Type()))
 }
 
 func (stc *SymbolTableCreator) VisitAssignStmt(node ast.AssignStmt) {
@@ -58,9 +67,9 @@ func (stc *SymbolTableCreator) VisitAssignStmt(node ast.AssignStmt) {
 	_, locked := stc.lockedSymbols[node.Identifier.Id.Value()]
 
 	if locked {
-		err := fmt.Errorf(
-			"%s: cannot modify loop index %s during loop",
+		err := token.Errorf(
 			node.Position(),
+			"cannot modify loop index %s during loop",
 			node.Identifier.Id.Value(),
 		)
 
@@ -80,44 +89,11 @@ func (stc *SymbolTableCreator) VisitForStmt(node ast.ForStmt) {
 	delete(stc.lockedSymbols, node.Index.Id.Value())
 }
 
-func (stc *SymbolTableCreator) VisitReadStmt(node ast.ReadStmt) {
-	node.TargetIdentifier.Accept(stc)
-}
-
-func (stc *SymbolTableCreator) VisitPrintStmt(node ast.PrintStmt) {
-	node.Expression.Accept(stc)
-}
-
-func (stc *SymbolTableCreator) VisitAssertStmt(node ast.AssertStmt) {
-	node.Expression.Accept(stc)
-}
-
-func (stc *SymbolTableCreator) VisitBinaryExpr(node ast.BinaryExpr) {
-	node.Left.Accept(stc)
-	node.Right.Accept(stc)
-}
-
-func (stc *SymbolTableCreator) VisitUnaryExpr(node ast.UnaryExpr) {
-	node.Operand.Accept(stc)
-}
-
-func (stc *SymbolTableCreator) VisitNullaryExpr(node ast.NullaryExpr) {
-	node.Operand.Accept(stc)
-}
-
-func (stc *SymbolTableCreator) VisitNumberOpnd(node ast.NumberOpnd) {
-	// Nothing to do
-}
-
-func (stc *SymbolTableCreator) VisitStringOpnd(node ast.StringOpnd) {
-	// Nothing to do
-}
-
 func (stc *SymbolTableCreator) VisitIdent(node ast.Ident) {
 	name := node.Id.Value()
 	_, exists := stc.symbols.Get(name)
 	if !exists {
-		err := fmt.Errorf("%s: variable %s used before declaration", node.Position(), name)
+		err := token.Errorf(node.Position(), "variable %s used before declaration", name)
 		stc.errors = append(stc.errors, err)
 	}
 }
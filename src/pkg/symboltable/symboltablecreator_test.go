@@ -7,6 +7,7 @@ import (
 
 	"github.com/mjjs/minipl-go/pkg/ast"
 	"github.com/mjjs/minipl-go/pkg/token"
+	"github.com/mjjs/minipl-go/pkg/types"
 )
 
 var symbolCreatorTestCases = []struct {
@@ -52,11 +53,7 @@ var symbolCreatorTestCases = []struct {
 				},
 			},
 		},
-		expectedOutput: &SymbolTable{
-			symbols: map[string]Symbol{
-				"foo": {STRING},
-			},
-		},
+		expectedOutput: NewSymbolTable().Insert("foo", types.String),
 	},
 	// DECLARATION
 	{
@@ -173,11 +170,42 @@ var symbolCreatorTestCases = []struct {
 				},
 			},
 		},
-		expectedOutput: &SymbolTable{
-			symbols: map[string]Symbol{
-				"i": {INTEGER},
+		expectedOutput: NewSymbolTable().Insert("i", types.Int),
+	},
+	// TYPE INFERENCE
+	{
+		name: "Declaration with inferred type",
+		input: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.DeclStmt{
+						Identifier: token.New(token.IDENT, "x"),
+						Expression: ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 1}},
+					},
+				},
+			},
+		},
+		expectedOutput: NewSymbolTable().InsertInferred("x"),
+	},
+	{
+		name: "Initializer refers to the declared variable",
+		input: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.DeclStmt{
+						Identifier: token.New(token.IDENT, "x"),
+						Expression: ast.NullaryExpr{Operand: ast.Ident{
+							Id:  token.New(token.IDENT, "x"),
+							Pos: token.Position{Line: 1, Column: 10},
+						}},
+						Pos: token.Position{Line: 1, Column: 1},
+					},
+				},
 			},
 		},
+		expectedErrors: []error{
+			errors.New("1:10: variable x used before declaration"),
+		},
 	},
 }
 
@@ -0,0 +1,69 @@
+package token
+
+// File is a source file of a program.
+type File struct {
+	name   string
+	source string
+}
+
+// Name returns the name of the file, which is also the Filename of the
+// positions in it.
+func (f *File) Name() string { return f.name }
+
+// Source returns the contents of the file.
+func (f *File) Source() string { return f.source }
+
+// FileSet holds the source files of a program, so that a position can be
+// mapped back to the file it is in.
+type FileSet struct {
+	files  []*File
+	byName map[string]*File
+}
+
+// NewFileSet returns an empty FileSet.
+func NewFileSet() *FileSet {
+	return &FileSet{byName: make(map[string]*File)}
+}
+
+// AddFile adds a file with the given name and contents to the set and
+// returns it. A file that has already been added with the same name is
+// replaced.
+func (s *FileSet) AddFile(name string, source string) *File {
+	file := &File{name: name, source: source}
+
+	if _, ok := s.byName[name]; !ok {
+		s.files = append(s.files, file)
+	} else {
+		for i, f := range s.files {
+			if f.name == name {
+				s.files[i] = file
+			}
+		}
+	}
+
+	s.byName[name] = file
+
+	return file
+}
+
+// File returns the file that pos is in, or nil if it is not in the set.
+func (s *FileSet) File(pos Position) *File {
+	return s.byName[pos.Filename]
+}
+
+// Files returns the files of the set in the order they were added.
+func (s *FileSet) Files() []*File {
+	return s.files
+}
+
+// UTF16Column is like the package level UTF16Column for a position in one of
+// the files of the set. The column counted in characters is returned if the
+// file is not in the set.
+func (s *FileSet) UTF16Column(pos Position) int {
+	file := s.File(pos)
+	if file == nil {
+		return pos.Column
+	}
+
+	return UTF16Column(file.source, pos)
+}
@@ -0,0 +1,42 @@
+package token
+
+import "testing"
+
+func TestFileSet(t *testing.T) {
+	fset := NewFileSet()
+	main := fset.AddFile("main.mpl", "import \"lib.mpl\";")
+	lib := fset.AddFile("lib.mpl", "print \"🦊\" + x;")
+
+	if file := fset.File(Position{Filename: "lib.mpl", Line: 1, Column: 1}); file != lib {
+		t.Errorf("Expected %v, got %v", lib, file)
+	}
+
+	if file := fset.File(Position{Filename: "missing.mpl"}); file != nil {
+		t.Errorf("Expected nil, got %v", file)
+	}
+
+	if files := fset.Files(); len(files) != 2 || files[0] != main || files[1] != lib {
+		t.Errorf("Expected the files in the order they were added, got %v", files)
+	}
+
+	pos := Position{Filename: "lib.mpl", Offset: 11, Line: 1, Column: 9}
+	if column := fset.UTF16Column(pos); column != 10 {
+		t.Errorf("Expected 10, got %d", column)
+	}
+}
+
+func TestPositionString(t *testing.T) {
+	testCases := []struct {
+		pos      Position
+		expected string
+	}{
+		{Position{Line: 2, Column: 7}, "2:7"},
+		{Position{Filename: "lib/util.mpl", Offset: 12, Line: 2, Column: 7}, "lib/util.mpl:2:7"},
+	}
+
+	for _, testCase := range testCases {
+		if actual := testCase.pos.String(); actual != testCase.expected {
+			t.Errorf("Expected %s, got %s", testCase.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseInt returns the value of an integer literal. Hexadecimal literals start
+// with 0x, binary literals with 0b and octal literals with 0o, and the rest are
+// decimal, even with leading zeros. An underscore can separate two digits, for
+// example 1_000_000 or 0xFF_FF. The error describes what is wrong with a
+// malformed literal, for example "invalid digit '9' in octal literal 0o9".
+func ParseInt(lexeme string) (int, error) {
+	base, name, digits := 10, "integer literal", lexeme
+
+	if len(lexeme) >= 2 && lexeme[0] == '0' {
+		switch lexeme[1] {
+		case 'x', 'X':
+			base, name = 16, "hexadecimal literal"
+		case 'b', 'B':
+			base, name = 2, "binary literal"
+		case 'o', 'O':
+			base, name = 8, "octal literal"
+		}
+
+		if base != 10 {
+			digits = lexeme[2:]
+		}
+	}
+
+	if digits == "" {
+		return 0, fmt.Errorf("%s %s without digits", name, lexeme)
+	}
+
+	var sb strings.Builder
+
+	previousDigit := false
+	for _, c := range digits {
+		if c == '_' {
+			if !previousDigit {
+				return 0, fmt.Errorf("misplaced '_' in %s %s", name, lexeme)
+			}
+
+			previousDigit = false
+			continue
+		}
+
+		if digitValue(c) >= base {
+			return 0, fmt.Errorf("invalid digit %q in %s %s", c, name, lexeme)
+		}
+
+		sb.WriteRune(c)
+		previousDigit = true
+	}
+
+	if !previousDigit {
+		return 0, fmt.Errorf("misplaced '_' in %s %s", name, lexeme)
+	}
+
+	value, err := strconv.ParseInt(sb.String(), base, strconv.IntSize)
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, fmt.Errorf("%s %s out of range", name, lexeme)
+	}
+
+	return int(value), err
+}
+
+// digitValue returns the value of an ASCII digit or letter, and a value that
+// is too large for any base for other characters.
+func digitValue(c rune) int {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'a' <= c && c <= 'z':
+		return int(c - 'a' + 10)
+	case 'A' <= c && c <= 'Z':
+		return int(c - 'A' + 10)
+	}
+
+	return 36
+}
@@ -0,0 +1,58 @@
+package token
+
+import "testing"
+
+func TestParseInt(t *testing.T) {
+	testCases := []struct {
+		lexeme   string
+		expected int
+	}{
+		{"0", 0},
+		{"42", 42},
+		{"0042", 42},
+		{"017", 17},
+		{"1_000_000", 1000000},
+		{"0xff", 255},
+		{"0XFF_FF", 65535},
+		{"0b1010", 10},
+		{"0B1_0", 2},
+		{"0o17", 15},
+		{"0O7_7", 63},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := ParseInt(testCase.lexeme)
+		if err != nil {
+			t.Errorf("Expected no error for %s, got %v", testCase.lexeme, err)
+		} else if actual != testCase.expected {
+			t.Errorf("Expected %s to be %d, got %d", testCase.lexeme, testCase.expected, actual)
+		}
+	}
+}
+
+func TestParseIntErrors(t *testing.T) {
+	testCases := []struct {
+		lexeme        string
+		expectedError string
+	}{
+		{"0x", "hexadecimal literal 0x without digits"},
+		{"0b", "binary literal 0b without digits"},
+		{"0x1G", "invalid digit 'G' in hexadecimal literal 0x1G"},
+		{"0b102", "invalid digit '2' in binary literal 0b102"},
+		{"0o78", "invalid digit '8' in octal literal 0o78"},
+		{"12ab", "invalid digit 'a' in integer literal 12ab"},
+		{"1٣", "invalid digit '٣' in integer literal 1٣"},
+		{"1__0", "misplaced '_' in integer literal 1__0"},
+		{"10_", "misplaced '_' in integer literal 10_"},
+		{"0x_1", "misplaced '_' in hexadecimal literal 0x_1"},
+		{"99999999999999999999", "integer literal 99999999999999999999 out of range"},
+		{"0x1_0000_0000_0000_0000", "hexadecimal literal 0x1_0000_0000_0000_0000 out of range"},
+	}
+
+	for _, testCase := range testCases {
+		_, err := ParseInt(testCase.lexeme)
+		if err == nil || err.Error() != testCase.expectedError {
+			t.Errorf("Expected error %s, got %v", testCase.expectedError, err)
+		}
+	}
+}
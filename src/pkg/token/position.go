@@ -2,11 +2,68 @@ package token
 
 import "fmt"
 
+// Position is a location in the source code. Filename is empty for source
+// code that does not come from a file. Offset is a byte offset starting at 0,
+// Line and Column start at 1 and Column counts characters, not bytes.
 type Position struct {
-	Line   int
-	Column int
+	Filename string
+	Offset   int
+	Line     int
+	Column   int
 }
 
 func (p Position) String() string {
+	if p.Filename != "" {
+		return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+	}
+
 	return fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
+
+// Error is an error found at a position in the source code, such as a syntax
+// or a type error.
+type Error struct {
+	Pos Position
+	Msg string
+}
+
+// Errorf returns an Error at pos with a message formatted like fmt.Sprintf.
+func Errorf(pos Position, format string, args ...interface{}) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// Span is the part of the source code from Start up to, but not including,
+// End.
+type Span struct {
+	Start Position
+	End   Position
+}
+
+func (s Span) String() string {
+	return fmt.Sprintf("%s-%s", s.Start, s.End)
+}
+
+// UTF16Column returns the column of pos counted in UTF-16 code units instead
+// of characters, as used by the Language Server Protocol. The column starts at
+// 1 like Column does; src is the source code the position refers to.
+func UTF16Column(src string, pos Position) int {
+	lineStart := pos.Offset
+	for lineStart > 0 && src[lineStart-1] != '\n' {
+		lineStart--
+	}
+
+	column := 1
+	for _, r := range src[lineStart:pos.Offset] {
+		if r >= 0x10000 {
+			column += 2
+		} else {
+			column++
+		}
+	}
+
+	return column
+}
@@ -0,0 +1,43 @@
+package token
+
+import "testing"
+
+func TestUTF16Column(t *testing.T) {
+	src := "print \"ä\";\nprint \"🦊\" + x;"
+
+	testCases := []struct {
+		name     string
+		pos      Position
+		expected int
+	}{
+		{"Start of file", Position{Offset: 0, Line: 1, Column: 1}, 1},
+		{"After a two byte character", Position{Offset: 10, Line: 1, Column: 10}, 10},
+		{"Start of a later line", Position{Offset: 12, Line: 2, Column: 1}, 1},
+		{"After a character outside the BMP", Position{Offset: 23, Line: 2, Column: 9}, 10},
+		{"End of file", Position{Offset: 29, Line: 2, Column: 15}, 16},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if actual := UTF16Column(src, testCase.pos); actual != testCase.expected {
+				t.Errorf("Expected %d, got %d", testCase.expected, actual)
+			}
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	testCases := []struct {
+		err      *Error
+		expected string
+	}{
+		{Errorf(Position{Line: 2, Column: 7}, "variable %s used before declaration", "x"), "2:7: variable x used before declaration"},
+		{Errorf(Position{Filename: "lib.mpl", Line: 1, Column: 1}, "syntax error"), "lib.mpl:1:1: syntax error"},
+	}
+
+	for _, testCase := range testCases {
+		if actual := testCase.err.Error(); actual != testCase.expected {
+			t.Errorf("Expected %s, got %s", testCase.expected, actual)
+		}
+	}
+}
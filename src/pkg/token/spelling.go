@@ -0,0 +1,95 @@
+package token
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// spellings holds how the tags that always have the same lexeme are written
+// in the source code.
+var spellings = map[TokenTag]string{
+	INTEGER: "int",
+	STRING:  "string",
+	BOOLEAN: "bool",
+
+	PLUS:        "+",
+	MINUS:       "-",
+	MULTIPLY:    "*",
+	INTEGER_DIV: "/",
+	LT:          "<",
+	LE:          "<=",
+	GT:          ">",
+	GE:          ">=",
+	EQ:          "=",
+	NE:          "<>",
+	AND:         "&",
+	OR:          "|",
+	NOT:         "!",
+	ASSIGN:      ":=",
+
+	LPAREN: "(",
+	RPAREN: ")",
+	SEMI:   ";",
+	COLON:  ":",
+	COMMA:  ",",
+
+	FOR:   "for",
+	IN:    "in",
+	DO:    "do",
+	END:   "end",
+	RANGE: "..",
+
+	ASSERT: "assert",
+	VAR:    "var",
+	READ:   "read",
+	PRINT:  "print",
+	IMPORT: "import",
+}
+
+// classNames describes the tags whose lexemes vary.
+var classNames = map[TokenTag]string{
+	INTEGER_LITERAL: "integer literal",
+	STRING_LITERAL:  "string literal",
+	BOOLEAN_LITERAL: "boolean literal",
+	IDENT:           "identifier",
+	EOF:             "end of file",
+	ERROR:           "invalid token",
+}
+
+// Spelling returns how a tag is written in the source code, for example ";"
+// for SEMI and "end" for END. The spelling of the tags whose lexemes vary,
+// such as IDENT, is empty.
+func Spelling(tag TokenTag) string { return spellings[tag] }
+
+// Describe describes a tag for error messages: the quoted spelling if it has
+// one, for example "';'", and a name such as "identifier" otherwise.
+func Describe(tag TokenTag) string {
+	if spelling, ok := spellings[tag]; ok {
+		return "'" + spelling + "'"
+	}
+
+	if name, ok := classNames[tag]; ok {
+		return name
+	}
+
+	return string(tag)
+}
+
+// Describe describes the token for error messages. Unlike the package level
+// Describe, the lexeme is included, for example "identifier 'x'".
+func (t Token) Describe() string {
+	switch t.tag {
+	case IDENT:
+		return fmt.Sprintf("identifier '%s'", t.lexeme)
+	case INTEGER_LITERAL:
+		return "integer literal " + t.lexeme
+	case STRING_LITERAL:
+		return "string literal " + strconv.Quote(t.lexeme)
+	case BOOLEAN_LITERAL:
+		return "boolean literal " + t.lexeme
+	case ERROR:
+		return t.lexeme
+	}
+
+	return Describe(t.tag)
+}
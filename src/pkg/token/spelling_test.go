@@ -0,0 +1,42 @@
+package token
+
+import "testing"
+
+func TestDescribe(t *testing.T) {
+	testCases := []struct {
+		token    Token
+		expected string
+	}{
+		{New(SEMI, ""), "';'"},
+		{New(ASSIGN, ""), "':='"},
+		{New(END, ""), "'end'"},
+		{New(INTEGER, ""), "'int'"},
+		{New(EOF, ""), "end of file"},
+		{New(IDENT, "x"), "identifier 'x'"},
+		{New(INTEGER_LITERAL, "42"), "integer literal 42"},
+		{New(STRING_LITERAL, "a\n"), `string literal "a\n"`},
+		{New(BOOLEAN_LITERAL, "true"), "boolean literal true"},
+		{New(ERROR, "unrecognized character '$'"), "unrecognized character '$'"},
+	}
+
+	for _, testCase := range testCases {
+		if actual := testCase.token.Describe(); actual != testCase.expected {
+			t.Errorf("Expected %s, got %s", testCase.expected, actual)
+		}
+	}
+}
+
+func TestEveryTagIsDescribed(t *testing.T) {
+	tags := []TokenTag{
+		INTEGER, STRING, BOOLEAN, INTEGER_LITERAL, STRING_LITERAL, BOOLEAN_LITERAL,
+		IDENT, PLUS, MINUS, MULTIPLY, INTEGER_DIV, LT, LE, GT, GE, EQ, NE, AND, OR, NOT, ASSIGN,
+		LPAREN, RPAREN, SEMI, COLON, COMMA, FOR, IN, DO, END, RANGE, ASSERT, VAR, READ,
+		PRINT, EOF, ERROR,
+	}
+
+	for _, tag := range tags {
+		if Describe(tag) == string(tag) {
+			t.Errorf("Tag %s has no description", tag)
+		}
+	}
+}
@@ -26,8 +26,13 @@ const (
 	MULTIPLY    = "MULTIPLY"    // *
 	INTEGER_DIV = "INTEGER_DIV" // /
 	LT          = "LT"          // <
+	LE          = "LE"          // <=
+	GT          = "GT"          // >
+	GE          = "GE"          // >=
 	EQ          = "EQ"          // =
+	NE          = "NE"          // <> or !=
 	AND         = "AND"         // &
+	OR          = "OR"          // |
 	NOT         = "NOT"         // !
 	ASSIGN      = "ASSIGN"      // :=
 
@@ -35,6 +40,7 @@ const (
 	RPAREN = "RPAREN" // )
 	SEMI   = "SEMI"   // ;
 	COLON  = "COLON"  // :
+	COMMA  = "COMMA"  // ,
 
 	// For loop
 	FOR   = "FOR"
@@ -47,6 +53,7 @@ const (
 	VAR    = "VAR"
 	READ   = "READ"
 	PRINT  = "PRINT"
+	IMPORT = "IMPORT"
 
 	EOF = "EOF"
 
@@ -64,13 +71,14 @@ type Token struct {
 // An empty lexeme can be passed in if the token is not expecting a lexeme.
 func New(tag TokenTag, lexeme string) Token { return Token{tag, lexeme} }
 
-// ValueInt returns the lexeme of the token as an integer or panics if the lexeme is not an integer.
+// ValueInt returns the lexeme of the token as an integer or panics if the
+// lexeme is not an integer literal. See ParseInt for the syntax of the literals.
 func (t Token) ValueInt() int {
 	if t.lexeme == "" {
 		panic("Attempting to take value of an empty lexeme")
 	}
 
-	num, err := strconv.Atoi(t.lexeme)
+	num, err := ParseInt(t.lexeme)
 	if err != nil {
 		panic(err)
 	}
@@ -99,29 +107,29 @@ func (t Token) Value() string {
 	return t.lexeme
 }
 
+// Lexeme returns the lexeme of the token, which is empty for tokens that do
+// not carry one. Unlike Value, Lexeme never panics.
+func (t Token) Lexeme() string { return t.lexeme }
+
 // Type returns the tag of the token.
 func (t Token) Type() TokenTag { return t.tag }
 
-func (t Token) IsOperator() bool {
-	operators := []TokenTag{PLUS, MINUS, MULTIPLY, INTEGER_DIV, LT, EQ, AND}
-	for _, op := range operators {
-		if t.tag == op {
-			return true
-		}
+// operators and statements hold the tags of the binary operators and of the
+// tokens that can start a statement.
+var (
+	operators = map[TokenTag]bool{
+		PLUS: true, MINUS: true, MULTIPLY: true, INTEGER_DIV: true,
+		LT: true, LE: true, GT: true, GE: true, EQ: true, NE: true, AND: true, OR: true,
 	}
-	return false
-}
+	statements = map[TokenTag]bool{
+		VAR: true, IDENT: true, FOR: true, READ: true, PRINT: true, ASSERT: true, IMPORT: true,
+	}
+)
+
+func (t Token) IsOperator() bool { return operators[t.tag] }
 
 func (t Token) IsType() bool {
 	return t.tag == INTEGER || t.tag == STRING || t.tag == BOOLEAN
 }
 
-func (t Token) IsStatement() bool {
-	statements := []TokenTag{VAR, IDENT, FOR, READ, PRINT, ASSERT}
-	for _, s := range statements {
-		if t.tag == s {
-			return true
-		}
-	}
-	return false
-}
+func (t Token) IsStatement() bool { return statements[t.tag] }
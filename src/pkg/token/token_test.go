@@ -0,0 +1,44 @@
+package token
+
+import "testing"
+
+func TestClassification(t *testing.T) {
+	testCases := []struct {
+		token       Token
+		isOperator  bool
+		isStatement bool
+	}{
+		{New(PLUS, ""), true, false},
+		{New(AND, ""), true, false},
+		{New(GE, ""), true, false},
+		{New(OR, ""), true, false},
+		{New(NOT, ""), false, false},
+		{New(VAR, ""), false, true},
+		{New(IDENT, "x"), false, true},
+		{New(IMPORT, ""), false, true},
+		{New(SEMI, ""), false, false},
+	}
+
+	for _, testCase := range testCases {
+		if actual := testCase.token.IsOperator(); actual != testCase.isOperator {
+			t.Errorf("Expected IsOperator of %v to be %t", testCase.token, testCase.isOperator)
+		}
+
+		if actual := testCase.token.IsStatement(); actual != testCase.isStatement {
+			t.Errorf("Expected IsStatement of %v to be %t", testCase.token, testCase.isStatement)
+		}
+	}
+}
+
+func BenchmarkClassification(b *testing.B) {
+	tokens := []Token{New(PLUS, ""), New(IDENT, "x"), New(SEMI, ""), New(PRINT, "")}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		tok := tokens[i%len(tokens)]
+		if tok.IsOperator() && tok.IsStatement() {
+			b.Fatal("A token cannot be both an operator and a statement")
+		}
+	}
+}
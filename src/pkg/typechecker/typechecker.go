@@ -1,17 +1,65 @@
 package typechecker
 
 import (
-	"fmt"
+	"reflect"
 
 	"github.com/mjjs/minipl-go/pkg/ast"
+	"github.com/mjjs/minipl-go/pkg/builtin"
 	"github.com/mjjs/minipl-go/pkg/stack"
 	"github.com/mjjs/minipl-go/pkg/symboltable"
 	"github.com/mjjs/minipl-go/pkg/token"
+	"github.com/mjjs/minipl-go/pkg/types"
 )
 
+// Info holds the types and symbols resolved by the type checker. A node is
+// looked up by its kind and its span in the source code, so the nodes of the
+// checked tree and copies of them find the same entries.
+type Info struct {
+	// types maps every expression to its type.
+	types map[nodeKey]types.Type
+	// idents maps every identifier to the symbol it refers to.
+	idents map[nodeKey]symboltable.Symbol
+}
+
+// nodeKey identifies a node of the checked tree. The nodes are values that
+// cannot all be compared, as a CallExpr holds a slice.
+type nodeKey struct {
+	kind reflect.Type
+	pos  token.Position
+	end  token.Position
+}
+
+func keyOf(node ast.Node) nodeKey {
+	return nodeKey{kind: reflect.TypeOf(node), pos: node.Position(), end: node.End()}
+}
+
+// TypeOf returns the type of an expression or an identifier, or nil if the
+// node was not checked or refers to a variable whose type is not inferred.
+func (info *Info) TypeOf(node ast.Node) types.Type {
+	switch node := node.(type) {
+	case ast.Expr:
+		return info.types[keyOf(node)]
+	case ast.Ident:
+		if symbol, ok := info.SymbolOf(node); ok {
+			return symbol.Type()
+		}
+	}
+
+	return nil
+}
+
+// SymbolOf returns the symbol an identifier refers to, or false if the
+// identifier was not checked or refers to no variable.
+func (info *Info) SymbolOf(ident ast.Ident) (symboltable.Symbol, bool) {
+	symbol, ok := info.idents[keyOf(ident)]
+	return symbol, ok
+}
+
 type TypeChecker struct {
 	stack   *stack.Stack
 	symbols *symboltable.SymbolTable
+	funcs   builtin.Set
+	info    *Info
 
 	errors []error
 }
@@ -20,12 +68,31 @@ func New(symbols *symboltable.SymbolTable) *TypeChecker {
 	return &TypeChecker{
 		stack:   stack.New(),
 		symbols: symbols,
+		info: &Info{
+			types:  make(map[nodeKey]types.Type),
+			idents: make(map[nodeKey]symboltable.Symbol),
+		},
 	}
 }
 
-func (tc *TypeChecker) CheckTypes(root ast.Node) []error {
+// WithFunctions makes the functions in funcs callable in addition to the
+// builtin ones.
+func (tc *TypeChecker) WithFunctions(funcs builtin.Set) *TypeChecker {
+	tc.funcs = funcs
+	return tc
+}
+
+// CheckTypes checks the types of the program and infers the types of the
+// variables declared without one. It can be run on a program for which the
+// symbol table creator reported errors: unknown identifiers have the invalid
+// type, and errors are not reported for expressions of the invalid type.
+// Statements are checked in program order, and the type of an inferred
+// variable is recorded in the symbol table as soon as its declaration is
+// checked, so every later use sees it. The types of all the expressions and
+// the symbols of all the identifiers are returned in an Info.
+func (tc *TypeChecker) CheckTypes(root ast.Node) (*Info, []error) {
 	root.Accept(tc)
-	return tc.errors
+	return tc.info, tc.errors
 }
 
 func (tc *TypeChecker) VisitProg(node ast.Prog) {
@@ -43,24 +110,25 @@ func (tc *TypeChecker) VisitDeclStmt(node ast.DeclStmt) {
 		return
 	}
 
-	var variableType symboltable.SymbolType
+	node.Expression.Accept(tc)
+	rhsType := tc.stack.Pop().(types.Type)
 
-	switch node.VariableType.Type() {
-	case token.INTEGER:
-		variableType = symboltable.INTEGER
-	case token.STRING:
-		variableType = symboltable.STRING
-	case token.BOOLEAN:
-		variableType = symboltable.BOOLEAN
+	if node.Inferred() {
+		// A redeclared variable keeps the type of its first declaration.
+		symbol, ok := tc.symbols.Get(node.Identifier.Value())
+		if ok && symbol.Inferred() && symbol.Type() == nil {
+			tc.symbols.Infer(node.Identifier.Value(), rhsType)
+		}
+		return
 	}
 
-	node.Expression.Accept(tc)
+	variableType := types.FromToken(node.VariableType.Type())
 
-	rhsType := tc.stack.Pop().(symboltable.SymbolType)
-	if variableType != rhsType {
-		err := fmt.Errorf(
-			"%s: cannot assign type %s to variable %s of type %s",
-			node.Position(), rhsType, node.Identifier.Value(), variableType,
+	if !types.AssignableTo(rhsType, variableType) {
+		err := token.Errorf(
+			node.Position(),
+			"cannot assign type %s to variable %s of type %s",
+			rhsType, node.Identifier.Value(), variableType,
 		)
 
 		tc.errors = append(tc.errors, err)
@@ -69,15 +137,16 @@ func (tc *TypeChecker) VisitDeclStmt(node ast.DeclStmt) {
 
 func (tc *TypeChecker) VisitAssignStmt(node ast.AssignStmt) {
 	node.Identifier.Accept(tc)
-	idType := tc.stack.Pop().(symboltable.SymbolType)
+	idType := tc.stack.Pop().(types.Type)
 
 	node.Expression.Accept(tc)
-	exprType := tc.stack.Pop().(symboltable.SymbolType)
+	exprType := tc.stack.Pop().(types.Type)
 
-	if exprType != idType {
-		err := fmt.Errorf(
-			"%s: cannot assign type %s to variable %s of type %s",
-			node.Position(), exprType, node.Identifier.Id.Value(), idType,
+	if !types.AssignableTo(exprType, idType) {
+		err := token.Errorf(
+			node.Position(),
+			"cannot assign type %s to variable %s of type %s",
+			exprType, node.Identifier.Id.Value(), idType,
 		)
 
 		tc.errors = append(tc.errors, err)
@@ -86,177 +155,286 @@ func (tc *TypeChecker) VisitAssignStmt(node ast.AssignStmt) {
 
 func (tc *TypeChecker) VisitForStmt(node ast.ForStmt) {
 	node.Index.Accept(tc)
-	indexType := tc.stack.Pop().(symboltable.SymbolType)
+	indexType := tc.stack.Pop().(types.Type)
 
 	node.Low.Accept(tc)
-	lowType := tc.stack.Pop().(symboltable.SymbolType)
+	lowType := tc.stack.Pop().(types.Type)
 
 	node.High.Accept(tc)
-	highType := tc.stack.Pop().(symboltable.SymbolType)
+	highType := tc.stack.Pop().(types.Type)
 
-	if indexType != symboltable.INTEGER {
-		err := fmt.Errorf(
-			"%s: loop index must be %s, not %s",
-			node.Position(), symboltable.INTEGER, indexType,
+	if !types.AssignableTo(indexType, types.Int) {
+		err := token.Errorf(
+			node.Position(),
+			"loop index must be %s, not %s",
+			types.Int, indexType,
 		)
 
 		tc.errors = append(tc.errors, err)
 	}
 
-	if lowType != symboltable.INTEGER {
-		err := fmt.Errorf(
-			"%s: for loop range lower bound must be %s, not %s",
-			node.Position(), symboltable.INTEGER, lowType,
+	if !types.AssignableTo(lowType, types.Int) {
+		err := token.Errorf(
+			node.Position(),
+			"for loop range lower bound must be %s, not %s",
+			types.Int, lowType,
 		)
 
 		tc.errors = append(tc.errors, err)
 	}
 
-	if highType != symboltable.INTEGER {
-		err := fmt.Errorf(
-			"%s: for loop range upper bound must be %s, not %s",
-			node.Position(), symboltable.INTEGER, highType,
+	if !types.AssignableTo(highType, types.Int) {
+		err := token.Errorf(
+			node.Position(),
+			"for loop range upper bound must be %s, not %s",
+			types.Int, highType,
 		)
 
 		tc.errors = append(tc.errors, err)
 	}
+
+	node.Statements.Accept(tc)
 }
 
 func (tc *TypeChecker) VisitReadStmt(node ast.ReadStmt) {
+	node.TargetIdentifier.Accept(tc)
+	tc.stack.Pop()
 }
 
 func (tc *TypeChecker) VisitPrintStmt(node ast.PrintStmt) {
 	node.Expression.Accept(tc)
+	tc.stack.Pop()
 }
 
 func (tc *TypeChecker) VisitAssertStmt(node ast.AssertStmt) {
 	node.Expression.Accept(tc)
 
-	exprType := tc.stack.Pop().(symboltable.SymbolType)
-	if exprType != symboltable.BOOLEAN {
-		err := fmt.Errorf(
-			"%s: assert statement is only defined for type %s, not %s",
-			node.Position(), symboltable.BOOLEAN, exprType,
+	exprType := tc.stack.Pop().(types.Type)
+	if !types.AssignableTo(exprType, types.Bool) {
+		err := token.Errorf(
+			node.Position(),
+			"assert statement is only defined for type %s, not %s",
+			types.Bool, exprType,
 		)
 
 		tc.errors = append(tc.errors, err)
 	}
 }
 
+// binaryOperandTypes holds the operand types each arithmetic and logical
+// operator is defined for. Comparisons are defined for every type: integers
+// are ordered by value, strings lexicographically by bytes and false is less
+// than true.
+var binaryOperandTypes = map[token.TokenTag][]types.Type{
+	token.PLUS:        {types.Int, types.String},
+	token.MINUS:       {types.Int},
+	token.MULTIPLY:    {types.Int},
+	token.INTEGER_DIV: {types.Int},
+	token.AND:         {types.Bool},
+	token.OR:          {types.Bool},
+}
+
+// VisitBinaryExpr checks that both operands have the same type and that the
+// operator is defined for it. An expression with an invalid operand has the
+// invalid type without further errors, and an expression with an error has
+// the invalid type so that the error is reported only once.
 func (tc *TypeChecker) VisitBinaryExpr(node ast.BinaryExpr) {
 	node.Left.Accept(tc)
-	left := tc.stack.Pop().(symboltable.SymbolType)
+	left := tc.stack.Pop().(types.Type)
 
 	node.Right.Accept(tc)
-	right := tc.stack.Pop().(symboltable.SymbolType)
+	right := tc.stack.Pop().(types.Type)
+
+	if types.IsInvalid(left) || types.IsInvalid(right) {
+		tc.push(node, types.Invalid)
+		return
+	}
 
-	if left != right {
-		err := fmt.Errorf(
-			"%s: unmatched types %s and %s for binary expression %s",
-			node.Position(), left, right, node.Operator.Type(),
+	if !types.Identical(left, right) {
+		err := token.Errorf(
+			node.Position(),
+			"unmatched types %s and %s for binary expression %s",
+			left, right, node.Operator.Type(),
 		)
 
 		tc.errors = append(tc.errors, err)
+		tc.push(node, types.Invalid)
+		return
 	}
 
 	switch node.Operator.Type() {
-	case token.PLUS:
-		if left != symboltable.INTEGER && left != symboltable.STRING {
-			err := fmt.Errorf(
-				"%s: operator %s not defined for type %s",
-				node.Position(), token.PLUS, left,
-			)
-
-			tc.errors = append(tc.errors, err)
-		}
-		tc.stack.Push(left)
+	case token.LT, token.LE, token.GT, token.GE, token.EQ, token.NE:
+		tc.push(node, types.Bool)
+		return
+	}
 
-	case token.MINUS:
-		if left != symboltable.INTEGER {
-			err := fmt.Errorf(
-				"%s: operator %s not defined for type %s",
-				node.Position(), token.MINUS, left,
-			)
+	if !tc.isOneOf(left, binaryOperandTypes[node.Operator.Type()]) {
+		err := token.Errorf(
+			node.Position(),
+			"operator %s not defined for type %s",
+			node.Operator.Type(), left,
+		)
 
-			tc.errors = append(tc.errors, err)
-		}
-		tc.stack.Push(left)
+		tc.errors = append(tc.errors, err)
+		tc.push(node, types.Invalid)
+		return
+	}
 
-	case token.MULTIPLY:
-		if left != symboltable.INTEGER {
-			err := fmt.Errorf(
-				"%s: operator %s not defined for type %s",
-				node.Position(), token.MULTIPLY, left,
-			)
+	tc.push(node, left)
+}
 
-			tc.errors = append(tc.errors, err)
+func (tc *TypeChecker) isOneOf(t types.Type, candidates []types.Type) bool {
+	for _, candidate := range candidates {
+		if types.Identical(t, candidate) {
+			return true
 		}
-		tc.stack.Push(left)
+	}
 
-	case token.INTEGER_DIV:
-		if left != symboltable.INTEGER {
-			err := fmt.Errorf(
-				"%s: operator %s not defined for type %s",
-				node.Position(), token.INTEGER_DIV, left,
-			)
+	return false
+}
 
-			tc.errors = append(tc.errors, err)
-		}
-		tc.stack.Push(left)
+// unaryOperandTypes holds the operand type each unary operator is defined for.
+var unaryOperandTypes = map[token.TokenTag]types.Type{
+	token.NOT:   types.Bool,
+	token.MINUS: types.Int,
+}
 
-	case token.AND:
-		if left != symboltable.BOOLEAN {
-			err := fmt.Errorf(
-				"%s: operator %s not defined for type %s",
-				node.Position(), token.AND, left,
-			)
+func (tc *TypeChecker) VisitUnaryExpr(node ast.UnaryExpr) {
+	node.Operand.Accept(tc)
+	t := tc.stack.Pop().(types.Type)
 
-			tc.errors = append(tc.errors, err)
-		}
+	if !types.AssignableTo(t, unaryOperandTypes[node.Unary.Type()]) {
+		err := token.Errorf(
+			node.Position(),
+			"unary operator %s not defined for type %s",
+			node.Unary.Type(), t,
+		)
 
-		tc.stack.Push(left)
+		tc.errors = append(tc.errors, err)
+		t = types.Invalid
+	}
 
-	case token.LT:
-		tc.stack.Push(symboltable.BOOLEAN)
+	tc.push(node, t)
+}
 
-	case token.EQ:
-		tc.stack.Push(symboltable.BOOLEAN)
+// VisitCallExpr checks that the function is a known one and that the
+// arguments match its parameters. An invalid argument matches any parameter.
+// A call with an error has the invalid type, and the result type of the
+// function otherwise.
+func (tc *TypeChecker) VisitCallExpr(node ast.CallExpr) {
+	args := node.Args
+	argTypes := make([]types.Type, len(args))
+	for i, arg := range args {
+		arg.Accept(tc)
+		argTypes[i] = tc.stack.Pop().(types.Type)
 	}
-}
 
-func (tc *TypeChecker) VisitUnaryExpr(node ast.UnaryExpr) {
-	node.Operand.Accept(tc)
-	t := tc.stack.Pop().(symboltable.SymbolType)
+	name := node.Func.Value()
+
+	f, ok := tc.funcs.Lookup(name)
+	if !ok {
+		tc.errors = append(tc.errors, token.Errorf(node.Position(), "unknown function %s", name))
+		tc.push(node, types.Invalid)
+		return
+	}
 
-	if node.Unary.Type() == token.NOT && t != symboltable.BOOLEAN {
-		err := fmt.Errorf(
-			"%s: unary operator %s not defined for type %s",
-			node.Position(), token.NOT, t,
+	params := f.Signature.Params()
+	if len(args) != len(params) {
+		err := token.Errorf(
+			node.Position(),
+			"%s takes %d %s, got %d",
+			name, len(params), plural(len(params), "argument"), len(args),
 		)
 
 		tc.errors = append(tc.errors, err)
+		tc.push(node, types.Invalid)
+		return
 	}
 
-	tc.stack.Push(t)
+	result := f.Signature.Result()
+	for i, param := range params {
+		if !types.AssignableTo(argTypes[i], param) {
+			err := token.Errorf(
+				args[i].Position(),
+				"argument %d of %s must be %s, not %s",
+				i+1, name, param, argTypes[i],
+			)
+
+			tc.errors = append(tc.errors, err)
+			result = types.Invalid
+		}
+	}
+
+	tc.push(node, result)
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+
+	return word + "s"
+}
+
+func (tc *TypeChecker) VisitImportStmt(node ast.ImportStmt) {
+	node.Statements.Accept(tc)
+}
+
+func (tc *TypeChecker) VisitBadStmt(node ast.BadStmt) {}
+
+// VisitBadExpr gives the invalid type to an expression with syntax errors,
+// which have already been reported by the parser.
+func (tc *TypeChecker) VisitBadExpr(node ast.BadExpr) {
+	tc.push(node, types.Invalid)
 }
 
 func (tc *TypeChecker) VisitNullaryExpr(node ast.NullaryExpr) {
 	node.Operand.Accept(tc)
+	tc.push(node, tc.stack.Pop().(types.Type))
 }
 
 func (tc *TypeChecker) VisitNumberOpnd(node ast.NumberOpnd) {
-	tc.stack.Push(symboltable.INTEGER)
+	tc.push(node, types.Int)
 }
 
 func (tc *TypeChecker) VisitStringOpnd(node ast.StringOpnd) {
-	tc.stack.Push(symboltable.STRING)
+	tc.push(node, types.String)
+}
+
+func (tc *TypeChecker) VisitBoolOpnd(node ast.BoolOpnd) {
+	tc.push(node, types.Bool)
 }
 
+// VisitIdent pushes the type of the symbol the identifier refers to. Unknown
+// identifiers and variables whose type has not been inferred yet have the
+// invalid type.
 func (tc *TypeChecker) VisitIdent(node ast.Ident) {
 	symbol, ok := tc.symbols.Get(node.Id.Value())
 	if !ok {
-		panic("Type checker came across a symbol not in the symbol table")
+		// The symbol table creator has already reported the identifier.
+		tc.stack.Push(types.Invalid)
+		return
+	}
+
+	tc.info.idents[keyOf(node)] = symbol
+
+	if symbol.Type() == nil {
+		err := token.Errorf(
+			node.Position(),
+			"variable %s is used before its type is inferred",
+			node.Id.Value(),
+		)
+
+		tc.errors = append(tc.errors, err)
+		tc.stack.Push(types.Invalid)
+		return
 	}
 
 	tc.stack.Push(symbol.Type())
 }
+
+// push pushes the type of an expression and records it in the Info.
+func (tc *TypeChecker) push(node ast.Expr, t types.Type) {
+	tc.info.types[keyOf(node)] = t
+	tc.stack.Push(t)
+}
@@ -1,12 +1,14 @@
 package typechecker
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/mjjs/minipl-go/pkg/ast"
 	"github.com/mjjs/minipl-go/pkg/symboltable"
 	"github.com/mjjs/minipl-go/pkg/token"
+	"github.com/mjjs/minipl-go/pkg/types"
 )
 
 var typeCheckerTestCases = []struct {
@@ -32,11 +34,11 @@ var typeCheckerTestCases = []struct {
 				},
 			},
 		},
-		symbols: symboltable.NewSymbolTable().Insert("foo", symboltable.STRING),
+		symbols: symboltable.NewSymbolTable().Insert("foo", types.String),
 		expectedErrors: []error{
 			fmt.Errorf(
 				"1:1: cannot assign type %s to variable foo of type %s",
-				symboltable.INTEGER, symboltable.STRING,
+				types.Int, types.String,
 			),
 		},
 	},
@@ -52,7 +54,81 @@ var typeCheckerTestCases = []struct {
 				},
 			},
 		},
-		symbols: symboltable.NewSymbolTable().Insert("foo", symboltable.STRING),
+		symbols: symboltable.NewSymbolTable().Insert("foo", types.String),
+	},
+	// INVALID TYPE
+	{
+		name: "Unknown identifiers do not cause errors",
+		input: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.DeclStmt{
+						Identifier: token.New(token.IDENT, "foo"),
+						Expression: ast.BinaryExpr{
+							Left:     ast.Ident{Id: token.New(token.IDENT, "bar")},
+							Operator: token.New(token.MINUS, ""),
+							Right:    ast.StringOpnd{Value: "baz"},
+						},
+					},
+					ast.AssertStmt{
+						Expression: ast.UnaryExpr{
+							Unary:   token.New(token.NOT, ""),
+							Operand: ast.Ident{Id: token.New(token.IDENT, "foo")},
+						},
+					},
+					ast.ForStmt{
+						Index:      ast.Ident{Id: token.New(token.IDENT, "i")},
+						Low:        ast.NullaryExpr{Operand: ast.Ident{Id: token.New(token.IDENT, "foo")}},
+						High:       ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 5}},
+						Statements: ast.Stmts{},
+					},
+				},
+			},
+		},
+		symbols: symboltable.NewSymbolTable().InsertInferred("foo"),
+	},
+	{
+		name: "Errors are reported once for nested expressions",
+		input: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.DeclStmt{
+						Identifier:   token.New(token.IDENT, "foo"),
+						VariableType: token.New(token.INTEGER, ""),
+						Expression: ast.BinaryExpr{
+							Left: ast.BinaryExpr{
+								Left:     ast.StringOpnd{Value: "bar", Pos: token.Position{Line: 3, Column: 9}},
+								Operator: token.New(token.MINUS, ""),
+								Right:    ast.StringOpnd{Value: "baz"},
+							},
+							Operator: token.New(token.MULTIPLY, ""),
+							Right:    ast.NumberOpnd{Value: 2},
+						},
+					},
+				},
+			},
+		},
+		symbols: symboltable.NewSymbolTable().Insert("foo", types.Int),
+		expectedErrors: []error{
+			fmt.Errorf("3:9: operator %s not defined for type %s", token.MINUS, types.String),
+		},
+	},
+	{
+		name: "Bad expressions do not cause errors",
+		input: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.DeclStmt{
+						Identifier:   token.New(token.IDENT, "foo"),
+						VariableType: token.New(token.INTEGER, ""),
+						Expression:   ast.BadExpr{},
+					},
+					ast.BadStmt{},
+					ast.AssertStmt{Expression: ast.BadExpr{}},
+				},
+			},
+		},
+		symbols: symboltable.NewSymbolTable().Insert("foo", types.Int),
 	},
 	// ASSERT
 	{
@@ -70,7 +146,7 @@ var typeCheckerTestCases = []struct {
 		expectedErrors: []error{
 			fmt.Errorf(
 				"22:1: assert statement is only defined for type %s, not %s",
-				symboltable.BOOLEAN, symboltable.STRING,
+				types.Bool, types.String,
 			),
 		},
 	},
@@ -90,6 +166,18 @@ var typeCheckerTestCases = []struct {
 			},
 		},
 	},
+	{
+		name: "Assert with boolean literal",
+		input: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.AssertStmt{
+						Expression: ast.NullaryExpr{Operand: ast.BoolOpnd{Value: true}},
+					},
+				},
+			},
+		},
+	},
 	// NOT
 	{
 		name: "Not operator with non-boolean operand",
@@ -109,18 +197,58 @@ var typeCheckerTestCases = []struct {
 				},
 			},
 		},
-		symbols: symboltable.NewSymbolTable().Insert("foo", symboltable.BOOLEAN),
+		symbols: symboltable.NewSymbolTable().Insert("foo", types.Bool),
 		expectedErrors: []error{
 			fmt.Errorf(
 				"13:1: unary operator %s not defined for type %s",
-				token.NOT, symboltable.STRING,
+				token.NOT, types.String,
 			),
+		},
+	},
+	{
+		name: "Unary minus with non-integer operand",
+		input: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.PrintStmt{
+						Expression: ast.UnaryExpr{
+							Unary:   token.New(token.MINUS, ""),
+							Operand: ast.BoolOpnd{Value: true},
+							Pos:     token.Position{Line: 4, Column: 7},
+						},
+					},
+				},
+			},
+		},
+		expectedErrors: []error{
 			fmt.Errorf(
-				"12:1: cannot assign type %s to variable foo of type %s",
-				symboltable.STRING, symboltable.BOOLEAN,
+				"4:7: unary operator %s not defined for type %s",
+				token.MINUS, types.Bool,
 			),
 		},
 	},
+	{
+		name: "Unary minus in a binary expression",
+		input: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.DeclStmt{
+						Identifier:   token.New(token.IDENT, "foo"),
+						VariableType: token.New(token.INTEGER, ""),
+						Expression: ast.BinaryExpr{
+							Left: ast.UnaryExpr{
+								Unary:   token.New(token.MINUS, ""),
+								Operand: ast.NumberOpnd{Value: 1},
+							},
+							Operator: token.New(token.MINUS, ""),
+							Right:    ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 2}},
+						},
+					},
+				},
+			},
+		},
+		symbols: symboltable.NewSymbolTable().Insert("foo", types.Int),
+	},
 	{
 		name: "Not operator with boolean operand",
 		input: ast.Prog{
@@ -138,7 +266,7 @@ var typeCheckerTestCases = []struct {
 				},
 			},
 		},
-		symbols: symboltable.NewSymbolTable().Insert("foo", symboltable.BOOLEAN),
+		symbols: symboltable.NewSymbolTable().Insert("foo", types.Bool),
 	},
 	// EQUALITY OPERATOR
 	{
@@ -220,7 +348,7 @@ var typeCheckerTestCases = []struct {
 		expectedErrors: []error{
 			fmt.Errorf(
 				"5:1: unmatched types %s and %s for binary expression %s",
-				symboltable.STRING, symboltable.INTEGER, token.EQ,
+				types.String, types.Int, token.EQ,
 			),
 		},
 	},
@@ -288,7 +416,7 @@ var typeCheckerTestCases = []struct {
 		expectedErrors: []error{
 			fmt.Errorf(
 				"5:1: operator %s not defined for type %s",
-				token.PLUS, symboltable.BOOLEAN,
+				token.PLUS, types.Bool,
 			),
 		},
 	},
@@ -315,7 +443,7 @@ var typeCheckerTestCases = []struct {
 		expectedErrors: []error{
 			fmt.Errorf(
 				"5:1: unmatched types %s and %s for binary expression %s",
-				symboltable.STRING, symboltable.INTEGER, token.PLUS,
+				types.String, types.Int, token.PLUS,
 			),
 		},
 	},
@@ -360,7 +488,7 @@ var typeCheckerTestCases = []struct {
 		expectedErrors: []error{
 			fmt.Errorf(
 				"5:1: operator %s not defined for type %s",
-				token.MINUS, symboltable.STRING,
+				token.MINUS, types.String,
 			),
 		},
 	},
@@ -395,7 +523,7 @@ var typeCheckerTestCases = []struct {
 		expectedErrors: []error{
 			fmt.Errorf(
 				"5:1: operator %s not defined for type %s",
-				token.MINUS, symboltable.BOOLEAN,
+				token.MINUS, types.Bool,
 			),
 		},
 	},
@@ -422,11 +550,7 @@ var typeCheckerTestCases = []struct {
 		expectedErrors: []error{
 			fmt.Errorf(
 				"5:1: unmatched types %s and %s for binary expression %s",
-				symboltable.STRING, symboltable.INTEGER, token.MINUS,
-			),
-			fmt.Errorf(
-				"5:1: operator %s not defined for type %s",
-				token.MINUS, symboltable.STRING,
+				types.String, types.Int, token.MINUS,
 			),
 		},
 	},
@@ -470,7 +594,7 @@ var typeCheckerTestCases = []struct {
 		expectedErrors: []error{
 			fmt.Errorf(
 				"5:1: operator %s not defined for type %s",
-				token.MULTIPLY, symboltable.STRING,
+				token.MULTIPLY, types.String,
 			),
 		},
 	},
@@ -505,7 +629,7 @@ var typeCheckerTestCases = []struct {
 		expectedErrors: []error{
 			fmt.Errorf(
 				"5:1: operator %s not defined for type %s",
-				token.MULTIPLY, symboltable.BOOLEAN,
+				token.MULTIPLY, types.Bool,
 			),
 		},
 	},
@@ -532,15 +656,11 @@ var typeCheckerTestCases = []struct {
 		expectedErrors: []error{
 			fmt.Errorf(
 				"5:1: unmatched types %s and %s for binary expression %s",
-				symboltable.STRING, symboltable.INTEGER, token.MULTIPLY,
-			),
-			fmt.Errorf(
-				"5:1: operator %s not defined for type %s",
-				token.MULTIPLY, symboltable.STRING,
+				types.String, types.Int, token.MULTIPLY,
 			),
 		},
 	},
-	// symboltable.INTEGER DIVISION OPERATOR
+	// types.Int DIVISION OPERATOR
 	{
 		name: "Integer division with ints",
 		input: ast.Prog{
@@ -580,7 +700,7 @@ var typeCheckerTestCases = []struct {
 		expectedErrors: []error{
 			fmt.Errorf(
 				"5:1: operator %s not defined for type %s",
-				token.INTEGER_DIV, symboltable.STRING,
+				token.INTEGER_DIV, types.String,
 			),
 		},
 	},
@@ -615,7 +735,7 @@ var typeCheckerTestCases = []struct {
 		expectedErrors: []error{
 			fmt.Errorf(
 				"5:1: operator %s not defined for type %s",
-				token.INTEGER_DIV, symboltable.BOOLEAN,
+				token.INTEGER_DIV, types.Bool,
 			),
 		},
 	},
@@ -642,11 +762,7 @@ var typeCheckerTestCases = []struct {
 		expectedErrors: []error{
 			fmt.Errorf(
 				"5:1: unmatched types %s and %s for binary expression %s",
-				symboltable.STRING, symboltable.INTEGER, token.INTEGER_DIV,
-			),
-			fmt.Errorf(
-				"5:1: operator %s not defined for type %s",
-				token.INTEGER_DIV, symboltable.STRING,
+				types.String, types.Int, token.INTEGER_DIV,
 			),
 		},
 	},
@@ -674,7 +790,7 @@ var typeCheckerTestCases = []struct {
 		expectedErrors: []error{
 			fmt.Errorf(
 				"5:1: operator %s not defined for type %s",
-				token.AND, symboltable.INTEGER,
+				token.AND, types.Int,
 			),
 		},
 	},
@@ -701,7 +817,7 @@ var typeCheckerTestCases = []struct {
 		expectedErrors: []error{
 			fmt.Errorf(
 				"5:1: operator %s not defined for type %s",
-				token.AND, symboltable.STRING,
+				token.AND, types.String,
 			),
 		},
 	},
@@ -752,11 +868,51 @@ var typeCheckerTestCases = []struct {
 		expectedErrors: []error{
 			fmt.Errorf(
 				"5:1: unmatched types %s and %s for binary expression %s",
-				symboltable.STRING, symboltable.INTEGER, token.AND,
+				types.String, types.Int, token.AND,
 			),
+		},
+	},
+	// OR OPERATOR
+	{
+		name: "OR with booleans",
+		input: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.AssertStmt{
+						Expression: ast.BinaryExpr{
+							Left:     ast.NullaryExpr{Operand: ast.BoolOpnd{Value: false}},
+							Operator: token.New(token.OR, ""),
+							Right:    ast.NullaryExpr{Operand: ast.BoolOpnd{Value: true}},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		name: "OR with strings",
+		input: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.PrintStmt{
+						Expression: ast.BinaryExpr{
+							Left: ast.NullaryExpr{
+								Operand: ast.StringOpnd{
+									Value: "a",
+									Pos:   token.Position{Line: 3, Column: 7},
+								},
+							},
+							Operator: token.New(token.OR, ""),
+							Right:    ast.NullaryExpr{Operand: ast.StringOpnd{Value: "b"}},
+						},
+					},
+				},
+			},
+		},
+		expectedErrors: []error{
 			fmt.Errorf(
-				"5:1: operator %s not defined for type %s",
-				token.AND, symboltable.STRING,
+				"3:7: operator %s not defined for type %s",
+				token.OR, types.String,
 			),
 		},
 	},
@@ -840,10 +996,151 @@ var typeCheckerTestCases = []struct {
 		expectedErrors: []error{
 			fmt.Errorf(
 				"666:1: unmatched types %s and %s for binary expression %s",
-				symboltable.STRING, symboltable.INTEGER, token.LT,
+				types.String, types.Int, token.LT,
 			),
 		},
 	},
+	// OTHER COMPARISON OPERATORS
+	{
+		name: "Comparisons are booleans",
+		input: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.AssertStmt{
+						Expression: ast.BinaryExpr{
+							Left: ast.BinaryExpr{
+								Left:     ast.NullaryExpr{Operand: ast.StringOpnd{Value: "a"}},
+								Operator: token.New(token.GE, ""),
+								Right:    ast.NullaryExpr{Operand: ast.StringOpnd{Value: "b"}},
+							},
+							Operator: token.New(token.NE, ""),
+							Right: ast.BinaryExpr{
+								Left:     ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 1}},
+								Operator: token.New(token.LE, ""),
+								Right:    ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 2}},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		name: "GREATER THAN with unmatched types",
+		input: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.PrintStmt{
+						Expression: ast.BinaryExpr{
+							Left: ast.NullaryExpr{
+								Operand: ast.BoolOpnd{
+									Value: true,
+									Pos:   token.Position{Line: 2, Column: 7},
+								},
+							},
+							Operator: token.New(token.GT, ""),
+							Right:    ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 1}},
+						},
+					},
+				},
+			},
+		},
+		expectedErrors: []error{
+			fmt.Errorf(
+				"2:7: unmatched types %s and %s for binary expression %s",
+				types.Bool, types.Int, token.GT,
+			),
+		},
+	},
+	// FUNCTION CALLS
+	{
+		name: "Valid function calls",
+		input: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.AssertStmt{
+						Expression: ast.BinaryExpr{
+							Left: ast.CallExpr{
+								Func: token.New(token.IDENT, "len"),
+								Args: []ast.Expr{
+									ast.NullaryExpr{Operand: ast.CallExpr{
+										Func: token.New(token.IDENT, "toString"),
+										Args: []ast.Expr{ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 42}}},
+									}},
+								},
+							},
+							Operator: token.New(token.EQ, ""),
+							Right:    ast.NumberOpnd{Value: 2},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		name: "Unknown function",
+		input: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.PrintStmt{
+						Expression: ast.BinaryExpr{
+							Left: ast.CallExpr{
+								Func: token.New(token.IDENT, "foo"),
+								Args: []ast.Expr{},
+								Pos:  token.Position{Line: 1, Column: 7},
+							},
+							Operator: token.New(token.PLUS, ""),
+							Right:    ast.NumberOpnd{Value: 1},
+						},
+					},
+				},
+			},
+		},
+		expectedErrors: []error{
+			fmt.Errorf("1:7: unknown function foo"),
+		},
+	},
+	{
+		name: "Function call with the wrong number of arguments",
+		input: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.PrintStmt{
+						Expression: ast.NullaryExpr{Operand: ast.CallExpr{
+							Func: token.New(token.IDENT, "charAt"),
+							Args: []ast.Expr{ast.NullaryExpr{Operand: ast.StringOpnd{Value: "abc"}}},
+							Pos:  token.Position{Line: 1, Column: 7},
+						}},
+					},
+				},
+			},
+		},
+		expectedErrors: []error{
+			fmt.Errorf("1:7: charAt takes 2 arguments, got 1"),
+		},
+	},
+	{
+		name: "Function call with arguments of the wrong type",
+		input: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.PrintStmt{
+						Expression: ast.NullaryExpr{Operand: ast.CallExpr{
+							Func: token.New(token.IDENT, "indexOf"),
+							Args: []ast.Expr{
+								ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 1, Pos: token.Position{Line: 1, Column: 15}}},
+								ast.NullaryExpr{Operand: ast.BoolOpnd{Value: true, Pos: token.Position{Line: 1, Column: 18}}},
+							},
+						}},
+					},
+				},
+			},
+		},
+		expectedErrors: []error{
+			fmt.Errorf("1:15: argument 1 of indexOf must be %s, not %s", types.String, types.Int),
+			fmt.Errorf("1:18: argument 2 of indexOf must be %s, not %s", types.String, types.Bool),
+		},
+	},
 	// FOR LOOP
 	{
 		name: "For statement with non-integer index",
@@ -864,11 +1161,11 @@ var typeCheckerTestCases = []struct {
 				},
 			},
 		},
-		symbols: symboltable.NewSymbolTable().Insert("i", symboltable.STRING),
+		symbols: symboltable.NewSymbolTable().Insert("i", types.String),
 		expectedErrors: []error{
 			fmt.Errorf(
 				"1:1: loop index must be %s, not %s",
-				symboltable.INTEGER, symboltable.STRING,
+				types.Int, types.String,
 			),
 		},
 	},
@@ -891,11 +1188,11 @@ var typeCheckerTestCases = []struct {
 				},
 			},
 		},
-		symbols: symboltable.NewSymbolTable().Insert("i", symboltable.INTEGER),
+		symbols: symboltable.NewSymbolTable().Insert("i", types.Int),
 		expectedErrors: []error{
 			fmt.Errorf(
 				"1:1: for loop range lower bound must be %s, not %s",
-				symboltable.INTEGER, symboltable.STRING,
+				types.Int, types.String,
 			),
 		},
 	},
@@ -918,11 +1215,11 @@ var typeCheckerTestCases = []struct {
 				},
 			},
 		},
-		symbols: symboltable.NewSymbolTable().Insert("i", symboltable.INTEGER),
+		symbols: symboltable.NewSymbolTable().Insert("i", types.Int),
 		expectedErrors: []error{
 			fmt.Errorf(
 				"1:1: for loop range upper bound must be %s, not %s",
-				symboltable.INTEGER, symboltable.STRING,
+				types.Int, types.String,
 			),
 		},
 	},
@@ -944,15 +1241,312 @@ var typeCheckerTestCases = []struct {
 				},
 			},
 		},
-		symbols: symboltable.NewSymbolTable().Insert("i", symboltable.INTEGER),
+		symbols: symboltable.NewSymbolTable().Insert("i", types.Int),
+	},
+	{
+		name: "For statement body with unmatched types",
+		input: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.ForStmt{
+						Index: ast.Ident{Id: token.New(token.IDENT, "i")},
+						Low:   ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 0}},
+						High:  ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 2}},
+						Statements: ast.Stmts{
+							Statements: []ast.Stmt{
+								ast.PrintStmt{
+									Expression: ast.BinaryExpr{
+										Left:     ast.NumberOpnd{Value: 1, Pos: token.Position{Line: 2, Column: 8}},
+										Operator: token.New(token.PLUS, ""),
+										Right:    ast.StringOpnd{Value: "a"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		symbols: symboltable.NewSymbolTable().Insert("i", types.Int),
+		expectedErrors: []error{
+			fmt.Errorf("2:8: unmatched types %s and %s for binary expression PLUS", types.Int, types.String),
+		},
+	},
+	{
+		name: "Inferred declaration in a for statement body",
+		input: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.ForStmt{
+						Index: ast.Ident{Id: token.New(token.IDENT, "i")},
+						Low:   ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 0}},
+						High:  ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 2}},
+						Statements: ast.Stmts{
+							Statements: []ast.Stmt{
+								ast.DeclStmt{
+									Identifier: token.New(token.IDENT, "x"),
+									Expression: ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 1}},
+								},
+							},
+						},
+					},
+					ast.DeclStmt{
+						Identifier:   token.New(token.IDENT, "s"),
+						VariableType: token.New(token.STRING, ""),
+						Expression: ast.NullaryExpr{Operand: ast.Ident{
+							Id:  token.New(token.IDENT, "x"),
+							Pos: token.Position{Line: 3, Column: 19},
+						}},
+						Pos: token.Position{Line: 3, Column: 1},
+					},
+				},
+			},
+		},
+		symbols: symboltable.NewSymbolTable().
+			Insert("i", types.Int).
+			InsertInferred("x").
+			Insert("s", types.String),
+		expectedErrors: []error{
+			fmt.Errorf("3:1: cannot assign type %s to variable s of type %s", types.Int, types.String),
+		},
+	},
+	{
+		name: "Use of a variable whose type is not inferred",
+		input: ast.Prog{
+			Statements: ast.Stmts{
+				Statements: []ast.Stmt{
+					ast.PrintStmt{
+						Expression: ast.NullaryExpr{Operand: ast.Ident{
+							Id:  token.New(token.IDENT, "x"),
+							Pos: token.Position{Line: 1, Column: 7},
+						}},
+					},
+					ast.DeclStmt{
+						Identifier: token.New(token.IDENT, "x"),
+						Expression: ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 1}},
+					},
+				},
+			},
+		},
+		symbols: symboltable.NewSymbolTable().InsertInferred("x"),
+		expectedErrors: []error{
+			errors.New("1:7: variable x is used before its type is inferred"),
+		},
 	},
 }
 
+func TestInferredTypesAreRecorded(t *testing.T) {
+	input := ast.Prog{
+		Statements: ast.Stmts{
+			Statements: []ast.Stmt{
+				ast.DeclStmt{
+					Identifier: token.New(token.IDENT, "s"),
+					Expression: ast.NullaryExpr{Operand: ast.StringOpnd{Value: "foo"}},
+				},
+				ast.DeclStmt{
+					Identifier: token.New(token.IDENT, "b"),
+					Expression: ast.BinaryExpr{
+						Left:     ast.Ident{Id: token.New(token.IDENT, "s")},
+						Operator: token.New(token.EQ, ""),
+						Right:    ast.StringOpnd{Value: "bar"},
+					},
+				},
+				ast.AssignStmt{
+					Identifier: ast.Ident{Id: token.New(token.IDENT, "s")},
+					Expression: ast.NullaryExpr{Operand: ast.NumberOpnd{Value: 1}},
+					Pos:        token.Position{Line: 3, Column: 1},
+				},
+			},
+		},
+	}
+
+	symbols := symboltable.NewSymbolTable().InsertInferred("s").InsertInferred("b")
+
+	_, errors := New(symbols).CheckTypes(input)
+
+	expectedError := fmt.Sprintf(
+		"3:1: cannot assign type %s to variable s of type %s",
+		types.Int, types.String,
+	)
+	if len(errors) != 1 || errors[0].Error() != expectedError {
+		t.Errorf("Expected the error %s, got %v", expectedError, errors)
+	}
+
+	expectedTypes := map[string]types.Type{
+		"s": types.String,
+		"b": types.Bool,
+	}
+	for name, expected := range expectedTypes {
+		symbol, _ := symbols.Get(name)
+		if !types.Identical(symbol.Type(), expected) || !symbol.Inferred() {
+			t.Errorf("Expected %s to have the inferred type %s, got %s", name, expected, symbol.Type())
+		}
+	}
+}
+
+func TestInfo(t *testing.T) {
+	one := ast.NumberOpnd{Value: 1, Pos: token.Position{Line: 1, Column: 16}}
+	two := ast.NumberOpnd{Value: 2, Pos: token.Position{Line: 1, Column: 20}}
+	sum := ast.BinaryExpr{Left: one, Operator: token.New(token.PLUS, ""), Right: two}
+	x := ast.Ident{Id: token.New(token.IDENT, "x"), Pos: token.Position{Line: 2, Column: 7}}
+	lt := ast.BinaryExpr{Left: x, Operator: token.New(token.LT, ""), Right: one}
+	text := ast.NullaryExpr{Operand: ast.StringOpnd{Value: "foo"}}
+
+	input := ast.Prog{
+		Statements: ast.Stmts{
+			Statements: []ast.Stmt{
+				ast.DeclStmt{
+					Identifier:   token.New(token.IDENT, "x"),
+					VariableType: token.New(token.INTEGER, ""),
+					Expression:   sum,
+				},
+				ast.PrintStmt{Expression: lt},
+				ast.PrintStmt{Expression: text},
+			},
+		},
+	}
+
+	symbols := symboltable.NewSymbolTable().Insert("x", types.Int)
+
+	info, errors := New(symbols).CheckTypes(input)
+	if len(errors) > 0 {
+		t.Fatalf("Expected no errors, got %s", errors)
+	}
+
+	expectedTypes := []struct {
+		node     ast.Node
+		expected types.Type
+	}{
+		{one, types.Int},
+		{two, types.Int},
+		{sum, types.Int},
+		{x, types.Int},
+		{lt, types.Bool},
+		{text, types.String},
+		{text.Operand, types.String},
+	}
+
+	for _, testCase := range expectedTypes {
+		actual := info.TypeOf(testCase.node)
+		if actual == nil || !types.Identical(actual, testCase.expected) {
+			t.Errorf("Expected %#v to have type %s, got %v", testCase.node, testCase.expected, actual)
+		}
+	}
+
+	if symbol, ok := info.SymbolOf(x); !ok || symbol.Name() != "x" {
+		t.Errorf("Expected identifier x to refer to symbol x, got %+v", symbol)
+	}
+
+	if len(info.types) != 6 {
+		t.Errorf("Expected 6 expressions to be recorded, got %d", len(info.types))
+	}
+}
+
+func TestInfoOfCalls(t *testing.T) {
+	call := func(column int) ast.CallExpr {
+		return ast.CallExpr{
+			Func: token.New(token.IDENT, "len"),
+			Args: []ast.Expr{ast.NullaryExpr{Operand: ast.StringOpnd{
+				Value:  "abc",
+				Pos:    token.Position{Line: 1, Column: column + 4},
+				EndPos: token.Position{Line: 1, Column: column + 9},
+			}}},
+			Pos:    token.Position{Line: 1, Column: column},
+			EndPos: token.Position{Line: 1, Column: column + 10},
+		}
+	}
+
+	input := ast.Prog{
+		Statements: ast.Stmts{
+			Statements: []ast.Stmt{
+				ast.PrintStmt{Expression: call(7)},
+				ast.PrintStmt{Expression: call(26)},
+			},
+		},
+	}
+
+	info, errors := New(symboltable.NewSymbolTable()).CheckTypes(input)
+	if len(errors) > 0 {
+		t.Fatalf("Expected no errors, got %s", errors)
+	}
+
+	for _, column := range []int{7, 26} {
+		if actual := info.TypeOf(call(column)); actual == nil || !types.Identical(actual, types.Int) {
+			t.Errorf("Expected the call at column %d to have type int, got %v", column, actual)
+		}
+	}
+
+	if actual := info.TypeOf(call(40)); actual != nil {
+		t.Errorf("Expected an unchecked call to have no type, got %s", actual)
+	}
+}
+
+func TestInfoOfLoopBodies(t *testing.T) {
+	i := ast.Ident{Id: token.New(token.IDENT, "i"), Pos: token.Position{Line: 1, Column: 5}}
+	use := ast.Ident{Id: token.New(token.IDENT, "i"), Pos: token.Position{Line: 2, Column: 7}}
+	product := ast.BinaryExpr{
+		Left:     use,
+		Operator: token.New(token.MULTIPLY, ""),
+		Right:    ast.NumberOpnd{Value: 2, Pos: token.Position{Line: 2, Column: 11}},
+	}
+
+	input := ast.Prog{
+		Statements: ast.Stmts{
+			Statements: []ast.Stmt{
+				ast.ForStmt{
+					Index: i,
+					Low:   ast.NumberOpnd{Value: 0, Pos: token.Position{Line: 1, Column: 10}},
+					High:  ast.NumberOpnd{Value: 3, Pos: token.Position{Line: 1, Column: 13}},
+					Statements: ast.Stmts{
+						Statements: []ast.Stmt{ast.PrintStmt{Expression: product}},
+					},
+				},
+			},
+		},
+	}
+
+	info, errors := New(symboltable.NewSymbolTable().Insert("i", types.Int)).CheckTypes(input)
+	if len(errors) > 0 {
+		t.Fatalf("Expected no errors, got %s", errors)
+	}
+
+	if actual := info.TypeOf(product); actual == nil || !types.Identical(actual, types.Int) {
+		t.Errorf("Expected the expression in the loop body to have type int, got %v", actual)
+	}
+
+	if symbol, ok := info.SymbolOf(use); !ok || symbol.Name() != "i" {
+		t.Errorf("Expected the identifier in the loop body to refer to symbol i, got %+v", symbol)
+	}
+}
+
+func TestInfoOfUninferredVariables(t *testing.T) {
+	x := ast.Ident{Id: token.New(token.IDENT, "x"), Pos: token.Position{Line: 1, Column: 7}}
+
+	input := ast.Prog{
+		Statements: ast.Stmts{
+			Statements: []ast.Stmt{ast.PrintStmt{Expression: ast.NullaryExpr{Operand: x}}},
+		},
+	}
+
+	info, errors := New(symboltable.NewSymbolTable().InsertInferred("x")).CheckTypes(input)
+	if len(errors) != 1 {
+		t.Fatalf("Expected an error, got %s", errors)
+	}
+
+	if symbol, ok := info.SymbolOf(x); !ok || symbol.Name() != "x" {
+		t.Errorf("Expected identifier x to refer to symbol x, got %+v", symbol)
+	}
+
+	if actual := info.TypeOf(x); actual != nil {
+		t.Errorf("Expected identifier x to have no type, got %s", actual)
+	}
+}
+
 func TestCheckTypes(t *testing.T) {
 	for _, testCase := range typeCheckerTestCases {
 		t.Run(testCase.name, func(t *testing.T) {
 			typeChecker := New(testCase.symbols)
-			errors := typeChecker.CheckTypes(testCase.input)
+			_, errors := typeChecker.CheckTypes(testCase.input)
 
 			if len(testCase.expectedErrors) > 0 {
 				if len(testCase.expectedErrors) != len(errors) {
@@ -0,0 +1,163 @@
+// Package types declares the types of Mini-PL values and the rules for
+// comparing them.
+package types
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/mjjs/minipl-go/pkg/token"
+)
+
+// Type is the type of a value. Types are compared with Identical, not with
+// the == operator.
+type Type interface {
+	String() string
+	aType()
+}
+
+// BasicKind identifies a basic type.
+type BasicKind int
+
+const (
+	InvalidKind BasicKind = iota
+	IntKind
+	StringKind
+	BoolKind
+)
+
+// Basic is a predeclared type such as int.
+type Basic struct {
+	kind BasicKind
+	name string
+}
+
+func (b *Basic) Kind() BasicKind { return b.kind }
+func (b *Basic) String() string  { return b.name }
+func (b *Basic) aType()          {}
+
+// The basic types. Invalid is the type of expressions that contain a type
+// error.
+var (
+	Invalid = &Basic{InvalidKind, "invalid type"}
+	Int     = &Basic{IntKind, "int"}
+	String  = &Basic{StringKind, "string"}
+	Bool    = &Basic{BoolKind, "bool"}
+)
+
+// Array is a fixed length sequence of elements of the same type.
+type Array struct {
+	elem Type
+	len  int
+}
+
+func NewArray(elem Type, len int) *Array { return &Array{elem: elem, len: len} }
+
+func (a *Array) Elem() Type { return a.elem }
+func (a *Array) Len() int   { return a.len }
+
+func (a *Array) String() string {
+	return "array [" + strconv.Itoa(a.len) + "] of " + a.elem.String()
+}
+
+func (a *Array) aType() {}
+
+// Function is the type of a function. The result is nil for functions that
+// do not return a value.
+type Function struct {
+	params []Type
+	result Type
+}
+
+func NewFunction(params []Type, result Type) *Function {
+	return &Function{params: params, result: result}
+}
+
+func (f *Function) Params() []Type { return f.params }
+func (f *Function) Result() Type   { return f.result }
+
+func (f *Function) String() string {
+	params := make([]string, len(f.params))
+	for i, param := range f.params {
+		params[i] = param.String()
+	}
+
+	s := "func(" + strings.Join(params, ", ") + ")"
+	if f.result != nil {
+		s += " " + f.result.String()
+	}
+
+	return s
+}
+
+func (f *Function) aType() {}
+
+// Identical reports whether x and y are the same type.
+func Identical(x, y Type) bool {
+	switch x := x.(type) {
+	case *Basic:
+		if y, ok := y.(*Basic); ok {
+			return x.kind == y.kind
+		}
+
+	case *Array:
+		if y, ok := y.(*Array); ok {
+			return x.len == y.len && Identical(x.elem, y.elem)
+		}
+
+	case *Function:
+		if y, ok := y.(*Function); ok {
+			if len(x.params) != len(y.params) {
+				return false
+			}
+
+			for i := range x.params {
+				if !Identical(x.params[i], y.params[i]) {
+					return false
+				}
+			}
+
+			if x.result == nil || y.result == nil {
+				return x.result == nil && y.result == nil
+			}
+
+			return Identical(x.result, y.result)
+		}
+	}
+
+	return false
+}
+
+// AssignableTo reports whether a value of type v can be assigned to a
+// variable of type t. Values must have identical types to be assignable.
+// The invalid type is assignable to and from every type, so that an error
+// already reported for an expression is not reported again where its value
+// is used.
+func AssignableTo(v, t Type) bool {
+	if IsInvalid(v) || IsInvalid(t) {
+		return true
+	}
+
+	return Identical(v, t)
+}
+
+// IsInvalid reports whether t is the invalid type.
+func IsInvalid(t Type) bool {
+	b, ok := t.(*Basic)
+	return ok && b.kind == InvalidKind
+}
+
+// FromToken returns the type named by a type keyword token, or Invalid if
+// the tag does not name a type.
+func FromToken(tag token.TokenTag) Type {
+	switch tag {
+	case token.INTEGER:
+		return Int
+	case token.STRING:
+		return String
+	case token.BOOLEAN:
+		return Bool
+	}
+
+	return Invalid
+}
@@ -0,0 +1,72 @@
+package types
+
+import "testing"
+
+func TestString(t *testing.T) {
+	testCases := []struct {
+		typ      Type
+		expected string
+	}{
+		{Int, "int"},
+		{String, "string"},
+		{Bool, "bool"},
+		{Invalid, "invalid type"},
+		{NewArray(Int, 3), "array [3] of int"},
+		{NewArray(NewArray(Bool, 2), 4), "array [4] of array [2] of bool"},
+		{NewFunction(nil, nil), "func()"},
+		{NewFunction([]Type{String, Int}, String), "func(string, int) string"},
+	}
+
+	for _, testCase := range testCases {
+		if actual := testCase.typ.String(); actual != testCase.expected {
+			t.Errorf("Expected %s, got %s", testCase.expected, actual)
+		}
+	}
+}
+
+func TestIdentical(t *testing.T) {
+	testCases := []struct {
+		x, y     Type
+		expected bool
+	}{
+		{Int, Int, true},
+		{Int, String, false},
+		{Invalid, Invalid, true},
+		{Invalid, Int, false},
+		{NewArray(Int, 3), NewArray(Int, 3), true},
+		{NewArray(Int, 3), NewArray(Int, 4), false},
+		{NewArray(Int, 3), NewArray(String, 3), false},
+		{NewArray(Int, 3), Int, false},
+		{NewFunction([]Type{Int}, Bool), NewFunction([]Type{Int}, Bool), true},
+		{NewFunction([]Type{Int}, Bool), NewFunction([]Type{Int}, nil), false},
+		{NewFunction([]Type{Int}, nil), NewFunction([]Type{Int}, nil), true},
+		{NewFunction([]Type{Int}, Bool), NewFunction([]Type{String}, Bool), false},
+		{NewFunction([]Type{Int}, Bool), NewFunction(nil, Bool), false},
+	}
+
+	for _, testCase := range testCases {
+		if actual := Identical(testCase.x, testCase.y); actual != testCase.expected {
+			t.Errorf("Identical(%s, %s): expected %t, got %t", testCase.x, testCase.y, testCase.expected, actual)
+		}
+	}
+}
+
+func TestAssignableTo(t *testing.T) {
+	testCases := []struct {
+		v, t     Type
+		expected bool
+	}{
+		{Int, Int, true},
+		{String, Int, false},
+		{Invalid, Int, true},
+		{Bool, Invalid, true},
+		{NewArray(Int, 2), NewArray(Int, 2), true},
+		{NewArray(Int, 2), NewArray(Int, 3), false},
+	}
+
+	for _, testCase := range testCases {
+		if actual := AssignableTo(testCase.v, testCase.t); actual != testCase.expected {
+			t.Errorf("AssignableTo(%s, %s): expected %t, got %t", testCase.v, testCase.t, testCase.expected, actual)
+		}
+	}
+}